@@ -0,0 +1,146 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/devshahriar/notification-manager/model"
+)
+
+// Store keeps the archives of expired delivery logs
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List returns the keys starting with prefix in lexical order
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Config selects the archive store. S3 is used when the bucket is set, otherwise Dir. Both empty disable archival
+type Config struct {
+	Dir         string
+	S3Endpoint  string
+	S3Bucket    string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// NewStore returns the store of the config or nil when archival is disabled
+func NewStore(conf Config) Store {
+	if conf.S3Bucket != "" {
+		return NewS3Store(conf.S3Endpoint, conf.S3Bucket, conf.S3Region, conf.S3AccessKey, conf.S3SecretKey)
+	}
+	if conf.Dir != "" {
+		return &DirStore{Dir: conf.Dir}
+	}
+	return nil
+}
+
+// SplitByDay groups the logs by the UTC day they were created on so every archive holds the logs of a single day
+func SplitByDay(logs []model.Logs) [][]model.Logs {
+	days := [][]model.Logs{}
+	index := map[string]int{}
+	for _, v := range logs {
+		day := GetDayPrefix(v.CreatedAt)
+		i, ok := index[day]
+		if !ok {
+			i = len(days)
+			index[day] = i
+			days = append(days, nil)
+		}
+		days[i] = append(days[i], v)
+	}
+	return days
+}
+
+// GetArchiveKey names the archive of the logs of a day by their day and id range.
+// Keys of a day share the prefix of GetDayPrefix
+func GetArchiveKey(logs []model.Logs) string {
+	first, last := logs[0], logs[len(logs)-1]
+	return fmt.Sprintf("%v%020d-%020d.jsonl.gz", GetDayPrefix(first.CreatedAt), first.Id, last.Id)
+}
+
+func GetDayPrefix(day time.Time) string {
+	return "logs/" + day.UTC().Format("2006/01/02") + "/"
+}
+
+// GetDayPrefixes returns the day prefixes of the archives that can hold logs created in [from, to)
+func GetDayPrefixes(from, to time.Time) []string {
+	prefixes := []string{}
+	day := from.UTC().Truncate(24 * time.Hour)
+	for day.Before(to) {
+		prefixes = append(prefixes, GetDayPrefix(day))
+		day = day.Add(24 * time.Hour)
+	}
+	return prefixes
+}
+
+// EncodeLogs writes the logs as gzip compressed json lines
+func EncodeLogs(logs []model.Logs) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, v := range logs {
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func DecodeLogs(data []byte) ([]model.Logs, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	logs := []model.Logs{}
+	dec := json.NewDecoder(zr)
+	for {
+		var log model.Logs
+		err := dec.Decode(&log)
+		if err == io.EOF {
+			return logs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+}
+
+// ReadRange decodes the archived logs created in [from, to)
+func ReadRange(ctx context.Context, store Store, from, to time.Time) ([]model.Logs, error) {
+	logs := []model.Logs{}
+	for _, prefix := range GetDayPrefixes(from, to) {
+		keys, err := store.List(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			data, err := store.Get(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			archived, err := DecodeLogs(data)
+			if err != nil {
+				return nil, fmt.Errorf("decoding archive %v: %w", key, err)
+			}
+			for _, v := range archived {
+				if !v.CreatedAt.Before(from) && v.CreatedAt.Before(to) {
+					logs = append(logs, v)
+				}
+			}
+		}
+	}
+	return logs, nil
+}
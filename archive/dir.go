@@ -0,0 +1,53 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirStore keeps the archives as files under Dir
+type DirStore struct {
+	Dir string
+}
+
+// Put writes to a temporary file first so a crash never leaves a truncated archive
+func (d *DirStore) Put(ctx context.Context, key string, data []byte) error {
+	path := filepath.Join(d.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (d *DirStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(d.Dir, filepath.FromSlash(key)))
+}
+
+func (d *DirStore) List(ctx context.Context, prefix string) ([]string, error) {
+	dir := filepath.Join(d.Dir, filepath.FromSlash(prefix[:strings.LastIndex(prefix, "/")+1]))
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	base := prefix[:strings.LastIndex(prefix, "/")+1]
+	for _, v := range entries {
+		key := base + v.Name()
+		if v.IsDir() || strings.HasSuffix(key, ".tmp") || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,124 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// S3Store keeps the archives in a bucket of an S3 compatible store like MinIO.
+// Objects are addressed path style ({endpoint}/{bucket}/{key}) which every compatible store supports
+type S3Store struct {
+	Endpoint string
+	Bucket   string
+	Region   string
+	Client   *http.Client
+	signer   *v4.Signer
+}
+
+// NewS3Store creates the store. An empty endpoint uses AWS S3 of the region
+func NewS3Store(endpoint, bucket, region, accessKey, secretKey string) *S3Store {
+	if region == "" {
+		region = "us-east-1"
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%v.amazonaws.com", region)
+	}
+	return &S3Store{
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		Bucket:   bucket,
+		Region:   region,
+		Client:   &http.Client{Timeout: 60 * time.Second},
+		signer: v4.NewSigner(credentials.NewStaticCredentials(accessKey, secretKey, ""), func(s *v4.Signer) {
+			s.DisableURIPathEscaping = true
+		}),
+	}
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.do(ctx, http.MethodPut, "/"+key, nil, data)
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, "/"+key, nil, nil)
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+func (s *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		body, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listBucketResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, err
+		}
+		for _, v := range result.Contents {
+			keys = append(keys, v.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *S3Store) do(ctx context.Context, method, path string, query url.Values, data []byte) ([]byte, error) {
+	u := s.Endpoint + "/" + s.Bucket + path
+	if len(query) > 0 {
+		u += "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+	}
+
+	body := bytes.NewReader(data)
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/gzip")
+	}
+	if _, err := s.signer.Sign(req, body, "s3", s.Region, time.Now()); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("s3 %v %v failed with status %v: %s", method, path, resp.StatusCode, respBody)
+	}
+	return respBody, nil
+}
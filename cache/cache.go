@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry struct {
+	value     interface{}
+	tag       string
+	expiresAt time.Time
+}
+
+// Cache is an in-process TTL cache. Every entry carries a tag (the user config id)
+// and invalidating the tag drops every entry of the user at once
+type Cache struct {
+	mu  sync.Mutex
+	ttl time.Duration
+
+	entries map[string]entry
+	tags    map[string]map[string]bool
+
+	// seq counts invalidations. invalidated holds the last invalidation of each tag
+	// so a load that started before it doesn't store a stale value.
+	// Loads that started before floor are never stored
+	seq         uint64
+	floor       uint64
+	invalidated map[string]invalidation
+}
+
+type invalidation struct {
+	seq uint64
+	at  time.Time
+}
+
+func New(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:         ttl,
+		entries:     map[string]entry{},
+		tags:        map[string]map[string]bool{},
+		invalidated: map[string]invalidation{},
+	}
+}
+
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expiresAt) {
+		c.remove(key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Seq returns the invalidation counter to pass to Set once the value is loaded
+func (c *Cache) Seq() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seq
+}
+
+// Set stores value unless its tag was invalidated after seq was taken
+func (c *Cache) Set(key, tag string, value interface{}, seq uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if seq < c.floor || c.invalidated[tag].seq > seq {
+		return false
+	}
+
+	c.remove(key)
+	c.entries[key] = entry{value: value, tag: tag, expiresAt: time.Now().Add(c.ttl)}
+	if c.tags[tag] == nil {
+		c.tags[tag] = map[string]bool{}
+	}
+	c.tags[tag][key] = true
+	return true
+}
+
+// Invalidate drops every entry of the tag
+func (c *Cache) Invalidate(tag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	c.invalidated[tag] = invalidation{seq: c.seq, at: time.Now()}
+	for key := range c.tags[tag] {
+		delete(c.entries, key)
+	}
+	delete(c.tags, tag)
+}
+
+// Flush drops every entry. Used when invalidations might have been missed
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	c.entries = map[string]entry{}
+	c.tags = map[string]map[string]bool{}
+	c.invalidated = map[string]invalidation{}
+	c.floor = c.seq
+}
+
+// Sweep removes expired entries and invalidation records older than the ttl.
+// Dropped records raise the floor so slow loads stay rejected
+func (c *Cache) Sweep() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, e := range c.entries {
+		if now.After(e.expiresAt) {
+			c.remove(key)
+		}
+	}
+	for tag, v := range c.invalidated {
+		if now.Sub(v.at) > c.ttl {
+			if v.seq > c.floor {
+				c.floor = v.seq
+			}
+			delete(c.invalidated, tag)
+		}
+	}
+}
+
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *Cache) remove(key string) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	delete(c.tags[e.tag], key)
+	if len(c.tags[e.tag]) == 0 {
+		delete(c.tags, e.tag)
+	}
+}
+
+// GetOrLoad returns the cached value of key or loads it with fn.
+// fn returns the tag of the value. An empty tag keeps the value out of the cache.
+// A nil cache always loads
+func GetOrLoad[T any](c *Cache, key string, fn func() (T, string, error)) (T, error) {
+	if c == nil {
+		value, _, err := fn()
+		return value, err
+	}
+
+	if value, ok := c.Get(key); ok {
+		return value.(T), nil
+	}
+
+	seq := c.Seq()
+	value, tag, err := fn()
+	if err != nil || tag == "" {
+		return value, err
+	}
+	c.Set(key, tag, value, seq)
+	return value, nil
+}
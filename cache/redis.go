@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// NewRedisClient connects to the machinery result backend. The address is password@host:port like machinery expects
+func NewRedisClient(backend string) redis.UniversalClient {
+	opt := &redis.UniversalOptions{Addrs: []string{backend}}
+	parts := strings.Split(backend, "@")
+	if len(parts) == 2 {
+		opt.Password = parts[0]
+		opt.Addrs = []string{parts[1]}
+	}
+	return redis.NewUniversalClient(opt)
+}
+
+// Publish tells every subscribed worker to drop the cached entries of the tag
+func Publish(ctx context.Context, client redis.UniversalClient, channel, tag string) error {
+	return client.Publish(ctx, channel, tag).Err()
+}
+
+// Subscribe invalidates the cache with the tags published on channel until ctx is done.
+// The cache is flushed whenever the subscription is re-established as invalidations could have been missed
+func (c *Cache) Subscribe(ctx context.Context, client redis.UniversalClient, channel string, logger *zap.SugaredLogger) {
+	pubsub := client.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	sweep := time.NewTicker(c.ttl)
+	defer sweep.Stop()
+
+	messages := make(chan interface{})
+	go func() {
+		defer close(messages)
+		for {
+			msg, err := pubsub.Receive(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				logger.Errorw("Error while receiving routing cache invalidations. Flushing the cache", "error", err)
+				c.Flush()
+				time.Sleep(time.Second)
+				continue
+			}
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	subscribed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sweep.C:
+			c.Sweep()
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				if subscribed {
+					logger.Info("Resubscribed to routing cache invalidations. Flushing the cache")
+					c.Flush()
+				}
+				subscribed = true
+			case *redis.Message:
+				c.Invalidate(msg.Payload)
+			}
+		}
+	}
+}
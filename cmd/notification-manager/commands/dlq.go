@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/worker"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dlqFilter      pb.DeadLetterFilter
+	dlqLimit       int
+	dlqAfterId     uint64
+	dlqReplayRate  int
+	dlqReplayLimit int
+)
+
+var dlqCmd = &cobra.Command{
+	Use:   "dlq",
+	Short: "Inspects, replays and purges the tasks that failed after their last retry",
+}
+
+var listDlqCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the dead letters of the filter, newest first",
+	Run: func(cmd *cobra.Command, args []string) {
+		Db, logger := openDb()
+
+		filter, err := worker.ParseDeadLetterFilter(&dlqFilter)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		letters, err := Db.ListDeadLetters(context.Background(), filter, dlqAfterId, dlqLimit)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ROW\tID\tCREATED\tWORKER\tTASK\tNOTIFICATION\tTYPE\tSTATUS\tREPLAYS\tLAST ERROR")
+		for _, v := range letters {
+			l := worker.ToDeadLetter(v)
+			fmt.Fprintf(w, "%d\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%d\t%.80v\n",
+				v.Id, l.DeadLetterId, l.CreatedAt, l.WorkerName, l.TaskName, l.NotificationId, l.NotificationType, l.Status, l.ReplayCount, l.LastError)
+		}
+		_ = w.Flush()
+		if len(letters) == dlqLimit {
+			fmt.Printf("\nMore dead letters with --after %d\n", letters[len(letters)-1].Id)
+		}
+	},
+}
+
+var showDlqCmd = &cobra.Command{
+	Use:   "show <dead letter id>",
+	Short: "Prints a dead letter with its task signature",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		Db, logger := openDb()
+
+		letter, err := Db.GetDeadLetter(context.Background(), args[0])
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		out, err := json.MarshalIndent(worker.ToDeadLetter(letter), "", "  ")
+		if err != nil {
+			logger.Fatal(err)
+		}
+		fmt.Println(string(out))
+	},
+}
+
+var replayDlqCmd = &cobra.Command{
+	Use:   "replay [dead letter id...]",
+	Short: "Replays the dead letters of the ids or, without ids, the DEAD letters of the filter",
+	Run: func(cmd *cobra.Command, args []string) {
+		Db, logger := openDb()
+
+		filter, err := worker.ParseDeadLetterFilter(&dlqFilter)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		replayer, err := worker.NewDeadLetterReplayer(Db, logger)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		reply, err := replayer.ReplayDeadLetters(context.Background(), args, filter, dlqReplayLimit, dlqReplayRate)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		for _, v := range reply.Failed {
+			logger.Errorw("Failed to replay dead letter", "deadLetterId", v.DeadLetterId, "error", v.Error)
+		}
+		logger.Infow("Replayed dead letters", "replayed", reply.Replayed, "failed", len(reply.Failed))
+	},
+}
+
+var purgeDlqCmd = &cobra.Command{
+	Use:   "purge [dead letter id...]",
+	Short: "Deletes the dead letters of the ids or of the filter",
+	Run: func(cmd *cobra.Command, args []string) {
+		Db, logger := openDb()
+
+		filter, err := worker.ParseDeadLetterFilter(&dlqFilter)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if len(args) == 0 && filter.IsEmpty() {
+			logger.Fatal("Dead letter ids or a filter is required")
+		}
+
+		purged, err := Db.PurgeDeadLetters(context.Background(), args, filter)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.Infow("Purged dead letters", "purged", purged)
+	},
+}
+
+func registerDlqFilterFlags(c *cobra.Command) {
+	c.Flags().StringVarP(&dlqFilter.WorkerName, "worker", "", "", "Worker the tasks were meant for")
+	c.Flags().StringVarP(&dlqFilter.NotificationType, "notification-type", "", "", "Notification type")
+	c.Flags().StringVarP(&dlqFilter.From, "from", "", "", "Dead-lettered at or after, RFC3339")
+	c.Flags().StringVarP(&dlqFilter.To, "to", "", "", "Dead-lettered before, RFC3339")
+}
+
+func init() {
+	for _, c := range []*cobra.Command{listDlqCmd, showDlqCmd, replayDlqCmd, purgeDlqCmd} {
+		registerFlags(c)
+	}
+	for _, c := range []*cobra.Command{listDlqCmd, replayDlqCmd, purgeDlqCmd} {
+		registerDlqFilterFlags(c)
+	}
+	for _, c := range []*cobra.Command{listDlqCmd, purgeDlqCmd} {
+		c.Flags().StringVarP(&dlqFilter.Status, "status", "", "", contract.DEAD_LETTER_DEAD+" or "+contract.DEAD_LETTER_REPLAYED)
+	}
+
+	listDlqCmd.Flags().IntVarP(&dlqLimit, "limit", "", contract.DEFAULT_LOG_PAGE_SIZE, "Dead letters per page")
+	listDlqCmd.Flags().Uint64VarP(&dlqAfterId, "after", "", 0, "Lists the dead letters older than this row")
+
+	replayDlqCmd.Flags().IntVarP(&dlqReplayLimit, "limit", "", contract.DEFAULT_DEAD_LETTER_REPLAYS, "Most dead letters replayed from the filter")
+	replayDlqCmd.Flags().IntVarP(&dlqReplayRate, "rate", "", 0, "Replays per second. 0 doesn't throttle")
+
+	dlqCmd.AddCommand(listDlqCmd, showDlqCmd, replayDlqCmd, purgeDlqCmd)
+	rootCmd.AddCommand(dlqCmd)
+}
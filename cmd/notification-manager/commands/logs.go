@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Traders-Connect/utils"
+	"github.com/devshahriar/notification-manager/archive"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/db"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var restoreFrom, restoreTo string
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "logs",
+}
+
+var restoreLogsCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restores the archived delivery logs created in [from, to) into the " + contract.RESTORED_LOGS_TABLE + " table",
+	Run: func(cmd *cobra.Command, args []string) {
+		arg := contract.GetWorkerArgs()
+
+		logger, err := utils.NewLogger("notification-manager", "info")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		from, err := parseRestoreTime(restoreFrom)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		to, err := parseRestoreTime(restoreTo)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		store := getArchiveStore(arg)
+		if store == nil {
+			logger.Fatal("No log archive configured. Set --log-archive-dir or --log-archive-s3-bucket")
+		}
+
+		DBDsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", arg.DbUser, arg.DbPass, arg.DbHost, arg.DbName)
+		Db, err := db.NewMysql(DBDsn, logger)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		ctx := context.Background()
+		logs, err := archive.ReadRange(ctx, store, from, to)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		restored, err := Db.RestoreLogs(ctx, logs)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.Infow("Restored archived logs", "from", from, "to", to, "archived", len(logs), "restored", restored, "table", contract.RESTORED_LOGS_TABLE)
+	},
+}
+
+func init() {
+	registerFlags(restoreLogsCmd)
+	restoreLogsCmd.Flags().StringVarP(&restoreFrom, "from", "", "", "Start of the range as 2006-01-02 or RFC3339")
+	restoreLogsCmd.Flags().StringVarP(&restoreTo, "to", "", "", "Exclusive end of the range as 2006-01-02 or RFC3339")
+	_ = restoreLogsCmd.MarkFlagRequired("from")
+	_ = restoreLogsCmd.MarkFlagRequired("to")
+
+	logsCmd.AddCommand(restoreLogsCmd)
+	rootCmd.AddCommand(logsCmd)
+}
+
+func parseRestoreTime(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// getArchiveStore returns the log archive of the flags or nil when archival is disabled
+func getArchiveStore(arg *contract.WorkerArgs) archive.Store {
+	return archive.NewStore(archive.Config{
+		Dir:         arg.LogArchiveDir,
+		S3Endpoint:  arg.LogArchiveS3Endpoint,
+		S3Bucket:    arg.LogArchiveS3Bucket,
+		S3Region:    arg.LogArchiveS3Region,
+		S3AccessKey: arg.LogArchiveS3Key,
+		S3SecretKey: arg.LogArchiveS3Secret,
+	})
+}
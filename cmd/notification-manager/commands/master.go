@@ -6,10 +6,12 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/Traders-Connect/utils"
 	"github.com/devshahriar/notification-manager/contract"
 	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/tracing"
 	"github.com/devshahriar/notification-manager/worker"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -39,13 +41,20 @@ var masterCmd = &cobra.Command{
 		}
 
 		w := &worker.Worker{
-			Name:         arg.Name,
-			WorkerType:   arg.WorkerType,
-			Concurrency:  arg.Concurrency,
-			WorkerConfig: arg.WorkerConfig,
-			Logger:       logger,
-			Db:           Db,
+			Name:            arg.Name,
+			WorkerType:      arg.WorkerType,
+			Settings:        workerSettings(arg),
+			WorkerConfig:    arg.WorkerConfig,
+			Logger:          logger,
+			Db:              Db,
+			ShutdownTimeout: time.Duration(arg.ShutdownTimeout) * time.Second,
 		}
+		//Ingesting master meta so slaves will be able to send failed deliveries back for fallback.
+		//Worker type is forced as deployments set it to the worker name
+		arg.WorkerType = contract.MASTER
+		w.WorkerType = contract.MASTER
+		registerWorker(w, arg)
+
 		w.InitTaskFactory()
 		w.InitMachineryWorker()
 
@@ -57,8 +66,31 @@ var masterCmd = &cobra.Command{
 			sig := <-sigs
 			log.Info("received signals", "signal", sig.String())
 			cancel()
+
+			//A second signal exits without waiting for the tasks in flight
+			<-sigs
+			log.Info("received second signal, exiting without draining")
+			os.Exit(1)
 		}()
 
+		tracing.Init(ctx, arg.Name, arg.TraceCollectorUrl, float64(arg.TraceSamplePercent)/100, logger)
+		w.StartHeartbeat(ctx)
+		w.StartWorkerPoolRefresh(ctx)
+		w.Dependencies = startHealthChecks(ctx, Db, arg, w.ConsumerCheck, logger)
+		if arg.HealthAddr != "" {
+			w.ServeHealth(ctx, arg.HealthAddr)
+		}
+
+		if arg.RoutingCacheTTL > 0 {
+			w.InitRoutingCache(ctx, time.Duration(arg.RoutingCacheTTL)*time.Second)
+		}
+
+		w.StartLogRetention(ctx, worker.RetentionPolicy{
+			Retention:  time.Duration(arg.LogRetentionDays) * 24 * time.Hour,
+			Compaction: time.Duration(arg.LogCompactDays) * 24 * time.Hour,
+			Store:      getArchiveStore(arg),
+		})
+
 		w.Run(ctx)
 	},
 }
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "secrets",
+}
+
+var rotateSecretsCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Re-encrypts the stored bot tokens with the primary secret key. Plaintext tokens get encrypted",
+	Run: func(cmd *cobra.Command, args []string) {
+		Db, logger := openDb()
+
+		rotated, err := Db.RotateBotTokens(context.Background())
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.Infow("Rotated bot tokens", "rotated", rotated)
+	},
+}
+
+var redactLogsCmd = &cobra.Command{
+	Use:   "redact-logs",
+	Short: "Removes the bot tokens older releases wrote into the delivery logs",
+	Run: func(cmd *cobra.Command, args []string) {
+		Db, logger := openDb()
+
+		redacted, err := Db.RedactLogSecrets(context.Background())
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.Infow("Redacted delivery logs", "redacted", redacted)
+	},
+}
+
+func init() {
+	registerFlags(rotateSecretsCmd)
+	registerFlags(redactLogsCmd)
+
+	secretsCmd.AddCommand(rotateSecretsCmd, redactLogsCmd)
+	rootCmd.AddCommand(secretsCmd)
+}
@@ -8,9 +8,12 @@ import (
 	"syscall"
 
 	"github.com/Traders-Connect/utils"
+	"github.com/devshahriar/notification-manager/cache"
 	"github.com/devshahriar/notification-manager/contract"
 	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/health"
 	"github.com/devshahriar/notification-manager/server"
+	"github.com/devshahriar/notification-manager/tracing"
 	"github.com/devshahriar/notification-manager/worker"
 	"github.com/spf13/cobra"
 )
@@ -48,6 +51,7 @@ var serverCmd = &cobra.Command{
 		mServer := server.GetMachineryServer()
 
 		service := server.NewNotificationService(Db, mServer, logger, contract.GetServerAgrs())
+		service.Redis = cache.NewRedisClient(arg.WorkerConfig.ResultBackend)
 		if service.MachinaryServer == nil {
 			logger.Info("mserver is nil")
 		} else {
@@ -62,6 +66,8 @@ var serverCmd = &cobra.Command{
 			logger.Infow("received signals", "signal", sig.String())
 			cancel()
 		}()
+		tracing.Init(ctx, "notification-server", arg.TraceCollectorUrl, float64(arg.TraceSamplePercent)/100, logger)
+		service.Health = startHealthChecks(ctx, Db, arg, health.AMQPCheck(arg.WorkerConfig.Broker), logger)
 		service.Run(ctx)
 
 	},
@@ -80,6 +86,8 @@ func registerServiceFlags(c *cobra.Command) {
 	c.Flags().StringVarP(&serviceArgs.Addr, "addr", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_ADDR", "0.0.0.0:9030"), "Grpc service address")
 	c.Flags().StringVarP(&serviceArgs.AddrInt, "addr-int", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_ADDR_INT", "0.0.0.0:9031"), "Grpc service port")
 	c.Flags().StringVarP(&serviceArgs.MetricsAddr, "metrics-addr", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_METRICS_ADDR", "0.0.0.0:9035"), "Grpc service prometheus metrics address")
+	c.Flags().StringVarP(&serviceArgs.HttpAddr, "http-addr", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_HTTP_ADDR", "0.0.0.0:9032"), "Http service address for acknowledgement links")
+	c.Flags().StringVarP(&serviceArgs.HttpMetricsAddr, "http-metrics-addr", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_HTTP_METRICS_ADDR", "0.0.0.0:9036"), "Http service prometheus metrics address")
 
 	c.Flags().StringVarP(&serviceArgs.TelegramBotToken, "telegram-bot-token", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_TELEGRAM_BOT_TOKEN", ""), "Telegram default bot token")
 }
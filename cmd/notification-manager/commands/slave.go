@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/Traders-Connect/utils"
 	log "github.com/sirupsen/logrus"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/devshahriar/notification-manager/contract"
 	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/tracing"
 	"github.com/devshahriar/notification-manager/worker"
 )
 
@@ -39,28 +41,60 @@ var slaveCmd = &cobra.Command{
 			logger.Fatal(err)
 		}
 
+		retryPolicies, err := worker.ParseRetryPolicies(arg.DeliveryRetries)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
 		w := &worker.Worker{
-			Name:         arg.Name,
-			WorkerType:   arg.WorkerType,
-			Concurrency:  arg.Concurrency,
-			WorkerConfig: arg.WorkerConfig,
-			Logger:       logger,
-			Db:           db,
+			Name:            arg.Name,
+			WorkerType:      arg.WorkerType,
+			Settings:        workerSettings(arg),
+			WorkerConfig:    arg.WorkerConfig,
+			Logger:          logger,
+			Db:              db,
+			RetryPolicies:   retryPolicies,
+			ShutdownTimeout: time.Duration(arg.ShutdownTimeout) * time.Second,
 		}
 
-		w.InitTaskFactory()
-		w.InitMachineryWorker()
+		if arg.CircuitBreaker != "" {
+			settings, err := worker.ParseBreakerSettings(arg.CircuitBreaker)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			w.Breakers = worker.NewBreakers(settings)
+		}
 
 		//Ingesting slave meta in worker meta table so master worker will be able to discover slave
-		w.Db.IngestWorkerMeta(arg)
+		registerWorker(w, arg)
+
+		w.InitTaskFactory()
+		w.InitMachineryWorker()
 
 		ctx, cancel := context.WithCancel(context.Background())
 		go func() {
 			sig := <-sigs
 			log.Info("received signals", "signal", sig.String())
 			cancel()
+
+			//A second signal exits without waiting for the tasks in flight
+			<-sigs
+			log.Info("received second signal, exiting without draining")
+			os.Exit(1)
 		}()
 
+		tracing.Init(ctx, arg.Name, arg.TraceCollectorUrl, float64(arg.TraceSamplePercent)/100, logger)
+		w.StartHeartbeat(ctx)
+		w.StartSettingsRefresh(ctx)
+		w.Dependencies = startHealthChecks(ctx, db, arg, w.ConsumerCheck, logger)
+		if arg.HealthAddr != "" {
+			w.ServeHealth(ctx, arg.HealthAddr)
+		}
+
+		if arg.RoutingCacheTTL > 0 {
+			w.InitRoutingCache(ctx, time.Duration(arg.RoutingCacheTTL)*time.Second)
+		}
+
 		w.Run(ctx)
 
 	},
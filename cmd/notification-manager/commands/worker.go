@@ -1,11 +1,19 @@
 package commands
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/Traders-Connect/utils"
+	"github.com/devshahriar/notification-manager/cache"
 	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/health"
+	"github.com/devshahriar/notification-manager/worker"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 )
 
 func init() {
@@ -37,6 +45,21 @@ func registerFlags(c *cobra.Command) {
 		log.Fatal(err)
 	}
 	c.Flags().IntVarP(&args.Concurrency, "concurrency", "c", int(wc), "Concurrency value for a worker")
+	prefetch, err := utils.LookupEnvOrInt64("NOTIFICATION_MANAGER_PREFETCH", contract.DEFAULT_PREFETCH_COUNT)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.Flags().IntVarP(&args.PrefetchCount, "prefetch", "", int(prefetch), "Tasks the broker delivers to a worker ahead of processing")
+	providerTimeout, err := utils.LookupEnvOrInt64("NOTIFICATION_MANAGER_PROVIDER_TIMEOUT", contract.DELIVERY_TIMEOUT_SECONDS)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.Flags().IntVarP(&args.ProviderTimeout, "provider-timeout", "", int(providerTimeout), "Seconds a call to the provider of a slave may take")
+	rateLimit, err := utils.LookupEnvOrInt64("NOTIFICATION_MANAGER_RATE_LIMIT", 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.Flags().IntVarP(&args.RateLimit, "rate-limit", "", int(rateLimit), "Provider calls per second of each slave replica. 0 is unlimited")
 
 	// db
 	c.Flags().StringVarP(&args.DbHost, "db-host", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_DB_HOST", "db:3306"), "DB host name")
@@ -47,4 +70,111 @@ func registerFlags(c *cobra.Command) {
 	//mailgun creds
 	c.Flags().StringVarP(&args.EmailApiKey, "email-api-key", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_EMAIL_API_KEY", ""), "Email api key")
 	c.Flags().StringVarP(&args.EmailBaseUrl, "email-baseurl", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_EMAIL_BASE_URL", ""), "Email base key")
+
+	//escalation
+	c.Flags().StringVarP(&args.PublicUrl, "public-url", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_PUBLIC_URL", "http://localhost:9032"), "Public url of the http server used in acknowledgement links")
+
+	//routing cache
+	ttl, err := utils.LookupEnvOrInt64("NOTIFICATION_MANAGER_ROUTING_CACHE_TTL", 300)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.Flags().IntVarP(&args.RoutingCacheTTL, "routing-cache-ttl", "", int(ttl), "Seconds the workers cache routing plans and notification meta. 0 disables the cache")
+
+	//tracing
+	c.Flags().StringVarP(&args.TraceCollectorUrl, "trace-collector-url", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_TRACE_COLLECTOR_URL", ""), "Zipkin compatible span endpoint ex: http://localhost:9411/api/v2/spans. Empty disables export")
+	sample, err := utils.LookupEnvOrInt64("NOTIFICATION_MANAGER_TRACE_SAMPLE_PERCENT", 10)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.Flags().IntVarP(&args.TraceSamplePercent, "trace-sample-percent", "", int(sample), "Percent of the notifications traced")
+
+	//log retention
+	retention, err := utils.LookupEnvOrInt64("NOTIFICATION_MANAGER_LOG_RETENTION_DAYS", 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.Flags().IntVarP(&args.LogRetentionDays, "log-retention-days", "", int(retention), "Days the delivery logs are kept. 0 keeps them forever")
+	compact, err := utils.LookupEnvOrInt64("NOTIFICATION_MANAGER_LOG_COMPACT_DAYS", 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.Flags().IntVarP(&args.LogCompactDays, "log-compact-days", "", int(compact), "Days after which the rendered message is dropped from the delivery logs. 0 disables compaction")
+	c.Flags().StringVarP(&args.LogArchiveDir, "log-archive-dir", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_LOG_ARCHIVE_DIR", ""), "Directory expired logs are archived to. Empty disables archival unless a bucket is set")
+	c.Flags().StringVarP(&args.LogArchiveS3Endpoint, "log-archive-s3-endpoint", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_LOG_ARCHIVE_S3_ENDPOINT", ""), "S3 compatible endpoint ex: http://minio:9000. Empty uses AWS S3")
+	c.Flags().StringVarP(&args.LogArchiveS3Bucket, "log-archive-s3-bucket", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_LOG_ARCHIVE_S3_BUCKET", ""), "Bucket expired logs are archived to")
+	c.Flags().StringVarP(&args.LogArchiveS3Region, "log-archive-s3-region", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_LOG_ARCHIVE_S3_REGION", "us-east-1"), "Region of the archive bucket")
+	c.Flags().StringVarP(&args.LogArchiveS3Key, "log-archive-s3-access-key", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_LOG_ARCHIVE_S3_ACCESS_KEY", ""), "Access key of the archive bucket")
+	c.Flags().StringVarP(&args.LogArchiveS3Secret, "log-archive-s3-secret-key", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_LOG_ARCHIVE_S3_SECRET_KEY", ""), "Secret key of the archive bucket")
+
+	//delivery retries
+	c.Flags().StringVarP(&args.DeliveryRetries, "delivery-retries", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_DELIVERY_RETRIES", contract.DEFAULT_DELIVERY_RETRIES), "Retries of transient delivery errors per channel as channel=attempts:base delay:max delay separated by commas")
+
+	//circuit breakers
+	c.Flags().StringVarP(&args.CircuitBreaker, "circuit-breaker", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_CIRCUIT_BREAKER", contract.DEFAULT_CIRCUIT_BREAKER), "Circuit breaker of each provider and bot token as error percent:min requests:window:open duration. Empty disables the breakers")
+	c.Flags().StringVarP(&args.HealthAddr, "health-addr", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_HEALTH_ADDR", "0.0.0.0:9037"), "Address of the /health and /metrics endpoint of the worker. Empty disables it")
+
+	//shutdown
+	shutdown, err := utils.LookupEnvOrInt64("NOTIFICATION_MANAGER_SHUTDOWN_TIMEOUT", contract.DEFAULT_SHUTDOWN_TIMEOUT_SECONDS)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.Flags().IntVarP(&args.ShutdownTimeout, "shutdown-timeout", "", int(shutdown), "Seconds the tasks in flight get to finish on SIGTERM before the worker exits and the broker requeues them")
+
+	//secrets
+	c.Flags().StringVarP(&args.SecretKeys, "secret-keys", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_SECRET_KEYS", ""), "Keys encrypting the stored bot tokens as id:base64 32 byte key pairs separated by commas. Empty stores them in plaintext")
+	c.Flags().StringVarP(&args.SecretPrimaryKeyId, "secret-primary-key-id", "", utils.LookupEnvOrString("NOTIFICATION_MANAGER_SECRET_PRIMARY_KEY_ID", ""), "Id of the key new secrets are encrypted with. Empty uses the first key")
+}
+
+// openDb connects to the database of the flags for the maintenance commands
+func openDb() (db.DB, *zap.SugaredLogger) {
+	arg := contract.GetWorkerArgs()
+
+	logger, err := utils.NewLogger("notification-manager", "info")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	DBDsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", arg.DbUser, arg.DbPass, arg.DbHost, arg.DbName)
+	Db, err := db.NewMysql(DBDsn, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return Db, logger
+}
+
+// workerSettings are the settings of the flags that a worker publishes when it registers
+func workerSettings(arg *contract.WorkerArgs) contract.WorkerSettings {
+	return contract.WorkerSettings{
+		Concurrency:            arg.Concurrency,
+		PrefetchCount:          arg.PrefetchCount,
+		ProviderTimeoutSeconds: arg.ProviderTimeout,
+		RateLimit:              arg.RateLimit,
+	}
+}
+
+// registerWorker registers the worker before it consumes so it starts with the stored settings,
+// which win over the flags once the worker is registered
+func registerWorker(w *worker.Worker, arg *contract.WorkerArgs) {
+	stored, err := w.Db.IngestWorkerMeta(arg)
+	if err != nil {
+		w.Logger.Errorw("Error while registering worker. Running with the settings of the flags", "error", err)
+		return
+	}
+	if flags := workerSettings(arg); flags != stored {
+		w.Logger.Warnw("Settings of the flags differ from the stored settings. Running with the stored settings, change them with SetWorkerSettings",
+			"flags", flags, "stored", stored)
+	}
+	w.Settings = stored
+}
+
+// startHealthChecks polls the database, the broker and the result backend until ctx is done.
+// Workers check the broker with the state of their consumer, the server with a connection of its own
+func startHealthChecks(ctx context.Context, Db db.DB, arg *contract.WorkerArgs, brokerCheck health.Check, logger *zap.SugaredLogger) *health.Checker {
+	checker := health.NewChecker(contract.HEALTH_CHECK_INTERVAL_SECONDS*time.Second, contract.HEALTH_CHECK_TIMEOUT_SECONDS*time.Second, logger)
+	checker.Add(contract.HEALTH_MYSQL, Db.Ping)
+	checker.Add(contract.HEALTH_BROKER, brokerCheck)
+	checker.Add(contract.HEALTH_RESULT_BACKEND, health.RedisCheck(cache.NewRedisClient(arg.WorkerConfig.ResultBackend)))
+	checker.Start(ctx)
+	return checker
 }
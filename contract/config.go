@@ -16,6 +16,32 @@ type WorkerArgs struct {
 	DbName       string
 	EmailApiKey  string
 	EmailBaseUrl string
+	PublicUrl    string
+
+	RoutingCacheTTL int // Seconds. 0 disables the routing cache of the workers
+
+	TraceCollectorUrl  string // Zipkin v2 json endpoint. Empty disables span export
+	TraceSamplePercent int
+
+	LogRetentionDays     int // 0 keeps the logs forever
+	LogCompactDays       int // Days after which the rendered message is dropped from the logs. 0 disables compaction
+	LogArchiveDir        string
+	LogArchiveS3Endpoint string
+	LogArchiveS3Bucket   string
+	LogArchiveS3Region   string
+	LogArchiveS3Key      string
+	LogArchiveS3Secret   string
+
+	DeliveryRetries string // channel=attempts:base delay:max delay policies
+	CircuitBreaker  string // error percent:min requests:window:open duration. Empty disables the breakers
+	HealthAddr      string // Address of the health and metrics endpoint of the workers. Empty disables it
+	ShutdownTimeout int    // Seconds the tasks in flight get to finish on shutdown
+	PrefetchCount   int
+	ProviderTimeout int // Seconds a call to the provider may take
+	RateLimit       int // Provider calls per second of a slave replica. 0 is unlimited
+
+	SecretKeys         string // id:base64 key pairs encrypting the stored credentials. Empty stores them in plaintext
+	SecretPrimaryKeyId string
 }
 
 type ServiceArgs struct {
@@ -24,6 +50,8 @@ type ServiceArgs struct {
 	AddrInt          string
 	MetricsAddr      string
 	TelegramBotToken string
+	HttpAddr         string
+	HttpMetricsAddr  string
 }
 
 func GetWorkerArgs() *WorkerArgs {
@@ -2,6 +2,7 @@ package contract
 
 import (
 	"strings"
+	"time"
 
 	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
 )
@@ -13,8 +14,298 @@ const (
 	TELEGRAM                  = "telegram"
 	DISCORD                   = "discord"
 	DefaultTelegramBot        = "Traders connect"
+	MASTER                    = "master"
 )
 
+// Delivery log status
+const (
+	STATUS_SUCCESS            = "SUCCESS"
+	STATUS_FAILED             = "FAILED"
+	STATUS_FALLBACK           = "FALLBACK"
+	STATUS_FALLBACK_EXHAUSTED = "FALLBACK_EXHAUSTED"
+	STATUS_DEAD_LETTER        = "DEAD_LETTER"
+)
+
+// Task headers
+const (
+	HEADER_CHAIN_ID      = "chain_id"
+	HEADER_FILTERED_BOTS = "filtered_bots"
+	HEADER_SCHEDULED_ID  = "scheduled_id"
+	HEADER_BROADCAST_ID  = "broadcast_id"
+
+	HEADER_NOTIFICATION_ID = "notification_id"
+	HEADER_LAST_ERROR      = "last_error"
+	HEADER_DEAD_LETTER_ID  = "dead_letter_id"
+
+	HEADER_PARKED_DESTINATIONS = "parked_destinations"
+	HEADER_PARK_COUNT          = "park_count"
+)
+
+// Notification lifecycle states. A transition without notification type is about the notification itself
+const (
+	NOTIFICATION_ACCEPTED = "ACCEPTED"
+	NOTIFICATION_ROUTED   = "ROUTED"
+	NOTIFICATION_SKIPPED  = "SKIPPED"
+	NOTIFICATION_SENT     = "SENT"
+	NOTIFICATION_FAILED   = "FAILED"
+	NOTIFICATION_RETRYING = "RETRYING"
+
+	NOTIFICATION_DEAD_LETTERED = "DEAD_LETTERED"
+)
+
+// Delivery errors are retried when transient and given up on when permanent
+const (
+	ERROR_TRANSIENT = "transient"
+	ERROR_PERMANENT = "permanent"
+
+	//ERROR_BREAKER_OPEN classifies the deliveries an open circuit breaker kept from the provider in the metrics
+	ERROR_BREAKER_OPEN = "breaker_open"
+)
+
+// Delivery retries of the channels without a retry policy. DEFAULT_DELIVERY_RETRIES is the default of --delivery-retries
+const (
+	DEFAULT_DELIVERY_ATTEMPTS          = 3
+	DEFAULT_DELIVERY_BASE_DELAY_MS     = 1000
+	DEFAULT_DELIVERY_MAX_DELAY_SECONDS = 30
+	DEFAULT_DELIVERY_RETRIES           = "email=3:1s:30s,telegram=3:1s:30s,discord=3:1s:30s"
+	DELIVERY_TIMEOUT_SECONDS           = 30
+)
+
+// Throughput of the workers. DEFAULT_PREFETCH_COUNT is the default of --prefetch
+const (
+	DEFAULT_PREFETCH_COUNT          = 150
+	WORKER_SETTINGS_REFRESH_SECONDS = 10
+)
+
+// Circuit breakers of the providers. DEFAULT_CIRCUIT_BREAKER is the default of --circuit-breaker
+const (
+	BREAKER_CLOSED    = "CLOSED"
+	BREAKER_OPEN      = "OPEN"
+	BREAKER_HALF_OPEN = "HALF_OPEN"
+
+	DEFAULT_CIRCUIT_BREAKER = "50:20:60s:30s"
+	MAX_TASK_PARKS          = 20 // Times a task is parked on open breakers before its deliveries fail
+)
+
+// Machinery retries of the tasks failing before they reach a provider, like a failed db read. Seconds
+const (
+	TASK_RETRY_COUNT   = 1
+	TASK_RETRY_TIMEOUT = 100
+)
+
+// Scheduled notification status
+const (
+	SCHEDULED_PENDING   = "SCHEDULED"
+	SCHEDULED_SENT      = "SENT"
+	SCHEDULED_CANCELLED = "CANCELLED"
+	SCHEDULED_FAILED    = "FAILED"
+)
+
+// ExplainRoute reasons
+const (
+	ROUTE_SEND                 = "SEND"
+	ROUTE_FALLBACK_STANDBY     = "FALLBACK_STANDBY"
+	ROUTE_INTEGRATION_DISABLED = "INTEGRATION_DISABLED"
+	ROUTE_CONFIG_DISABLED      = "CONFIG_DISABLED"
+	ROUTE_ACCOUNT_BLOCKED      = "ACCOUNT_BLOCKED"
+	ROUTE_CHANNEL_BLOCKED      = "CHANNEL_BLOCKED"
+	ROUTE_BOT_DISABLED         = "BOT_DISABLED"
+	ROUTE_NO_CHANNEL           = "NO_CHANNEL"
+	ROUTE_NO_BOT               = "NO_BOT"
+	ROUTE_NO_WORKER            = "NO_WORKER"
+	ROUTE_FILTERED             = "FILTERED"
+	ROUTE_DUPLICATE            = "DUPLICATE"
+)
+
+// Broadcast status
+const (
+	BROADCAST_RUNNING   = "RUNNING"
+	BROADCAST_PAUSED    = "PAUSED"
+	BROADCAST_CANCELLED = "CANCELLED"
+	BROADCAST_COMPLETED = "COMPLETED"
+)
+
+// BROADCAST is the event type slaves log broadcast deliveries with
+const BROADCAST = "BROADCAST"
+
+// TASK_BROADCAST_BATCH is the master task sending the next batch of a broadcast
+const TASK_BROADCAST_BATCH = "task_broadcast_batch"
+
+// TASK_ROUTE_ENVELOPE is the master task routing a notification sent as a TaskEnvelope.
+// The positional task_route_notification is still accepted during rolling upgrades
+const TASK_ROUTE_ENVELOPE = "task_route_envelope"
+
+// TASK_ENVELOPE_VERSION is the TaskEnvelope version the workers send and the newest they accept
+const TASK_ENVELOPE_VERSION = 1
+
+// TASK_ENVELOPE_RETRY_SECONDS is how long a worker that isn't upgraded yet requeues an envelope of a newer version for
+const TASK_ENVELOPE_RETRY_SECONDS = 30
+
+// TASK_ROUTE_NOTIFICATIONS is the master task routing a chunk of batch ingested notifications
+const TASK_ROUTE_NOTIFICATIONS = "task_route_notifications"
+
+// Batch ingestion limits. Accepted events are published to the master in chunks
+// with a few chunks in flight at a time
+const (
+	MAX_INGEST_BATCH_SIZE      = 5000
+	INGEST_CHUNK_SIZE          = 100
+	INGEST_PUBLISH_CONCURRENCY = 4
+)
+
+// NotificationEvent is a batch ingested notification in the chunks sent to the master
+type NotificationEvent struct {
+	NotificationId string
+	UserId         string
+	AccountId      string
+	EventType      string
+	Data           map[string]string
+}
+
+// Broadcast throttling defaults
+const (
+	DEFAULT_BROADCAST_BATCH_SIZE             = 100
+	DEFAULT_BROADCAST_BATCH_INTERVAL_SECONDS = 1
+)
+
+// Delivery log pagination and export
+const (
+	DEFAULT_LOG_PAGE_SIZE = 50
+	MAX_LOG_PAGE_SIZE     = 500
+	MAX_LOG_EXPORT_ROWS   = 100000
+	LOG_EXPORT_CSV        = "csv"
+	LOG_EXPORT_JSON       = "json"
+)
+
+// LogFilter selects delivery logs. Empty fields don't filter
+type LogFilter struct {
+	UserConfig       string
+	AccountId        string
+	EventType        string
+	NotificationType string
+	Status           string
+	From             *time.Time
+	To               *time.Time
+}
+
+// Log retention. The master purges and compacts the logs in batches of LOG_PURGE_BATCH_SIZE every
+// LOG_RETENTION_INTERVAL_MINUTES and pauses between batches so replication and other writers keep up.
+// Restored archives are inserted into RESTORED_LOGS_TABLE so the next purge doesn't delete them again
+const (
+	LOG_RETENTION_INTERVAL_MINUTES = 60
+	LOG_PURGE_BATCH_SIZE           = 1000
+	LOG_PURGE_BATCH_PAUSE_MS       = 200
+	RESTORED_LOGS_TABLE            = "restored_logs"
+)
+
+// Dead letters. A task that failed after its last retry is published to the <exchange>.dlx exchange of its worker,
+// into a queue named after its binding key with the .dlq suffix, where it expires after DEAD_LETTER_TTL_DAYS.
+// It is also stored with its last error and the stored row is what gets listed, replayed and purged
+const (
+	DEAD_LETTER_EXCHANGE_SUFFIX = ".dlx"
+	DEAD_LETTER_QUEUE_SUFFIX    = ".dlq"
+	DEAD_LETTER_TTL_DAYS        = 14
+	DEAD_LETTER_DEAD            = "DEAD"
+	DEAD_LETTER_REPLAYED        = "REPLAYED"
+	DEFAULT_DEAD_LETTER_REPLAYS = 1000
+)
+
+// DeadLetterFilter selects dead letters. Empty fields don't filter
+type DeadLetterFilter struct {
+	WorkerName       string
+	NotificationType string
+	Status           string
+	From             *time.Time
+	To               *time.Time
+}
+
+// IsEmpty tells if the filter selects every dead letter
+func (f DeadLetterFilter) IsEmpty() bool {
+	return f.WorkerName == "" && f.NotificationType == "" && f.Status == "" && f.From == nil && f.To == nil
+}
+
+// Templates a logged delivery is resent with
+const (
+	RESEND_TEMPLATE_ORIGINAL = "original"
+	RESEND_TEMPLATE_CURRENT  = "current"
+)
+
+// SECRET_ROTATION_BATCH_SIZE is the number of bot configs read per batch while rotating the secret keys
+const SECRET_ROTATION_BATCH_SIZE = 100
+
+// Version of the build. Set with -ldflags "-X github.com/devshahriar/notification-manager/contract.Version=<version>"
+var Version = "dev"
+
+// Workers send a heartbeat every interval. A worker is unavailable when none of its instances
+// sent one within the timeout. Workers that never sent a heartbeat are assumed available
+const (
+	WORKER_HEARTBEAT_INTERVAL_SECONDS = 10
+	WORKER_HEARTBEAT_TIMEOUT_SECONDS  = 30
+	WORKER_POOL_REFRESH_SECONDS       = 10
+	WORKER_HEARTBEAT_RETENTION_HOURS  = 24
+)
+
+// Worker health
+const (
+	WORKER_HEALTHY     = "HEALTHY"
+	WORKER_UNAVAILABLE = "UNAVAILABLE"
+	WORKER_UNKNOWN     = "UNKNOWN"
+	WORKER_DEGRADED    = "DEGRADED" // A circuit breaker of the worker is open
+)
+
+// Dependencies checked by the gRPC health service and the /ready endpoint of the workers.
+// They are the service names of the gRPC health checks, the empty service name checks them all
+const (
+	HEALTH_MYSQL          = "mysql"
+	HEALTH_BROKER         = "amqp"
+	HEALTH_RESULT_BACKEND = "redis"
+
+	HEALTH_CHECK_INTERVAL_SECONDS = 10
+	HEALTH_CHECK_TIMEOUT_SECONDS  = 5
+
+	CONSUMER_RECONNECT_SECONDS = 5 // Wait before a worker consumes again once its connection to the broker is lost
+)
+
+// DEFAULT_SHUTDOWN_TIMEOUT_SECONDS fits the drain of the workers in the default termination grace period of Kubernetes
+const DEFAULT_SHUTDOWN_TIMEOUT_SECONDS = 25
+
+// ROUTING_INVALIDATION_CHANNEL is the redis channel the server publishes the user config id of changed configs on
+const ROUTING_INVALIDATION_CHANNEL = "notification_manager_routing_invalidation"
+
+// METADATA_PLATFORM is the grpc metadata key of IntAddAccountConfig carrying the trading platform of the account e.g. MT5
+const METADATA_PLATFORM = "x-platform"
+
+// Escalation status
+const (
+	ESCALATION_PENDING      = "PENDING"
+	ESCALATION_ACKNOWLEDGED = "ACKNOWLEDGED"
+	ESCALATION_RESOLVED     = "RESOLVED"
+	ESCALATION_EXHAUSTED    = "EXHAUSTED"
+)
+
+// ACK_URL is the key of the acknowledgement link in the notification data.
+// Message templates can place it with %ACK_URL%
+const ACK_URL = "ACK_URL"
+
+// Connection flap suppression defaults used when the user has no flap settings
+const (
+	DEFAULT_FLAP_ENABLED              = false // Users opt in with SetFlapSettings
+	DEFAULT_FLAP_GRACE_PERIOD_SECONDS = 60
+	DEFAULT_FLAP_THRESHOLD            = 4
+	DEFAULT_FLAP_WINDOW_SECONDS       = 600
+)
+
+// Account connection states
+const (
+	CONNECTION_CONNECTED    = "CONNECTED"
+	CONNECTION_DISCONNECTED = "DISCONNECTED"
+	CONNECTION_UNSTABLE     = "UNSTABLE"
+)
+
+// ResolvingEvents maps an event to the events whose escalations it stops
+var ResolvingEvents map[string][]string = map[string][]string{
+	nm.EventType_ACCOUNT_CONNECTED.String(): {nm.EventType_ACCOUNT_CONNECTION_ERROR.String()},
+}
+
 var NotificationType map[string]bool = map[string]bool{
 	strings.ToLower(nm.NotificationType_EMAIL.String()):    true,
 	strings.ToLower(nm.NotificationType_TELEGRAM.String()): true,
@@ -32,6 +323,16 @@ type WorkerMeta struct {
 	ExchangeType     string
 	BindingKey       string // Routing key
 	NotificationType string
+	WorkerSettings
+}
+
+// WorkerSettings tune the throughput of a slave. Slaves publish them in worker meta when first registered
+// and pick up the changes made with SetWorkerSettings while they run
+type WorkerSettings struct {
+	Concurrency            int
+	PrefetchCount          int
+	ProviderTimeoutSeconds int // Timeout of a call to the provider
+	RateLimit              int // Provider calls per second of each replica. 0 is unlimited
 }
 
 type EnabledNotification struct {
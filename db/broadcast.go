@@ -0,0 +1,327 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"gorm.io/gorm"
+)
+
+// segmentQuery selects the user configs of a broadcast segment
+func (m *Mysql) segmentQuery(ctx context.Context, segment *pb.BroadcastSegment) (*gorm.DB, error) {
+	query := m.DB.WithContext(ctx).Table("user_configs uc")
+	if segment == nil {
+		return query, nil
+	}
+
+	if len(segment.UserIds) > 0 {
+		query = query.Where("uc.user_id IN ?", segment.UserIds)
+	}
+
+	if len(segment.EnabledNotificationTypes) > 0 {
+		conditions := []string{}
+		for _, v := range segment.EnabledNotificationTypes {
+			column := GetColumnName(strings.ToUpper(v))
+			if column == "" {
+				return nil, fmt.Errorf("invalid notification type in segment: %v", v)
+			}
+			conditions = append(conditions, fmt.Sprintf("uc.%v = true", column))
+		}
+		query = query.Where("(" + strings.Join(conditions, " OR ") + ")")
+	}
+
+	if len(segment.Platforms) > 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM account_configs ac WHERE ac.config_id = uc.id AND ac.platform IN ?)", segment.Platforms)
+	}
+	return query, nil
+}
+
+func (m *Mysql) CreateBroadcast(ctx context.Context, broadcast *model.Broadcasts, segment *pb.BroadcastSegment) error {
+	fName := "CreateBroadcast"
+	start := time.Now()
+
+	query, err := m.segmentQuery(ctx, segment)
+	if err != nil {
+		return err
+	}
+
+	segmentBytes, err := json.Marshal(segment)
+	if err != nil {
+		return err
+	}
+	broadcast.Segment = segmentBytes
+
+	err = query.Count(&broadcast.TotalUsers).Error
+	if err == nil {
+		err = m.DB.WithContext(ctx).Create(broadcast).Error
+	}
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While creating broadcast:%v err:%+v", broadcast.Name, err),
+		fmt.Sprintf("Success: Created broadcast:%v for %v users", broadcast.UuId, broadcast.TotalUsers),
+		start)
+
+	return err
+}
+
+func (m *Mysql) GetBroadcast(ctx context.Context, broadcastId string) (model.Broadcasts, error) {
+
+	broadcast := model.Broadcasts{}
+	err := m.DB.WithContext(ctx).Where("uu_id = ?", broadcastId).First(&broadcast).Error
+	if err != nil {
+		m.Log.Errorw("Error getting broadcast", "broadcastId", broadcastId, "error", err)
+		return broadcast, fmt.Errorf("broadcast doesn't exist id: %v", broadcastId)
+	}
+	return broadcast, nil
+}
+
+// GetBroadcastStatus returns the progress of a broadcast with the delivery counts of each notification type
+func (m *Mysql) GetBroadcastStatus(ctx context.Context, broadcastId string) (*pb.BroadcastStatus, error) {
+	fName := "GetBroadcastStatus"
+	start := time.Now()
+
+	broadcast, err := m.GetBroadcast(ctx, broadcastId)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		NotificationType string
+		Status           string
+		Count            int64
+	}
+	err = m.DB.WithContext(ctx).Model(&model.Logs{}).
+		Select("notification_type, status, count(*) as count").
+		Where("broadcast_id = ?", broadcastId).
+		Group("notification_type, status").
+		Scan(&counts).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While counting deliveries of broadcast:%v err:%+v", broadcastId, err),
+		fmt.Sprintf("Success: Counted deliveries of broadcast:%v", broadcastId),
+		start)
+
+	if err != nil {
+		return nil, err
+	}
+
+	channelCounts := map[string]*pb.BroadcastChannelCount{}
+	for _, v := range strings.Split(broadcast.NotificationTypes, ",") {
+		channelCounts[v] = &pb.BroadcastChannelCount{NotificationType: v}
+	}
+	for _, v := range counts {
+		count, ok := channelCounts[v.NotificationType]
+		if !ok {
+			continue
+		}
+		if v.Status == contract.STATUS_SUCCESS {
+			count.Sent += v.Count
+		} else {
+			count.Failed += v.Count
+		}
+	}
+
+	reply := &pb.BroadcastStatus{
+		BroadcastId:    broadcast.UuId,
+		Name:           broadcast.Name,
+		Status:         broadcast.Status,
+		TotalUsers:     broadcast.TotalUsers,
+		ProcessedUsers: broadcast.ProcessedUsers,
+	}
+	for _, v := range strings.Split(broadcast.NotificationTypes, ",") {
+		reply.ChannelCounts = append(reply.ChannelCounts, channelCounts[v])
+	}
+	return reply, nil
+}
+
+// SetBroadcastStatus moves a broadcast from one of the from statuses to status.
+// Resuming bumps the generation so batch tasks queued before the pause stop
+func (m *Mysql) SetBroadcastStatus(ctx context.Context, broadcastId string, from []string, status string) (model.Broadcasts, error) {
+	fName := "SetBroadcastStatus"
+	start := time.Now()
+
+	updates := map[string]interface{}{"status": status}
+	if status == contract.BROADCAST_RUNNING {
+		updates["generation"] = gorm.Expr("generation + 1")
+	}
+
+	result := m.DB.WithContext(ctx).Model(&model.Broadcasts{}).
+		Where("uu_id = ? AND status IN ?", broadcastId, from).
+		Updates(updates)
+
+	m.LogError(fName,
+		result.Error != nil,
+		fmt.Sprintf("Error: While setting status of broadcast:%v to %v err:%+v", broadcastId, status, result.Error),
+		fmt.Sprintf("Success: Set status of broadcast:%v to %v", broadcastId, status),
+		start)
+
+	if result.Error != nil {
+		return model.Broadcasts{}, result.Error
+	}
+
+	broadcast, err := m.GetBroadcast(ctx, broadcastId)
+	if err != nil {
+		return broadcast, err
+	}
+	if result.RowsAffected == 0 {
+		return broadcast, fmt.Errorf("broadcast is %v", broadcast.Status)
+	}
+	return broadcast, nil
+}
+
+// GetBroadcastBatch returns the next user configs of the broadcast segment
+func (m *Mysql) GetBroadcastBatch(ctx context.Context, broadcast model.Broadcasts) ([]model.UserConfig, error) {
+	fName := "GetBroadcastBatch"
+	start := time.Now()
+
+	segment := &pb.BroadcastSegment{}
+	if err := json.Unmarshal(broadcast.Segment, segment); err != nil {
+		return nil, err
+	}
+
+	query, err := m.segmentQuery(ctx, segment)
+	if err != nil {
+		return nil, err
+	}
+
+	var userConfigs []model.UserConfig
+	err = query.Select("uc.*").
+		Where("uc.id > ?", broadcast.LastUserConfig).
+		Order("uc.id asc").
+		Limit(broadcast.BatchSize).
+		Scan(&userConfigs).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While getting batch of broadcast:%v err:%+v", broadcast.UuId, err),
+		fmt.Sprintf("Success: Got %v users of broadcast:%v", len(userConfigs), broadcast.UuId),
+		start)
+
+	return userConfigs, err
+}
+
+// AdvanceBroadcast records a sent batch. It returns false when the broadcast
+// was paused, cancelled or resumed by another batch task in the meantime
+func (m *Mysql) AdvanceBroadcast(ctx context.Context, broadcast model.Broadcasts, lastUserConfig uint64, processed int, completed bool) (bool, error) {
+
+	updates := map[string]interface{}{
+		"last_user_config": lastUserConfig,
+		"processed_users":  gorm.Expr("processed_users + ?", processed),
+	}
+	if completed {
+		updates["status"] = contract.BROADCAST_COMPLETED
+	}
+
+	result := m.DB.WithContext(ctx).Model(&model.Broadcasts{}).
+		Where("uu_id = ? AND generation = ? AND status = ?", broadcast.UuId, broadcast.Generation, contract.BROADCAST_RUNNING).
+		Updates(updates)
+
+	if result.Error != nil {
+		m.Log.Errorw("Error advancing broadcast", "broadcastId", broadcast.UuId, "error", result.Error)
+	}
+	return result.RowsAffected > 0, result.Error
+}
+
+func (m *Mysql) GetBroadcastEmailMeta(ctx context.Context, userConfig, broadcastId string) (contract.EmailMeta, error) {
+	fName := "GetBroadcastEmailMeta"
+	start := time.Now()
+
+	var emailMeta contract.EmailMeta
+	err := m.DB.WithContext(ctx).Table("user_configs uc").
+		Select("uc.first_name, uc.default_email, b.message_template, b.subject").
+		Joins("JOIN broadcasts b ON b.uu_id = ?", broadcastId).
+		Where("uc.id = ?", userConfig).
+		Scan(&emailMeta).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: Getting broadcast email meta for userConfig:%v error:%+v", userConfig, err),
+		fmt.Sprintf("Success: Broadcast email meta fetched for userConfig:%v", userConfig),
+		start)
+
+	return emailMeta, err
+}
+
+// GetBroadcastBotMeta returns every enabled channel of the user's enabled bots of notificationType with the broadcast template
+func (m *Mysql) GetBroadcastBotMeta(ctx context.Context, userConfig, broadcastId, notificationType string) ([]model.BotNotificationMeta, error) {
+	fName := "GetBroadcastBotMeta"
+	start := time.Now()
+
+	var results []model.BotNotificationMeta
+	err := m.DB.WithContext(ctx).Table("bot_configs bc").
+		Distinct("bc.id as bot_config_id, uc.first_name, bc.bot_token, cc.channel_id, b.message_template, b.subject, bc.notification_type").
+		Joins("join bot_events_rules ber on bc.id = ber.bot_config_id").
+		Joins("join channel_rules cr on ber.id = cr.bot_event_rules_id").
+		Joins("join channel_configs cc on cc.id = cr.channel_config_id").
+		Joins("join user_configs uc on uc.id = bc.user_config").
+		Joins("join broadcasts b on b.uu_id = ?", broadcastId).
+		Where("bc.enabled = true AND cc.enabled = true AND bc.user_config = ? AND bc.notification_type = ?", userConfig, notificationType).
+		Scan(&results).Error
+	if err == nil {
+		results = m.decryptBotTokens(ctx, fName, results)
+	}
+
+	for i := range results {
+		results[i].EventType = contract.BROADCAST
+	}
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While getting broadcast bot meta for userConfig:%v err:%+v", userConfig, err),
+		fmt.Sprintf("Success: Got broadcast bot meta for userConfig:%v", userConfig),
+		start)
+
+	return results, err
+}
+
+// SetAccountPlatform records the trading platform of an account for broadcast segments
+func (m *Mysql) SetAccountPlatform(ctx context.Context, accId, platform string) error {
+	fName := "SetAccountPlatform"
+	start := time.Now()
+
+	err := m.DB.WithContext(ctx).Model(&model.AccountConfig{}).Where("account_id = ?", accId).Update("platform", platform).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While setting platform of accountId:%v err:%+v", accId, err),
+		fmt.Sprintf("Success: Set platform of accountId:%v to %v", accId, platform),
+		start)
+
+	return err
+}
+
+// SetAccountPlatforms records the trading platform of every account and returns the accounts that don't exist
+func (m *Mysql) SetAccountPlatforms(ctx context.Context, accounts []*pb.AccountPlatform) ([]string, error) {
+	fName := "SetAccountPlatforms"
+	start := time.Now()
+
+	notFound := []string{}
+	err := m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, v := range accounts {
+			result := tx.Model(&model.AccountConfig{}).Where("account_id = ?", v.AccountId).Update("platform", v.Platform)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				notFound = append(notFound, v.AccountId)
+			}
+		}
+		return nil
+	})
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While setting platform of %v accounts err:%+v", len(accounts), err),
+		fmt.Sprintf("Success: Set platform of %v accounts", len(accounts)-len(notFound)),
+		start)
+
+	return notFound, err
+}
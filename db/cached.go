@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devshahriar/notification-manager/cache"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+)
+
+// CachedDB serves the per event reads of the workers from the routing cache.
+// Entries are tagged with the user config id and dropped when the server publishes
+// a config change of the user. Cached values are shared and must not be modified
+type CachedDB struct {
+	DB
+	Cache *cache.Cache
+}
+
+func NewCachedDB(db DB, c *cache.Cache) *CachedDB {
+	return &CachedDB{DB: db, Cache: c}
+}
+
+// GetUserConfig caches the config ids of an account. Unknown accounts are not cached
+func (c *CachedDB) GetUserConfig(ctx context.Context, accId string) (contract.ConfigIds, error) {
+	return cache.GetOrLoad(c.Cache, "account:"+accId, func() (contract.ConfigIds, string, error) {
+		ids, err := c.DB.GetUserConfig(ctx, accId)
+		return ids, ids.UserConfigId, err
+	})
+}
+
+func (c *CachedDB) GetRoutingPlan(ctx context.Context, configIds contract.ConfigIds, eventType string) (model.RoutingPlan, error) {
+	key := fmt.Sprintf("plan:%v:%v:%v", configIds.UserConfigId, configIds.AccountConfId, eventType)
+	return cache.GetOrLoad(c.Cache, key, func() (model.RoutingPlan, string, error) {
+		plan, err := c.DB.GetRoutingPlan(ctx, configIds, eventType)
+		return plan, configIds.UserConfigId, err
+	})
+}
+
+func (c *CachedDB) GetEmailMeta(ctx context.Context, userConfig, accountId, eventType string) (contract.EmailMeta, error) {
+	key := fmt.Sprintf("email:%v:%v:%v", userConfig, accountId, eventType)
+	return cache.GetOrLoad(c.Cache, key, func() (contract.EmailMeta, string, error) {
+		meta, err := c.DB.GetEmailMeta(ctx, userConfig, accountId, eventType)
+		return meta, userConfig, err
+	})
+}
+
+func (c *CachedDB) GetBotNotificationMeta(ctx context.Context, userConfigId, eventType, notificationType string) ([]model.BotNotificationMeta, error) {
+	key := fmt.Sprintf("bot:%v:%v:%v", userConfigId, eventType, notificationType)
+	return cache.GetOrLoad(c.Cache, key, func() ([]model.BotNotificationMeta, string, error) {
+		meta, err := c.DB.GetBotNotificationMeta(ctx, userConfigId, eventType, notificationType)
+		return meta, userConfigId, err
+	})
+}
+
+func (c *CachedDB) GetFlapSettingsByUserConfig(ctx context.Context, userConfigId string) (model.FlapSettings, error) {
+	return cache.GetOrLoad(c.Cache, "flap:"+userConfigId, func() (model.FlapSettings, string, error) {
+		settings, err := c.DB.GetFlapSettingsByUserConfig(ctx, userConfigId)
+		return settings, userConfigId, err
+	})
+}
@@ -15,10 +15,13 @@ import (
 
 	"github.com/devshahriar/notification-manager/contract"
 	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/secrets"
 )
 
 type Mysql struct {
 	*mysql.InstrumentedMysql
+	Secrets *secrets.Cipher
 }
 
 func NewMysql(dsn string, logger *zap.SugaredLogger) (DB, error) {
@@ -41,13 +44,45 @@ func NewMysql(dsn string, logger *zap.SugaredLogger) (DB, error) {
 		model.BotEventsRules{},
 		model.ChannelConfig{},
 		model.ChannelRules{},
+
+		model.FallbackRules{},
+		model.EscalationPolicies{},
+		model.Escalations{},
+		model.FlapSettings{},
+		model.ConnectionStates{},
+		model.ScheduledNotifications{},
+		model.Broadcasts{},
+		model.WorkerHeartbeats{},
+		model.NotificationTransitions{},
+		model.DeadLetters{},
+		model.FanOuts{},
 	)
 
+	if err := RegisterTracing(im.DB); err != nil {
+		return nil, err
+	}
+
+	args := contract.GetWorkerArgs()
+	cipher, err := secrets.NewCipherFromConfig(args.SecretKeys, args.SecretPrimaryKeyId)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Mysql{
 		InstrumentedMysql: im,
+		Secrets:           cipher,
 	}, nil
 }
 
+// Ping checks the connection to the database
+func (m *Mysql) Ping(ctx context.Context) error {
+	sqlDB, err := m.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
 func (m *Mysql) CreateNotificationConfigIndex() {
 	err := m.DB.Exec("CREATE UNIQUE INDEX idx_eventtype_notification_type_user_config ON notification_configs (event_type, notification_type, user_config)").Error
 	if err != nil {
@@ -98,13 +133,21 @@ func (m *Mysql) AddDefaultBot(ctx context.Context, req *nm.InstallIntegrationReq
 		return err
 	}
 
+	botToken, err := m.Secrets.Encrypt(ctx, contract.GetServerAgrs().TelegramBotToken)
+	if err != nil {
+		m.LogError(fName, true, fmt.Sprintf("Error: while encrypting default bot token for userId:%v err:%+v", req.UserId, err), "", start)
+		return err
+	}
+
 	botConfig := model.BotConfigs{BotName: "Traders connect"}
-	botConfig.BotToken = contract.GetServerAgrs().TelegramBotToken
 	botConfig.UserConfig = *userConfig
-	botConfig.Enabled = true
 	botConfig.NotificationType = contract.TELEGRAM
 
-	result := m.DB.Model(&model.BotConfigs{}).FirstOrCreate(&botConfig)
+	// The encrypted token differs on every call so it can't be part of the lookup
+	result := m.DB.Model(&model.BotConfigs{}).
+		Where(botConfig).
+		Attrs(model.BotConfigs{BotToken: botToken, Enabled: true}).
+		FirstOrCreate(&botConfig)
 
 	m.LogError(fName,
 		result.Error != nil || result.RowsAffected == 0,
@@ -165,7 +208,7 @@ func (m *Mysql) UpdateUserConfig(ctx context.Context, UserMeta *nm.InstallIntegr
 		err := m.AddDefaultNotificationConfig(ctx, uint(*userConfigId), UserMeta.NotificationType.String(), defaultEvents)
 		if err != nil {
 			m.Log.Errorw("Error while ingesting default config for Install Integration call", "error:", err)
-			return fmt.Errorf("error while ingesting default config for Install integration call", err)
+			return fmt.Errorf("error while ingesting default config for Install integration call: %v", err)
 		}
 	}
 
@@ -233,7 +276,7 @@ func (m *Mysql) GetEnabledNotificationTypes(ctx context.Context, userConfigId st
 	var notificationType []model.NotificationConfig
 
 	err := m.DB.WithContext(ctx).Model(&model.NotificationConfig{}).
-		Select("id", "event_type", "notification_type").
+		Select("id", "event_type", "notification_type", "filter_expression").
 		Where("user_config = ? AND event_type = ? AND enabled = ?", userConfigId, eventType, true).
 		Scan(&notificationType).Error
 
@@ -257,8 +300,8 @@ func (m *Mysql) IsAccountNotificationDisabled(ctx context.Context, accountConfig
 
 	m.LogError(fName,
 		err != nil,
-		fmt.Sprintf("Error: Retrieving notification rule for accountConfig:%v ntConfId %v err:%+v", accountConfigId, err),
-		fmt.Sprintf("Success: Retrieved notification rule for accountConfig:%v ntConfId", accountConfigId, notificationConfigId),
+		fmt.Sprintf("Error: Retrieving notification rule for accountConfig:%v ntConfId %v err:%+v", accountConfigId, notificationConfigId, err),
+		fmt.Sprintf("Success: Retrieved notification rule for accountConfig:%v ntConfId %v", accountConfigId, notificationConfigId),
 		start)
 	return exists, err
 }
@@ -435,7 +478,8 @@ func (m *Mysql) GetWorkerMeta() ([]contract.WorkerMeta, error) {
 	var workerMeta []contract.WorkerMeta
 
 	err := m.DB.Model(&model.WorkerMeta{}).
-		Select("name", "worker_type", "exchange", "queue", "exchange_type", "binding_key", "notification_type").
+		Select("name", "worker_type", "exchange", "queue", "exchange_type", "binding_key", "notification_type",
+			"concurrency", "prefetch_count", "provider_timeout_seconds", "rate_limit").
 		Scan(&workerMeta).Error
 
 	m.LogError(fName,
@@ -447,7 +491,8 @@ func (m *Mysql) GetWorkerMeta() ([]contract.WorkerMeta, error) {
 	return workerMeta, nil
 }
 
-func (m *Mysql) IngestWorkerMeta(args *contract.WorkerArgs) error {
+// IngestWorkerMeta registers the worker and returns the stored settings it should run with
+func (m *Mysql) IngestWorkerMeta(args *contract.WorkerArgs) (contract.WorkerSettings, error) {
 	fName := "IngestWorkerMeta"
 	start := time.Now()
 
@@ -463,12 +508,44 @@ func (m *Mysql) IngestWorkerMeta(args *contract.WorkerArgs) error {
 		NotificationType: args.WorkerType,
 	}
 
-	result := m.DB.FirstOrCreate(existingWorkerMeta, workerMeta)
+	//The settings of the flags are only published when the worker is first registered
+	//so the changes made with SetWorkerSettings survive restarts
+	result := m.DB.Where(workerMeta).
+		Attrs(map[string]interface{}{
+			"concurrency":              args.Concurrency,
+			"prefetch_count":           args.PrefetchCount,
+			"provider_timeout_seconds": args.ProviderTimeout,
+			"rate_limit":               args.RateLimit,
+		}).
+		FirstOrCreate(existingWorkerMeta)
+
+	//Workers registered before they had settings get the flags for the unset ones
+	settings := contract.WorkerSettings{
+		Concurrency:            existingWorkerMeta.Concurrency,
+		PrefetchCount:          existingWorkerMeta.PrefetchCount,
+		ProviderTimeoutSeconds: existingWorkerMeta.ProviderTimeoutSeconds,
+		RateLimit:              existingWorkerMeta.RateLimit,
+	}
+	unset := map[string]interface{}{}
+	if settings.Concurrency == 0 {
+		settings.Concurrency = args.Concurrency
+		unset["concurrency"] = args.Concurrency
+	}
+	if settings.PrefetchCount == 0 {
+		settings.PrefetchCount = args.PrefetchCount
+		unset["prefetch_count"] = args.PrefetchCount
+	}
+	if settings.ProviderTimeoutSeconds == 0 {
+		settings.ProviderTimeoutSeconds = args.ProviderTimeout
+		unset["provider_timeout_seconds"] = args.ProviderTimeout
+	}
+	if result.Error == nil && len(unset) > 0 {
+		result.Error = m.DB.Model(existingWorkerMeta).Updates(unset).Error
+	}
 
-	if result.Error == nil && result.RowsAffected > 0 {
-		m.Log.Infof("New worker meta created name: %v", workerMeta.Name)
-	} else {
-		m.Log.Infof("Worker meta already exist: %v", workerMeta.Name)
+	if result.Error == nil {
+		m.Log.Infof("Worker meta registered name: %v concurrency: %v prefetch: %v provider timeout: %vs rate limit: %v/s",
+			workerMeta.Name, settings.Concurrency, settings.PrefetchCount, settings.ProviderTimeoutSeconds, settings.RateLimit)
 	}
 
 	m.LogError(fName,
@@ -476,7 +553,61 @@ func (m *Mysql) IngestWorkerMeta(args *contract.WorkerArgs) error {
 		fmt.Sprintf("Error: While Ingesting worker meta error:%v", result.Error),
 		fmt.Sprintf("Success: Worker meta ingested"),
 		start)
-	return result.Error
+	return settings, result.Error
+}
+
+// SetWorkerSettings changes the settings of the worker named in req. Zero keeps a setting and a negative
+// rate limit removes the limit. The settings of the worker are returned
+func (m *Mysql) SetWorkerSettings(ctx context.Context, req *pb.WorkerSettings) (contract.WorkerSettings, error) {
+	fName := "SetWorkerSettings"
+	start := time.Now()
+
+	updates := map[string]interface{}{}
+	if req.Concurrency > 0 {
+		updates["concurrency"] = req.Concurrency
+	}
+	if req.PrefetchCount > 0 {
+		updates["prefetch_count"] = req.PrefetchCount
+	}
+	if req.ProviderTimeoutSeconds > 0 {
+		updates["provider_timeout_seconds"] = req.ProviderTimeoutSeconds
+	}
+	if req.RateLimit > 0 {
+		updates["rate_limit"] = req.RateLimit
+	} else if req.RateLimit < 0 {
+		updates["rate_limit"] = 0
+	}
+
+	var settings contract.WorkerSettings
+	err := m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var meta model.WorkerMeta
+		if err := tx.Where("name = ?", req.Name).Order("id desc").First(&meta).Error; err != nil {
+			return err
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&model.WorkerMeta{}).Where("name = ?", req.Name).Updates(updates).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id = ?", meta.Id).First(&meta).Error; err != nil {
+				return err
+			}
+		}
+		settings = contract.WorkerSettings{
+			Concurrency:            meta.Concurrency,
+			PrefetchCount:          meta.PrefetchCount,
+			ProviderTimeoutSeconds: meta.ProviderTimeoutSeconds,
+			RateLimit:              meta.RateLimit,
+		}
+		return nil
+	})
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While setting settings of worker %v err:%+v", req.Name, err),
+		fmt.Sprintf("Success: Settings of worker %v set to %+v", req.Name, settings),
+		start)
+
+	return settings, err
 }
 
 func (m *Mysql) AddConfig(ctx context.Context, notificationConfig *nm.NotificationConfig) error {
@@ -1081,6 +1212,10 @@ func (m *Mysql) DumpLog(log model.Logs) error {
 	fName := "DumpLog"
 	start := time.Now()
 
+	log.ReqMeta = secrets.RedactJSON(log.ReqMeta)
+	if len(log.Payload) > 0 {
+		log.Payload = secrets.RedactJSON(log.Payload)
+	}
 	err := m.DB.Create(&log).Error
 
 	m.LogError(fName,
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"gorm.io/gorm"
+)
+
+func (m *Mysql) CreateDeadLetter(ctx context.Context, letter *model.DeadLetters) error {
+	fName := "CreateDeadLetter"
+	start := time.Now()
+
+	err := m.DB.WithContext(ctx).Create(letter).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While creating dead letter worker:%v task:%v err:%+v", letter.WorkerName, letter.TaskName, err),
+		fmt.Sprintf("Success: Created dead letter %v", letter.UuId),
+		start)
+
+	return err
+}
+
+func deadLetterQuery(query *gorm.DB, filter contract.DeadLetterFilter) *gorm.DB {
+	if filter.WorkerName != "" {
+		query = query.Where("worker_name = ?", filter.WorkerName)
+	}
+	if filter.NotificationType != "" {
+		query = query.Where("notification_type = ?", filter.NotificationType)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
+}
+
+// ListDeadLetters returns up to limit dead letters of the filter older than the dead letter afterId, newest first.
+// afterId 0 starts from the newest dead letter. The signatures are not loaded
+func (m *Mysql) ListDeadLetters(ctx context.Context, filter contract.DeadLetterFilter, afterId uint64, limit int) ([]model.DeadLetters, error) {
+	fName := "ListDeadLetters"
+	start := time.Now()
+
+	query := deadLetterQuery(m.DB.WithContext(ctx).Model(&model.DeadLetters{}), filter)
+	if afterId > 0 {
+		query = query.Where("id < ?", afterId)
+	}
+
+	var letters []model.DeadLetters
+	err := query.Omit("signature").Order("id DESC").Limit(limit).Find(&letters).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While listing dead letters filter:%+v err:%+v", filter, err),
+		fmt.Sprintf("Success: Listed %v dead letters", len(letters)),
+		start)
+
+	return letters, err
+}
+
+func (m *Mysql) GetDeadLetter(ctx context.Context, deadLetterId string) (model.DeadLetters, error) {
+	fName := "GetDeadLetter"
+	start := time.Now()
+
+	var letter model.DeadLetters
+	err := m.DB.WithContext(ctx).Where("uu_id = ?", deadLetterId).First(&letter).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While getting dead letter %v err:%+v", deadLetterId, err),
+		fmt.Sprintf("Success: Got dead letter %v", deadLetterId),
+		start)
+
+	return letter, err
+}
+
+// MarkDeadLetterReplayed claims the dead letter for a replay. With onlyDead a dead letter that was
+// already replayed is not claimed again, so concurrent bulk replays don't publish it twice
+func (m *Mysql) MarkDeadLetterReplayed(ctx context.Context, deadLetterId string, onlyDead bool) (bool, error) {
+	fName := "MarkDeadLetterReplayed"
+	start := time.Now()
+
+	query := m.DB.WithContext(ctx).Model(&model.DeadLetters{}).Where("uu_id = ?", deadLetterId)
+	if onlyDead {
+		query = query.Where("status = ?", contract.DEAD_LETTER_DEAD)
+	}
+	result := query.Updates(map[string]interface{}{
+		"status":       contract.DEAD_LETTER_REPLAYED,
+		"replay_count": gorm.Expr("replay_count + 1"),
+		"replayed_at":  time.Now().UTC(),
+	})
+
+	m.LogError(fName,
+		result.Error != nil,
+		fmt.Sprintf("Error: While marking dead letter %v replayed err:%+v", deadLetterId, result.Error),
+		fmt.Sprintf("Success: Marked dead letter %v replayed", deadLetterId),
+		start)
+
+	return result.RowsAffected > 0, result.Error
+}
+
+// ResetDeadLetter puts back a dead letter whose replay couldn't be published
+func (m *Mysql) ResetDeadLetter(ctx context.Context, deadLetterId string) error {
+	fName := "ResetDeadLetter"
+	start := time.Now()
+
+	err := m.DB.WithContext(ctx).Model(&model.DeadLetters{}).Where("uu_id = ?", deadLetterId).Updates(map[string]interface{}{
+		"status":       contract.DEAD_LETTER_DEAD,
+		"replay_count": gorm.Expr("replay_count - 1"),
+	}).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While resetting dead letter %v err:%+v", deadLetterId, err),
+		fmt.Sprintf("Success: Reset dead letter %v", deadLetterId),
+		start)
+
+	return err
+}
+
+// PurgeDeadLetters deletes the dead letters of ids or, without ids, of the filter
+func (m *Mysql) PurgeDeadLetters(ctx context.Context, ids []string, filter contract.DeadLetterFilter) (int64, error) {
+	fName := "PurgeDeadLetters"
+	start := time.Now()
+
+	query := m.DB.WithContext(ctx)
+	if len(ids) > 0 {
+		query = query.Where("uu_id IN ?", ids)
+	} else {
+		query = deadLetterQuery(query, filter)
+	}
+	result := query.Delete(&model.DeadLetters{})
+
+	m.LogError(fName,
+		result.Error != nil,
+		fmt.Sprintf("Error: While purging dead letters ids:%v filter:%+v err:%+v", ids, filter, result.Error),
+		fmt.Sprintf("Success: Purged %v dead letters", result.RowsAffected),
+		start)
+
+	return result.RowsAffected, result.Error
+}
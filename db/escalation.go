@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"gorm.io/gorm"
+)
+
+// SetEscalationPolicy replaces the escalation steps of a notification config
+func (m *Mysql) SetEscalationPolicy(ctx context.Context, req *pb.EscalationPolicy) error {
+	fName := "SetEscalationPolicy"
+	start := time.Now()
+
+	valid := m.CheckValidUser(ctx, req.UserId, req.NotificationConfigId, &model.NotificationConfig{})
+	if !valid {
+		return fmt.Errorf("invalid request for userId:%v , configId: %v", req.UserId, req.NotificationConfigId)
+	}
+
+	steps := []model.EscalationPolicies{}
+	for i, v := range req.Steps {
+		ntType := strings.ToLower(v.NotificationType)
+		if !contract.IsValidNotificationType(ntType) {
+			return fmt.Errorf("invalid notification type in escalation step %v: %v", i, v.NotificationType)
+		}
+		if v.DelayMinutes <= 0 {
+			return fmt.Errorf("escalation step %v must have a positive delay", i)
+		}
+
+		steps = append(steps, model.EscalationPolicies{
+			NotificationConfigId: req.NotificationConfigId,
+			Step:                 i,
+			NotificationType:     ntType,
+			DelayMinutes:         int(v.DelayMinutes),
+			Enabled:              req.Enabled,
+		})
+	}
+
+	err := m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("notification_config_id = ?", req.NotificationConfigId).Delete(&model.EscalationPolicies{}).Error; err != nil {
+			return err
+		}
+		if len(steps) == 0 {
+			return nil
+		}
+		return tx.Create(&steps).Error
+	})
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While setting escalation policy for notificationConfig:%v err:%+v", req.NotificationConfigId, err),
+		fmt.Sprintf("Success: Set escalation policy for notificationConfig:%v", req.NotificationConfigId),
+		start)
+
+	return err
+}
+
+func (m *Mysql) GetEscalationPolicy(ctx context.Context, req *pb.GetEscalationPolicyReq) (*pb.EscalationPolicy, error) {
+	fName := "GetEscalationPolicy"
+	start := time.Now()
+
+	valid := m.CheckValidUser(ctx, req.UserId, req.NotificationConfigId, &model.NotificationConfig{})
+	if !valid {
+		return nil, fmt.Errorf("invalid request for userId:%v , configId: %v", req.UserId, req.NotificationConfigId)
+	}
+
+	var steps []model.EscalationPolicies
+	err := m.DB.WithContext(ctx).Model(&model.EscalationPolicies{}).
+		Where("notification_config_id = ?", req.NotificationConfigId).
+		Order("step asc").
+		Scan(&steps).Error
+
+	reply := &pb.EscalationPolicy{
+		UserId:               req.UserId,
+		NotificationConfigId: req.NotificationConfigId,
+		Steps:                []*pb.EscalationStep{},
+	}
+	for _, v := range steps {
+		reply.Enabled = v.Enabled
+		reply.Steps = append(reply.Steps, &pb.EscalationStep{
+			NotificationType: v.NotificationType,
+			DelayMinutes:     int32(v.DelayMinutes),
+		})
+	}
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While getting escalation policy for notificationConfig:%v err:%+v", req.NotificationConfigId, err),
+		fmt.Sprintf("Success: Got escalation policy for notificationConfig:%v", req.NotificationConfigId),
+		start)
+
+	return reply, err
+}
+
+// GetEscalationSteps returns the enabled escalation steps of a notification config ordered by step
+func (m *Mysql) GetEscalationSteps(ctx context.Context, notificationConfigId uint64) ([]model.EscalationPolicies, error) {
+	fName := "GetEscalationSteps"
+	start := time.Now()
+
+	var steps []model.EscalationPolicies
+	err := m.DB.WithContext(ctx).Model(&model.EscalationPolicies{}).
+		Where("notification_config_id = ? AND enabled = ?", notificationConfigId, true).
+		Order("step asc").
+		Scan(&steps).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: Retrieving escalation steps for notificationConfig:%v err:%+v", notificationConfigId, err),
+		fmt.Sprintf("Success: Retrieved escalation steps for notificationConfig:%v", notificationConfigId),
+		start)
+
+	return steps, err
+}
+
+func (m *Mysql) CreateEscalation(ctx context.Context, escalation *model.Escalations) error {
+	fName := "CreateEscalation"
+	start := time.Now()
+
+	err := m.DB.WithContext(ctx).Create(escalation).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While creating escalation for notificationConfig:%v err:%+v", escalation.NotificationConfigId, err),
+		fmt.Sprintf("Success: Created escalation:%v", escalation.UuId),
+		start)
+
+	return err
+}
+
+func (m *Mysql) GetEscalation(ctx context.Context, escalationId string) (*model.Escalations, error) {
+
+	escalation := &model.Escalations{}
+	err := m.DB.WithContext(ctx).Model(&model.Escalations{}).Where("uu_id = ?", escalationId).First(escalation).Error
+	if err != nil {
+		m.Log.Errorw("Error getting escalation", "escalationId", escalationId, "error", err)
+		return nil, fmt.Errorf("escalation doesn't exist escalationId: %v", escalationId)
+	}
+	return escalation, nil
+}
+
+// UpdateEscalationStep moves a pending escalation to the given step.
+// Returns false when the escalation was acknowledged or resolved in the meantime
+func (m *Mysql) UpdateEscalationStep(ctx context.Context, escalationId string, step int) (bool, error) {
+
+	result := m.DB.WithContext(ctx).Model(&model.Escalations{}).
+		Where("uu_id = ? AND status = ?", escalationId, contract.ESCALATION_PENDING).
+		Update("step", step)
+
+	if result.Error != nil {
+		m.Log.Errorw("Error updating escalation step", "escalationId", escalationId, "error", result.Error)
+	}
+	return result.RowsAffected > 0, result.Error
+}
+
+// AcknowledgeEscalation stops a pending or exhausted escalation and returns its final status
+func (m *Mysql) AcknowledgeEscalation(ctx context.Context, escalationId, acknowledgedBy string) (string, error) {
+	fName := "AcknowledgeEscalation"
+	start := time.Now()
+
+	now := time.Now()
+	result := m.DB.WithContext(ctx).Model(&model.Escalations{}).
+		Where("uu_id = ? AND status IN ?", escalationId, []string{contract.ESCALATION_PENDING, contract.ESCALATION_EXHAUSTED}).
+		Updates(map[string]interface{}{
+			"status":          contract.ESCALATION_ACKNOWLEDGED,
+			"acknowledged_at": &now,
+			"acknowledged_by": acknowledgedBy,
+		})
+
+	m.LogError(fName,
+		result.Error != nil,
+		fmt.Sprintf("Error: While acknowledging escalation:%v err:%+v", escalationId, result.Error),
+		fmt.Sprintf("Success: Acknowledged escalation:%v", escalationId),
+		start)
+
+	if result.Error != nil {
+		return "", result.Error
+	}
+
+	escalation, err := m.GetEscalation(ctx, escalationId)
+	if err != nil {
+		return "", err
+	}
+	return escalation.Status, nil
+}
+
+func (m *Mysql) ExhaustEscalation(ctx context.Context, escalationId string) error {
+
+	err := m.DB.WithContext(ctx).Model(&model.Escalations{}).
+		Where("uu_id = ? AND status = ?", escalationId, contract.ESCALATION_PENDING).
+		Update("status", contract.ESCALATION_EXHAUSTED).Error
+
+	if err != nil {
+		m.Log.Errorw("Error exhausting escalation", "escalationId", escalationId, "error", err)
+	}
+	return err
+}
+
+// ResolveEscalations stops the pending escalations of an account for the given event types
+func (m *Mysql) ResolveEscalations(ctx context.Context, userConfigId, accId string, eventTypes []string) error {
+	fName := "ResolveEscalations"
+	start := time.Now()
+
+	result := m.DB.WithContext(ctx).Model(&model.Escalations{}).
+		Where("user_config = ? AND account_id = ? AND event_type IN ? AND status = ?", userConfigId, accId, eventTypes, contract.ESCALATION_PENDING).
+		Update("status", contract.ESCALATION_RESOLVED)
+
+	m.LogError(fName,
+		result.Error != nil,
+		fmt.Sprintf("Error: While resolving escalations for accountId:%v err:%+v", accId, result.Error),
+		fmt.Sprintf("Success: Resolved %v escalations for accountId:%v", result.RowsAffected, accId),
+		start)
+
+	return result.Error
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devshahriar/notification-manager/model"
+)
+
+// GetEventNotificationConfigs returns every notification config of the event, disabled ones included
+func (m *Mysql) GetEventNotificationConfigs(ctx context.Context, userConfigId string, eventType string) ([]model.NotificationConfig, error) {
+	fName := "GetEventNotificationConfigs"
+	start := time.Now()
+
+	var configs []model.NotificationConfig
+	err := m.DB.WithContext(ctx).Model(&model.NotificationConfig{}).
+		Select("id", "event_type", "notification_type", "enabled", "filter_expression").
+		Where("user_config = ? AND event_type = ?", userConfigId, eventType).
+		Order("id asc").
+		Scan(&configs).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: Retrieving notification configs for userConfig:%v and EventType:%v err:%+v", userConfigId, eventType, err),
+		fmt.Sprintf("Success: Retrieved notification configs for userConfig:%v", userConfigId),
+		start)
+
+	return configs, err
+}
+
+// GetBotRouteCandidates returns every bot chat the notification config is linked to
+func (m *Mysql) GetBotRouteCandidates(ctx context.Context, notificationConfigId uint64) ([]model.BotRouteCandidate, error) {
+	fName := "GetBotRouteCandidates"
+	start := time.Now()
+
+	var candidates []model.BotRouteCandidate
+	err := m.DB.WithContext(ctx).Table("bot_events_rules ber").
+		Select("bc.id as bot_config_id, bc.bot_name, bc.enabled as bot_enabled, ber.filter_expression, "+
+			"COALESCE(cc.channel_name, '') as channel_name, COALESCE(cc.channel_id, '') as channel_id, COALESCE(cc.enabled, false) as channel_enabled").
+		Joins("join bot_configs bc on bc.id = ber.bot_config_id").
+		Joins("left join channel_rules cr on cr.bot_event_rules_id = ber.id").
+		Joins("left join channel_configs cc on cc.id = cr.channel_config_id").
+		Where("ber.notification_config_id = ?", notificationConfigId).
+		Order("bc.id asc").
+		Scan(&candidates).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: Retrieving bot route candidates for notificationConfig:%v err:%+v", notificationConfigId, err),
+		fmt.Sprintf("Success: Retrieved bot route candidates for notificationConfig:%v", notificationConfigId),
+		start)
+
+	return candidates, err
+}
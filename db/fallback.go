@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"gorm.io/gorm"
+)
+
+// GetFallbackChain returns the enabled fallback rules of an event ordered by priority
+// Master uses it to pick the primary channel and the next channel after a failure
+func (m *Mysql) GetFallbackChain(ctx context.Context, userConfigId, eventType string) ([]model.FallbackRules, error) {
+	fName := "GetFallbackChain"
+	start := time.Now()
+
+	var rules []model.FallbackRules
+	err := m.DB.WithContext(ctx).Model(&model.FallbackRules{}).
+		Where("user_config = ? AND event_type = ? AND enabled = ?", userConfigId, eventType, true).
+		Order("priority asc").
+		Scan(&rules).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: Retrieving fallback chain for userConfig:%v eventType:%v err:%+v", userConfigId, eventType, err),
+		fmt.Sprintf("Success: Retrieved fallback chain for userConfig:%v eventType:%v", userConfigId, eventType),
+		start)
+
+	return rules, err
+}
+
+// SetFallbackChain replaces the fallback chain of an event with the ordered list in the request
+func (m *Mysql) SetFallbackChain(ctx context.Context, req *pb.FallbackChain) error {
+	fName := "SetFallbackChain"
+	start := time.Now()
+
+	userConfig, err := m.GetUserConfigId(ctx, req.UserId)
+	if err != nil {
+		m.Log.Errorw("Error while feting userConfig id for userId:", req.UserId)
+		return err
+	}
+
+	rules := []model.FallbackRules{}
+	seen := map[string]bool{}
+	for i, v := range req.NotificationTypes {
+		ntType := strings.ToLower(v)
+		if !contract.IsValidNotificationType(ntType) {
+			return fmt.Errorf("invalid notification type in fallback chain: %v", v)
+		}
+		if seen[ntType] {
+			return fmt.Errorf("notification type %v is repeated in fallback chain", v)
+		}
+		seen[ntType] = true
+
+		rules = append(rules, model.FallbackRules{
+			UserConfig:       *userConfig,
+			EventType:        req.EventType,
+			NotificationType: ntType,
+			Priority:         i,
+			Enabled:          req.Enabled,
+		})
+	}
+
+	err = m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_config = ? AND event_type = ?", *userConfig, req.EventType).Delete(&model.FallbackRules{}).Error; err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		return tx.Create(&rules).Error
+	})
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While setting fallback chain for userId:%v eventType:%v err:%+v", req.UserId, req.EventType, err),
+		fmt.Sprintf("Success: Set fallback chain for userId:%v eventType:%v", req.UserId, req.EventType),
+		start)
+
+	return err
+}
+
+func (m *Mysql) GetFallbackChains(ctx context.Context, req *pb.GetFallbackChainsReq) (*pb.GetFallbackChainsReply, error) {
+	fName := "GetFallbackChains"
+	start := time.Now()
+
+	userConfig, err := m.GetUserConfigId(ctx, req.UserId)
+	if err != nil {
+		m.Log.Errorw("Error while getting userConfig for UserId:", req.UserId)
+		return nil, fmt.Errorf("error while getting userConfig for UserId:%v", req.UserId)
+	}
+
+	query := m.DB.WithContext(ctx).Model(&model.FallbackRules{}).Where("user_config = ?", *userConfig)
+	if req.EventType != "" {
+		query = query.Where("event_type = ?", req.EventType)
+	}
+
+	var rules []model.FallbackRules
+	err = query.Order("event_type asc, priority asc").Scan(&rules).Error
+
+	reply := &pb.GetFallbackChainsReply{FallbackChains: []*pb.FallbackChain{}}
+	chains := map[string]*pb.FallbackChain{}
+	for _, v := range rules {
+		chain, ok := chains[v.EventType]
+		if !ok {
+			chain = &pb.FallbackChain{
+				UserId:    req.UserId,
+				EventType: v.EventType,
+				Enabled:   v.Enabled,
+			}
+			chains[v.EventType] = chain
+			reply.FallbackChains = append(reply.FallbackChains, chain)
+		}
+		chain.NotificationTypes = append(chain.NotificationTypes, v.NotificationType)
+	}
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While getting fallback chains for userId:%v err:%+v", req.UserId, err),
+		fmt.Sprintf("Success: Got fallback chains for userId:%v", req.UserId),
+		start)
+
+	return reply, err
+}
+
+func (m *Mysql) GetUserIdByConfigId(ctx context.Context, userConfigId string) (string, error) {
+
+	var userId string
+	err := m.DB.WithContext(ctx).Model(&model.UserConfig{}).Where("id = ?", userConfigId).Select("user_id").Scan(&userId).Error
+
+	if err != nil || userId == "" {
+		m.Log.Errorw("Error getting userId for userConfig", "userConfig", userConfigId, "error", err)
+		return "", fmt.Errorf("user config doesn't exist userConfig: %v", userConfigId)
+	}
+	return userId, nil
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devshahriar/notification-manager/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FinishFanOut counts a finished destination of the fan-out and returns the fan-out as counted.
+// Destinations finishing at the same time are serialized by the row lock
+func (m *Mysql) FinishFanOut(ctx context.Context, fanOut model.FanOuts, sent bool) (model.FanOuts, error) {
+	fName := "FinishFanOut"
+	start := time.Now()
+
+	state := model.FanOuts{}
+	err := m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(model.FanOuts{FanOutId: fanOut.FanOutId}).
+			Attrs(fanOut).
+			FirstOrCreate(&state).Error
+		if err != nil {
+			return err
+		}
+
+		state.Done++
+		if sent {
+			state.Sent++
+		}
+		if state.Done >= state.Total {
+			return tx.Delete(&state).Error
+		}
+		return tx.Save(&state).Error
+	})
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While finishing fan-out %v err:%+v", fanOut.FanOutId, err),
+		fmt.Sprintf("Success: Finished %d of %d destinations of fan-out %v", state.Done, state.Total, fanOut.FanOutId),
+		start)
+
+	return state, err
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devshahriar/notification-manager/filter"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/template"
+	"gorm.io/gorm"
+)
+
+// ValidateFilterExpression checks the expression against the data keys of the notification config event
+func (m *Mysql) ValidateFilterExpression(ctx context.Context, notificationConfigId uint64, expr string) error {
+
+	var eventType string
+	err := m.DB.WithContext(ctx).Model(&model.NotificationConfig{}).Where("id = ?", notificationConfigId).Select("event_type").Scan(&eventType).Error
+	if err != nil || eventType == "" {
+		return fmt.Errorf("notification config was not found NotificationConfig:%v", notificationConfigId)
+	}
+
+	if err := filter.Validate(expr, template.GetEventKeys(eventType)); err != nil {
+		return fmt.Errorf("invalid filter expression for eventType %v: %v", eventType, err)
+	}
+	return nil
+}
+
+// EditConfigFilter sets the filter of a notification config of the user config. Returns gorm.ErrRecordNotFound when the user config has no such notification config
+func (m *Mysql) EditConfigFilter(ctx context.Context, notificationConfigId, userConfig uint64, expr string) error {
+	fName := "EditConfigFilter"
+	start := time.Now()
+
+	if err := m.ValidateFilterExpression(ctx, notificationConfigId, expr); err != nil {
+		return err
+	}
+
+	result := m.DB.WithContext(ctx).Model(&model.NotificationConfig{}).
+		Where("id = ? AND user_config = ?", notificationConfigId, userConfig).
+		Update("filter_expression", expr)
+
+	err := result.Error
+	if err == nil && result.RowsAffected == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While editing filter expression for userConfig:%v notificationConfig:%v err:%+v", userConfig, notificationConfigId, err),
+		fmt.Sprintf("Success: Edited filter expression for userConfig:%v notificationConfig:%v", userConfig, notificationConfigId),
+		start)
+
+	return err
+}
+
+// EditBotEventFilter sets the filter of a bot event of a bot of the user config. Returns gorm.ErrRecordNotFound when the user config has no such bot event
+func (m *Mysql) EditBotEventFilter(ctx context.Context, userConfig, botConfigId, notificationConfigId uint64, expr string) error {
+	fName := "EditBotEventFilter"
+	start := time.Now()
+
+	if err := m.ValidateFilterExpression(ctx, notificationConfigId, expr); err != nil {
+		return err
+	}
+
+	result := m.DB.WithContext(ctx).Model(&model.BotEventsRules{}).
+		Where("bot_config_id = ? AND notification_config_id = ?", botConfigId, notificationConfigId).
+		Where("bot_config_id IN (?)", m.DB.Model(&model.BotConfigs{}).Select("id").Where("user_config = ?", userConfig)).
+		Update("filter_expression", expr)
+
+	err := result.Error
+	if err == nil && result.RowsAffected == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While editing filter expression for userConfig:%v botConfig:%v notificationConfig:%v err:%+v", userConfig, botConfigId, notificationConfigId, err),
+		fmt.Sprintf("Success: Edited filter expression for userConfig:%v botConfig:%v notificationConfig:%v", userConfig, botConfigId, notificationConfigId),
+		start)
+
+	return err
+}
+
+func (m *Mysql) GetConfigFilter(ctx context.Context, userConfig, notificationConfigId uint64) (string, error) {
+
+	var exprs []string
+	err := m.DB.WithContext(ctx).Model(&model.NotificationConfig{}).
+		Where("id = ? AND user_config = ?", notificationConfigId, userConfig).
+		Pluck("filter_expression", &exprs).Error
+	if err == nil && len(exprs) == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		m.Log.Errorw("Error getting filter expression", "userConfig", userConfig, "notificationConfigId", notificationConfigId, "error", err)
+		return "", err
+	}
+	return exprs[0], nil
+}
+
+func (m *Mysql) GetBotEventFilter(ctx context.Context, userConfig, botConfigId, notificationConfigId uint64) (string, error) {
+
+	var exprs []string
+	err := m.DB.WithContext(ctx).Model(&model.BotEventsRules{}).
+		Where("bot_config_id = ? AND notification_config_id = ?", botConfigId, notificationConfigId).
+		Where("bot_config_id IN (?)", m.DB.Model(&model.BotConfigs{}).Select("id").Where("user_config = ?", userConfig)).
+		Pluck("filter_expression", &exprs).Error
+	if err == nil && len(exprs) == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		m.Log.Errorw("Error getting bot event filter expression", "userConfig", userConfig, "botConfigId", botConfigId, "notificationConfigId", notificationConfigId, "error", err)
+		return "", err
+	}
+	return exprs[0], nil
+}
+
+// GetBotEventFilters returns the bot event rules of a notification config that have a filter expression
+func (m *Mysql) GetBotEventFilters(ctx context.Context, notificationConfigId uint64) ([]model.BotEventsRules, error) {
+	fName := "GetBotEventFilters"
+	start := time.Now()
+
+	var rules []model.BotEventsRules
+	err := m.DB.WithContext(ctx).Model(&model.BotEventsRules{}).
+		Select("id", "bot_config_id", "notification_config_id", "filter_expression").
+		Where("notification_config_id = ?", notificationConfigId).
+		Scan(&rules).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: Retrieving bot event filters for notificationConfig:%v err:%+v", notificationConfigId, err),
+		fmt.Sprintf("Success: Retrieved bot event filters for notificationConfig:%v", notificationConfigId),
+		start)
+
+	return rules, err
+}
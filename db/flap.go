@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (m *Mysql) SetFlapSettings(ctx context.Context, req *pb.FlapSettings) error {
+	fName := "SetFlapSettings"
+	start := time.Now()
+
+	if req.GracePeriodSeconds < 0 || req.FlapThreshold < 2 || req.FlapWindowSeconds <= 0 {
+		return fmt.Errorf("invalid flap settings. Grace period can't be negative, threshold must be at least 2 and window must be positive")
+	}
+
+	userConfig, err := m.GetUserConfigId(ctx, req.UserId)
+	if err != nil {
+		m.Log.Errorw("Error while feting userConfig id for userId:", req.UserId)
+		return err
+	}
+
+	settings := model.FlapSettings{
+		UserConfig:         *userConfig,
+		Enabled:            req.Enabled,
+		GracePeriodSeconds: int(req.GracePeriodSeconds),
+		FlapThreshold:      int(req.FlapThreshold),
+		FlapWindowSeconds:  int(req.FlapWindowSeconds),
+	}
+
+	err = m.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_config"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "enabled", "grace_period_seconds", "flap_threshold", "flap_window_seconds"}),
+	}).Create(&settings).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While setting flap settings for userId:%v err:%+v", req.UserId, err),
+		fmt.Sprintf("Success: Set flap settings for userId:%v", req.UserId),
+		start)
+
+	return err
+}
+
+func (m *Mysql) GetFlapSettings(ctx context.Context, req *pb.GetFlapSettingsReq) (*pb.FlapSettings, error) {
+
+	userConfig, err := m.GetUserConfigId(ctx, req.UserId)
+	if err != nil {
+		m.Log.Errorw("Error while getting userConfig for UserId:", req.UserId)
+		return nil, fmt.Errorf("error while getting userConfig for UserId:%v", req.UserId)
+	}
+
+	settings, err := m.GetFlapSettingsByUserConfig(ctx, fmt.Sprintf("%d", *userConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.FlapSettings{
+		UserId:             req.UserId,
+		Enabled:            settings.Enabled,
+		GracePeriodSeconds: int32(settings.GracePeriodSeconds),
+		FlapThreshold:      int32(settings.FlapThreshold),
+		FlapWindowSeconds:  int32(settings.FlapWindowSeconds),
+	}, nil
+}
+
+// GetFlapSettingsByUserConfig returns the flap settings of a user or the defaults when the user has none.
+// Flap suppression is disabled by default so connection events of a user are only held once the user opts in
+func (m *Mysql) GetFlapSettingsByUserConfig(ctx context.Context, userConfigId string) (model.FlapSettings, error) {
+
+	var settings []model.FlapSettings
+	err := m.DB.WithContext(ctx).Model(&model.FlapSettings{}).Where("user_config = ?", userConfigId).Scan(&settings).Error
+	if err != nil {
+		m.Log.Errorw("Error getting flap settings", "userConfig", userConfigId, "error", err)
+		return model.FlapSettings{}, err
+	}
+
+	if len(settings) == 0 {
+		return model.FlapSettings{
+			Enabled:            contract.DEFAULT_FLAP_ENABLED,
+			GracePeriodSeconds: contract.DEFAULT_FLAP_GRACE_PERIOD_SECONDS,
+			FlapThreshold:      contract.DEFAULT_FLAP_THRESHOLD,
+			FlapWindowSeconds:  contract.DEFAULT_FLAP_WINDOW_SECONDS,
+		}, nil
+	}
+	return settings[0], nil
+}
+
+// UpdateConnectionState locks the connection state of an account and saves the changes fn makes to it.
+// The lock keeps concurrent master tasks of the same account from reading a stale state. The row is upserted
+// first so the first events of an account have a row to lock instead of racing to insert it
+func (m *Mysql) UpdateConnectionState(ctx context.Context, accId string, fn func(state *model.ConnectionStates) error) error {
+	fName := "UpdateConnectionState"
+	start := time.Now()
+
+	err := m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.ConnectionStates{AccountId: accId}).Error
+		if err != nil {
+			return err
+		}
+
+		state := model.ConnectionStates{}
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(model.ConnectionStates{AccountId: accId}).
+			First(&state).Error
+		if err != nil {
+			return err
+		}
+
+		if err := fn(&state); err != nil {
+			return err
+		}
+		return tx.Save(&state).Error
+	})
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While updating connection state for accountId:%v err:%+v", accId, err),
+		fmt.Sprintf("Success: Updated connection state for accountId:%v", accId),
+		start)
+
+	return err
+}
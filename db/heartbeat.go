@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devshahriar/notification-manager/model"
+	"gorm.io/gorm/clause"
+)
+
+// Heartbeat records that a worker instance is alive
+func (m *Mysql) Heartbeat(ctx context.Context, heartbeat *model.WorkerHeartbeats) error {
+
+	err := m.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "instance_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "version", "capacity", "last_seen_at"}),
+	}).Create(heartbeat).Error
+
+	if err != nil {
+		m.Log.Errorw("Error recording heartbeat", "instanceId", heartbeat.InstanceId, "error", err)
+	}
+	return err
+}
+
+func (m *Mysql) GetWorkerHeartbeats(ctx context.Context) ([]model.WorkerHeartbeats, error) {
+	fName := "GetWorkerHeartbeats"
+	start := time.Now()
+
+	var heartbeats []model.WorkerHeartbeats
+	err := m.DB.WithContext(ctx).Model(&model.WorkerHeartbeats{}).Order("worker_name, instance_id").Scan(&heartbeats).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While getting worker heartbeats err:%+v", err),
+		fmt.Sprintf("Success: Got %v worker heartbeats", len(heartbeats)),
+		start)
+
+	return heartbeats, err
+}
+
+// DeleteStaleHeartbeats removes the instances that stopped before the given time
+func (m *Mysql) DeleteStaleHeartbeats(ctx context.Context, before time.Time) error {
+
+	err := m.DB.WithContext(ctx).Where("last_seen_at < ?", before).Delete(&model.WorkerHeartbeats{}).Error
+	if err != nil {
+		m.Log.Errorw("Error deleting stale heartbeats", "error", err)
+	}
+	return err
+}
@@ -2,13 +2,17 @@ package db
 
 import (
 	"context"
+	"time"
 
 	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
 	"github.com/devshahriar/notification-manager/contract"
 	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
 )
 
 type DB interface {
+	Ping(context.Context) error
+
 	GetUserConfig(context.Context, string) (contract.ConfigIds, error)
 	GetEnabledNotificationTypes(context.Context, string, string) ([]model.NotificationConfig, error)
 	GetEmailMeta(context.Context, string, string, string) (contract.EmailMeta, error)
@@ -17,7 +21,8 @@ type DB interface {
 	SetUserConfig(context.Context, *nm.InstallIntegrationReq) error
 	SetAccountConfig(context.Context, *nm.UserMetaReq) error
 	DeleteUserConfig(context.Context, string)
-	IngestWorkerMeta(args *contract.WorkerArgs) error
+	IngestWorkerMeta(args *contract.WorkerArgs) (contract.WorkerSettings, error)
+	SetWorkerSettings(ctx context.Context, req *pb.WorkerSettings) (contract.WorkerSettings, error)
 
 	AddConfig(context.Context, *nm.NotificationConfig) error
 	EditConfig(context.Context, *nm.EditConfigReq) error
@@ -71,4 +76,97 @@ type DB interface {
 
 	DumpLog(log model.Logs) error
 	CheckValidUser(ctx context.Context, reqUserId string, configId uint64, tableName interface{}) bool
+
+	//Fallback
+	GetFallbackChain(ctx context.Context, userConfigId, eventType string) ([]model.FallbackRules, error)
+	SetFallbackChain(ctx context.Context, req *pb.FallbackChain) error
+	GetFallbackChains(ctx context.Context, req *pb.GetFallbackChainsReq) (*pb.GetFallbackChainsReply, error)
+	GetUserIdByConfigId(ctx context.Context, userConfigId string) (string, error)
+
+	//Escalation
+	SetEscalationPolicy(ctx context.Context, req *pb.EscalationPolicy) error
+	GetEscalationPolicy(ctx context.Context, req *pb.GetEscalationPolicyReq) (*pb.EscalationPolicy, error)
+	GetEscalationSteps(ctx context.Context, notificationConfigId uint64) ([]model.EscalationPolicies, error)
+	CreateEscalation(ctx context.Context, escalation *model.Escalations) error
+	GetEscalation(ctx context.Context, escalationId string) (*model.Escalations, error)
+	UpdateEscalationStep(ctx context.Context, escalationId string, step int) (bool, error)
+	AcknowledgeEscalation(ctx context.Context, escalationId, acknowledgedBy string) (string, error)
+	ExhaustEscalation(ctx context.Context, escalationId string) error
+	ResolveEscalations(ctx context.Context, userConfigId, accId string, eventTypes []string) error
+
+	//Filter
+	ValidateFilterExpression(ctx context.Context, notificationConfigId uint64, expr string) error
+	EditConfigFilter(ctx context.Context, notificationConfigId, userConfig uint64, expr string) error
+	EditBotEventFilter(ctx context.Context, userConfig, botConfigId, notificationConfigId uint64, expr string) error
+	GetConfigFilter(ctx context.Context, userConfig, notificationConfigId uint64) (string, error)
+	GetBotEventFilter(ctx context.Context, userConfig, botConfigId, notificationConfigId uint64) (string, error)
+	GetBotEventFilters(ctx context.Context, notificationConfigId uint64) ([]model.BotEventsRules, error)
+
+	//Flap suppression
+	SetFlapSettings(ctx context.Context, req *pb.FlapSettings) error
+	GetFlapSettings(ctx context.Context, req *pb.GetFlapSettingsReq) (*pb.FlapSettings, error)
+	GetFlapSettingsByUserConfig(ctx context.Context, userConfigId string) (model.FlapSettings, error)
+	UpdateConnectionState(ctx context.Context, accId string, fn func(state *model.ConnectionStates) error) error
+
+	//Scheduled notifications
+	CreateScheduledNotification(ctx context.Context, scheduled *model.ScheduledNotifications) error
+	ListScheduledNotifications(ctx context.Context, req *pb.ListScheduledNotificationsReq) (*pb.ListScheduledNotificationsReply, error)
+	CancelScheduledNotification(ctx context.Context, req *pb.CancelScheduledNotificationReq) (*pb.CancelScheduledNotificationReply, error)
+	MarkScheduledNotificationSent(ctx context.Context, scheduledId string) (bool, error)
+	MarkScheduledNotificationFailed(ctx context.Context, scheduledId string) error
+
+	//Worker heartbeats
+	Heartbeat(ctx context.Context, heartbeat *model.WorkerHeartbeats) error
+	GetWorkerHeartbeats(ctx context.Context) ([]model.WorkerHeartbeats, error)
+	DeleteStaleHeartbeats(ctx context.Context, before time.Time) error
+
+	//Routing plan
+	GetRoutingPlan(ctx context.Context, configIds contract.ConfigIds, eventType string) (model.RoutingPlan, error)
+
+	//Route explanation
+	GetEventNotificationConfigs(ctx context.Context, userConfigId string, eventType string) ([]model.NotificationConfig, error)
+	GetBotRouteCandidates(ctx context.Context, notificationConfigId uint64) ([]model.BotRouteCandidate, error)
+
+	//Broadcasts
+	CreateBroadcast(ctx context.Context, broadcast *model.Broadcasts, segment *pb.BroadcastSegment) error
+	GetBroadcast(ctx context.Context, broadcastId string) (model.Broadcasts, error)
+	GetBroadcastStatus(ctx context.Context, broadcastId string) (*pb.BroadcastStatus, error)
+	SetBroadcastStatus(ctx context.Context, broadcastId string, from []string, status string) (model.Broadcasts, error)
+	GetBroadcastBatch(ctx context.Context, broadcast model.Broadcasts) ([]model.UserConfig, error)
+	AdvanceBroadcast(ctx context.Context, broadcast model.Broadcasts, lastUserConfig uint64, processed int, completed bool) (bool, error)
+	GetBroadcastEmailMeta(ctx context.Context, userConfig, broadcastId string) (contract.EmailMeta, error)
+	GetBroadcastBotMeta(ctx context.Context, userConfig, broadcastId, notificationType string) ([]model.BotNotificationMeta, error)
+	SetAccountPlatform(ctx context.Context, accId, platform string) error
+	SetAccountPlatforms(ctx context.Context, accounts []*pb.AccountPlatform) ([]string, error)
+
+	//Notification lifecycle
+	RecordNotificationTransitions(ctx context.Context, transitions []model.NotificationTransitions) error
+	GetNotificationTransitions(ctx context.Context, notificationId string) ([]model.NotificationTransitions, error)
+	GetNotificationLogs(ctx context.Context, notificationId string) ([]model.Logs, error)
+
+	//Delivery history
+	ListNotificationLogs(ctx context.Context, filter contract.LogFilter, afterId uint64, limit int) ([]model.Logs, error)
+	GetLog(ctx context.Context, logId uint64) (model.Logs, error)
+	GetDeliveryLogs(ctx context.Context, notificationId string) ([]model.Logs, error)
+
+	//Log retention
+	GetExpiredLogs(ctx context.Context, before time.Time, limit int) ([]model.Logs, error)
+	DeleteLogs(ctx context.Context, ids []uint64) (int64, error)
+	CompactLogs(ctx context.Context, before time.Time, afterId uint64, limit int) (uint64, int64, error)
+	RestoreLogs(ctx context.Context, logs []model.Logs) (int64, error)
+
+	//Secrets
+	RotateBotTokens(ctx context.Context) (int64, error)
+	RedactLogSecrets(ctx context.Context) (int64, error)
+
+	//Dead letters
+	CreateDeadLetter(ctx context.Context, letter *model.DeadLetters) error
+	ListDeadLetters(ctx context.Context, filter contract.DeadLetterFilter, afterId uint64, limit int) ([]model.DeadLetters, error)
+	GetDeadLetter(ctx context.Context, deadLetterId string) (model.DeadLetters, error)
+	MarkDeadLetterReplayed(ctx context.Context, deadLetterId string, onlyDead bool) (bool, error)
+	ResetDeadLetter(ctx context.Context, deadLetterId string) error
+	PurgeDeadLetters(ctx context.Context, ids []string, filter contract.DeadLetterFilter) (int64, error)
+
+	//Fan-outs
+	FinishFanOut(ctx context.Context, fanOut model.FanOuts, sent bool) (model.FanOuts, error)
 }
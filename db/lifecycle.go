@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devshahriar/notification-manager/model"
+)
+
+func (m *Mysql) RecordNotificationTransitions(ctx context.Context, transitions []model.NotificationTransitions) error {
+	if len(transitions) == 0 {
+		return nil
+	}
+
+	err := m.DB.WithContext(ctx).CreateInBatches(&transitions, 500).Error
+	if err != nil {
+		m.Log.Errorw("Error recording notification transitions", "notificationId", transitions[0].NotificationId, "error", err)
+	}
+	return err
+}
+
+func (m *Mysql) GetNotificationTransitions(ctx context.Context, notificationId string) ([]model.NotificationTransitions, error) {
+	fName := "GetNotificationTransitions"
+	start := time.Now()
+
+	var transitions []model.NotificationTransitions
+	err := m.DB.WithContext(ctx).Model(&model.NotificationTransitions{}).
+		Where("notification_id = ?", notificationId).
+		Order("id").
+		Scan(&transitions).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While getting transitions of notification:%v err:%+v", notificationId, err),
+		fmt.Sprintf("Success: Got %v transitions of notification:%v", len(transitions), notificationId),
+		start)
+
+	return transitions, err
+}
+
+func (m *Mysql) GetNotificationLogs(ctx context.Context, notificationId string) ([]model.Logs, error) {
+	fName := "GetNotificationLogs"
+	start := time.Now()
+
+	var logs []model.Logs
+	err := m.DB.WithContext(ctx).Model(&model.Logs{}).
+		Select("id", "created_at", "notification_type", "status", "chain_id", "fallback_from").
+		Where("notification_id = ?", notificationId).
+		Order("id").
+		Scan(&logs).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While getting logs of notification:%v err:%+v", notificationId, err),
+		fmt.Sprintf("Success: Got %v logs of notification:%v", len(logs), notificationId),
+		start)
+
+	return logs, err
+}
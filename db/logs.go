@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+)
+
+// ListNotificationLogs returns up to limit logs of the filter older than the log afterId, newest first.
+// afterId 0 starts from the newest log
+func (m *Mysql) ListNotificationLogs(ctx context.Context, filter contract.LogFilter, afterId uint64, limit int) ([]model.Logs, error) {
+	fName := "ListNotificationLogs"
+	start := time.Now()
+
+	query := m.DB.WithContext(ctx).Model(&model.Logs{})
+	if filter.UserConfig != "" {
+		query = query.Where("user_config = ?", filter.UserConfig)
+	}
+	if filter.AccountId != "" {
+		query = query.Where("account_id = ?", filter.AccountId)
+	}
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+	if filter.NotificationType != "" {
+		query = query.Where("notification_type = ?", filter.NotificationType)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if afterId > 0 {
+		query = query.Where("id < ?", afterId)
+	}
+
+	var logs []model.Logs
+	err := query.Order("id DESC").Limit(limit).Find(&logs).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While listing notification logs filter:%+v err:%+v", filter, err),
+		fmt.Sprintf("Success: Listed %v notification logs", len(logs)),
+		start)
+
+	return logs, err
+}
+
+func (m *Mysql) GetLog(ctx context.Context, logId uint64) (model.Logs, error) {
+	fName := "GetLog"
+	start := time.Now()
+
+	var log model.Logs
+	err := m.DB.WithContext(ctx).Where("id = ?", logId).First(&log).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While getting log %v err:%+v", logId, err),
+		fmt.Sprintf("Success: Got log %v", logId),
+		start)
+
+	return log, err
+}
+
+// GetDeliveryLogs returns the logs of the deliveries of a notification, oldest first.
+// Fallback and dead letter entries are left out
+func (m *Mysql) GetDeliveryLogs(ctx context.Context, notificationId string) ([]model.Logs, error) {
+	fName := "GetDeliveryLogs"
+	start := time.Now()
+
+	var logs []model.Logs
+	err := m.DB.WithContext(ctx).
+		Where("notification_id = ? AND status IN ?", notificationId, []string{contract.STATUS_SUCCESS, contract.STATUS_FAILED}).
+		Order("id").
+		Find(&logs).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While getting delivery logs of notification:%v err:%+v", notificationId, err),
+		fmt.Sprintf("Success: Got %v delivery logs of notification:%v", len(logs), notificationId),
+		start)
+
+	return logs, err
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"gorm.io/gorm/clause"
+)
+
+// GetExpiredLogs returns up to limit of the oldest logs created before the given time
+func (m *Mysql) GetExpiredLogs(ctx context.Context, before time.Time, limit int) ([]model.Logs, error) {
+	fName := "GetExpiredLogs"
+	start := time.Now()
+
+	var logs []model.Logs
+	err := m.DB.WithContext(ctx).Model(&model.Logs{}).
+		Where("created_at < ?", before).
+		Order("id").Limit(limit).
+		Find(&logs).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While getting logs created before:%v err:%+v", before, err),
+		fmt.Sprintf("Success: Got %v expired logs", len(logs)),
+		start)
+
+	return logs, err
+}
+
+// DeleteLogs deletes the logs by primary key so only the rows of the batch are locked
+func (m *Mysql) DeleteLogs(ctx context.Context, ids []uint64) (int64, error) {
+	fName := "DeleteLogs"
+	start := time.Now()
+
+	result := m.DB.WithContext(ctx).Where("id IN ?", ids).Delete(&model.Logs{})
+
+	m.LogError(fName,
+		result.Error != nil,
+		fmt.Sprintf("Error: While deleting %v logs err:%+v", len(ids), result.Error),
+		fmt.Sprintf("Success: Deleted %v logs", result.RowsAffected),
+		start)
+
+	return result.RowsAffected, result.Error
+}
+
+// CompactLogs drops the rendered message from the req meta of up to limit logs after afterId created before
+// the given time. It returns the last id it looked at so the next batch continues from there
+func (m *Mysql) CompactLogs(ctx context.Context, before time.Time, afterId uint64, limit int) (uint64, int64, error) {
+	fName := "CompactLogs"
+	start := time.Now()
+
+	var ids []uint64
+	err := m.DB.WithContext(ctx).Model(&model.Logs{}).
+		Where("id > ? AND created_at < ?", afterId, before).
+		Order("id").Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		m.LogError(fName, true, fmt.Sprintf("Error: While getting logs to compact after:%v err:%+v", afterId, err), "", start)
+		return afterId, 0, err
+	}
+	if len(ids) == 0 {
+		return afterId, 0, nil
+	}
+
+	result := m.DB.WithContext(ctx).Model(&model.Logs{}).
+		Where("id IN ? AND (JSON_CONTAINS_PATH(req_meta, 'one', '$.Message') OR payload IS NOT NULL)", ids).
+		UpdateColumns(map[string]interface{}{
+			"req_meta": clause.Expr{SQL: "JSON_REMOVE(req_meta, '$.Message')"},
+			"payload":  nil,
+		})
+
+	m.LogError(fName,
+		result.Error != nil,
+		fmt.Sprintf("Error: While compacting logs after:%v err:%+v", afterId, result.Error),
+		fmt.Sprintf("Success: Compacted %v logs up to:%v", result.RowsAffected, ids[len(ids)-1]),
+		start)
+
+	return ids[len(ids)-1], result.RowsAffected, result.Error
+}
+
+// RestoreLogs inserts archived logs into the restored logs table. Logs restored before are skipped
+func (m *Mysql) RestoreLogs(ctx context.Context, logs []model.Logs) (int64, error) {
+	fName := "RestoreLogs"
+	start := time.Now()
+
+	err := m.DB.WithContext(ctx).Table(contract.RESTORED_LOGS_TABLE).AutoMigrate(&model.Logs{})
+	if err != nil {
+		m.LogError(fName, true, fmt.Sprintf("Error: While migrating %v err:%+v", contract.RESTORED_LOGS_TABLE, err), "", start)
+		return 0, err
+	}
+	if len(logs) == 0 {
+		return 0, nil
+	}
+
+	result := m.DB.WithContext(ctx).Table(contract.RESTORED_LOGS_TABLE).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(logs, contract.LOG_PURGE_BATCH_SIZE)
+
+	m.LogError(fName,
+		result.Error != nil,
+		fmt.Sprintf("Error: While restoring %v logs err:%+v", len(logs), result.Error),
+		fmt.Sprintf("Success: Restored %v logs", result.RowsAffected),
+		start)
+
+	return result.RowsAffected, result.Error
+}
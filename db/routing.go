@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/filter"
+	"github.com/devshahriar/notification-manager/model"
+)
+
+// GetRoutingPlan reads the enabled configs of the event with the account blocks, integration status,
+// fallback chain and bot filters of the user in a fixed number of queries
+func (m *Mysql) GetRoutingPlan(ctx context.Context, configIds contract.ConfigIds, eventType string) (model.RoutingPlan, error) {
+	fName := "GetRoutingPlan"
+	start := time.Now()
+
+	plan := model.RoutingPlan{
+		UserId:     configIds.UserId,
+		Blocked:    map[uint64]bool{},
+		BotFilters: map[uint64][]model.BotEventsRules{},
+	}
+
+	integration, err := m.GetIntegrationStatus(ctx, &nm.IntegrationStatusReq{UserId: configIds.UserId})
+	if err != nil {
+		return plan, err
+	}
+	plan.Integration = integration
+
+	plan.Configs, err = m.GetEnabledNotificationTypes(ctx, configIds.UserConfigId, eventType)
+	if err != nil {
+		return plan, err
+	}
+
+	plan.Chain, err = m.GetFallbackChain(ctx, configIds.UserConfigId, eventType)
+	if err != nil {
+		return plan, err
+	}
+
+	configs := []uint64{}
+	botConfigs := []uint64{}
+	for _, v := range plan.Configs {
+		configs = append(configs, v.ID)
+		if v.NotificationType == contract.TELEGRAM || v.NotificationType == contract.DISCORD {
+			botConfigs = append(botConfigs, v.ID)
+		}
+	}
+
+	if configIds.AccountConfId != "" && len(configs) > 0 {
+		var blocked []uint64
+		err = m.DB.WithContext(ctx).Model(&model.AccountNotificationRules{}).
+			Where("account_config_id = ? AND notification_config_id IN ? AND disabled = ?", configIds.AccountConfId, configs, true).
+			Pluck("notification_config_id", &blocked).Error
+		if err != nil {
+			m.LogError(fName, true, fmt.Sprintf("Error: Retrieving blocked configs for accountConfig:%v err:%+v", configIds.AccountConfId, err), "", start)
+			return plan, err
+		}
+		for _, v := range blocked {
+			plan.Blocked[v] = true
+		}
+	}
+
+	if len(botConfigs) > 0 {
+		var rules []model.BotEventsRules
+		err = m.DB.WithContext(ctx).Model(&model.BotEventsRules{}).
+			Select("id", "bot_config_id", "notification_config_id", "filter_expression").
+			Where("notification_config_id IN ?", botConfigs).
+			Scan(&rules).Error
+		if err != nil {
+			m.LogError(fName, true, fmt.Sprintf("Error: Retrieving bot event filters for userConfig:%v err:%+v", configIds.UserConfigId, err), "", start)
+			return plan, err
+		}
+		for _, v := range rules {
+			plan.BotFilters[v.NotificationConfigId] = append(plan.BotFilters[v.NotificationConfigId], v)
+		}
+	}
+
+	m.ParseFilters(&plan)
+
+	m.LogError(fName,
+		false,
+		"",
+		fmt.Sprintf("Success: Built routing plan for userConfig:%v accountConfig:%v eventType:%v", configIds.UserConfigId, configIds.AccountConfId, eventType),
+		start)
+
+	return plan, nil
+}
+
+// ParseFilters parses the filter expressions of the plan once so routing an event only evaluates them.
+// An invalid expression is logged and doesn't filter
+func (m *Mysql) ParseFilters(plan *model.RoutingPlan) {
+	plan.Filters = map[string]filter.Node{}
+
+	exprs := []string{}
+	for _, v := range plan.Configs {
+		exprs = append(exprs, v.FilterExpression)
+	}
+	for _, rules := range plan.BotFilters {
+		for _, v := range rules {
+			exprs = append(exprs, v.FilterExpression)
+		}
+	}
+
+	for _, expr := range exprs {
+		if _, ok := plan.Filters[expr]; ok || expr == "" {
+			continue
+		}
+		node, err := filter.Parse(expr)
+		if err != nil {
+			m.Log.Errorw("Invalid filter expression. Ignoring filter", "userId", plan.UserId, "expression", expr, "error", err)
+		}
+		plan.Filters[expr] = node
+	}
+}
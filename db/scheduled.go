@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+)
+
+func (m *Mysql) CreateScheduledNotification(ctx context.Context, scheduled *model.ScheduledNotifications) error {
+	fName := "CreateScheduledNotification"
+	start := time.Now()
+
+	err := m.DB.WithContext(ctx).Create(scheduled).Error
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While scheduling notification for accountId:%v eventType:%v err:%+v", scheduled.AccountId, scheduled.EventType, err),
+		fmt.Sprintf("Success: Scheduled notification:%v at %v", scheduled.UuId, scheduled.SendAt),
+		start)
+
+	return err
+}
+
+func (m *Mysql) ListScheduledNotifications(ctx context.Context, req *pb.ListScheduledNotificationsReq) (*pb.ListScheduledNotificationsReply, error) {
+	fName := "ListScheduledNotifications"
+	start := time.Now()
+
+	query := m.DB.WithContext(ctx).Model(&model.ScheduledNotifications{})
+	if req.UserId != "" {
+		query = query.Where("user_id = ?", req.UserId)
+	}
+	if req.AccountId != "" {
+		query = query.Where("account_id = ?", req.AccountId)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var scheduled []model.ScheduledNotifications
+	err := query.Order("send_at asc").Scan(&scheduled).Error
+
+	reply := &pb.ListScheduledNotificationsReply{ScheduledNotifications: []*pb.ScheduledNotification{}}
+	for _, v := range scheduled {
+		data := map[string]string{}
+		_ = json.Unmarshal(v.Data, &data)
+
+		reply.ScheduledNotifications = append(reply.ScheduledNotifications, &pb.ScheduledNotification{
+			ScheduledNotificationId: v.UuId,
+			UserId:                  v.UserId,
+			AccountId:               v.AccountId,
+			EventType:               v.EventType,
+			Data:                    data,
+			SendAt:                  v.SendAt.UTC().Format(time.RFC3339),
+			Status:                  v.Status,
+		})
+	}
+
+	m.LogError(fName,
+		err != nil,
+		fmt.Sprintf("Error: While listing scheduled notifications for userId:%v accountId:%v err:%+v", req.UserId, req.AccountId, err),
+		fmt.Sprintf("Success: Listed scheduled notifications for userId:%v accountId:%v", req.UserId, req.AccountId),
+		start)
+
+	return reply, err
+}
+
+// CancelScheduledNotification cancels a notification that wasn't sent yet
+func (m *Mysql) CancelScheduledNotification(ctx context.Context, req *pb.CancelScheduledNotificationReq) (*pb.CancelScheduledNotificationReply, error) {
+	fName := "CancelScheduledNotification"
+	start := time.Now()
+
+	result := m.DB.WithContext(ctx).Model(&model.ScheduledNotifications{}).
+		Where("uu_id = ? AND status = ?", req.ScheduledNotificationId, contract.SCHEDULED_PENDING).
+		Update("status", contract.SCHEDULED_CANCELLED)
+
+	m.LogError(fName,
+		result.Error != nil,
+		fmt.Sprintf("Error: While cancelling scheduled notification:%v err:%+v", req.ScheduledNotificationId, result.Error),
+		fmt.Sprintf("Success: Cancelled scheduled notification:%v", req.ScheduledNotificationId),
+		start)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var status string
+	err := m.DB.WithContext(ctx).Model(&model.ScheduledNotifications{}).
+		Where("uu_id = ?", req.ScheduledNotificationId).Select("status").Scan(&status).Error
+	if err != nil || status == "" {
+		return nil, fmt.Errorf("scheduled notification doesn't exist id: %v", req.ScheduledNotificationId)
+	}
+	return &pb.CancelScheduledNotificationReply{Status: status}, nil
+}
+
+// MarkScheduledNotificationSent claims a scheduled notification for sending.
+// Returns false when it was cancelled or already sent
+func (m *Mysql) MarkScheduledNotificationSent(ctx context.Context, scheduledId string) (bool, error) {
+
+	result := m.DB.WithContext(ctx).Model(&model.ScheduledNotifications{}).
+		Where("uu_id = ? AND status = ?", scheduledId, contract.SCHEDULED_PENDING).
+		Update("status", contract.SCHEDULED_SENT)
+
+	if result.Error != nil {
+		m.Log.Errorw("Error marking scheduled notification sent", "scheduledId", scheduledId, "error", result.Error)
+	}
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkScheduledNotificationFailed marks a scheduled notification that couldn't be published so it isn't listed as pending
+func (m *Mysql) MarkScheduledNotificationFailed(ctx context.Context, scheduledId string) error {
+
+	err := m.DB.WithContext(ctx).Model(&model.ScheduledNotifications{}).
+		Where("uu_id = ? AND status = ?", scheduledId, contract.SCHEDULED_PENDING).
+		Update("status", contract.SCHEDULED_FAILED).Error
+
+	if err != nil {
+		m.Log.Errorw("Error marking scheduled notification failed", "scheduledId", scheduledId, "error", err)
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/secrets"
+	"gorm.io/gorm/clause"
+)
+
+// decryptBotTokens decrypts the tokens of the bot meta. Bots whose token can't be decrypted are dropped
+// so the other bots of the user still get the notification
+func (m *Mysql) decryptBotTokens(ctx context.Context, fName string, meta []model.BotNotificationMeta) []model.BotNotificationMeta {
+	decrypted := make([]model.BotNotificationMeta, 0, len(meta))
+	for _, v := range meta {
+		token, err := m.Secrets.Decrypt(ctx, v.BotToken)
+		if err != nil {
+			m.Log.Errorw("Error decrypting bot token", "fName", fName, "botConfigId", v.BotConfigId, "error", err)
+			continue
+		}
+		v.BotToken = token
+		decrypted = append(decrypted, v)
+	}
+	return decrypted
+}
+
+func (m *Mysql) maskBotToken(ctx context.Context, stored string) string {
+	token, err := m.Secrets.Decrypt(ctx, stored)
+	if err != nil {
+		return secrets.MASK
+	}
+	return secrets.Mask(token)
+}
+
+// RotateBotTokens re-encrypts the bot tokens that are in plaintext or wrapped with an old key with the primary key.
+// Tokens are swapped only if they weren't changed meanwhile
+func (m *Mysql) RotateBotTokens(ctx context.Context) (int64, error) {
+	fName := "RotateBotTokens"
+	start := time.Now()
+
+	if !m.Secrets.Enabled() {
+		return 0, fmt.Errorf("no secret keys configured")
+	}
+
+	rotated := int64(0)
+	lastId := uint64(0)
+	for {
+		var bots []model.BotConfigs
+		err := m.DB.WithContext(ctx).Model(&model.BotConfigs{}).
+			Select("id", "bot_token").
+			Where("id > ?", lastId).
+			Order("id").Limit(contract.SECRET_ROTATION_BATCH_SIZE).
+			Find(&bots).Error
+		if err != nil {
+			m.LogError(fName, true, fmt.Sprintf("Error: While getting bot tokens after:%v err:%+v", lastId, err), "", start)
+			return rotated, err
+		}
+		if len(bots) == 0 {
+			break
+		}
+		lastId = bots[len(bots)-1].ID
+
+		for _, v := range bots {
+			if !m.Secrets.NeedsRotation(v.BotToken) {
+				continue
+			}
+			token, err := m.Secrets.Decrypt(ctx, v.BotToken)
+			if err != nil {
+				m.LogError(fName, true, fmt.Sprintf("Error: While decrypting bot token of botConfigId:%v err:%+v", v.ID, err), "", start)
+				return rotated, err
+			}
+			encrypted, err := m.Secrets.Encrypt(ctx, token)
+			if err != nil {
+				return rotated, err
+			}
+
+			result := m.DB.WithContext(ctx).Model(&model.BotConfigs{}).
+				Where("id = ? AND bot_token = ?", v.ID, v.BotToken).
+				UpdateColumn("bot_token", encrypted)
+			if result.Error != nil {
+				m.LogError(fName, true, fmt.Sprintf("Error: While rotating bot token of botConfigId:%v err:%+v", v.ID, result.Error), "", start)
+				return rotated, result.Error
+			}
+			rotated += result.RowsAffected
+		}
+	}
+
+	m.LogError(fName, false, "", fmt.Sprintf("Success: Rotated %v bot tokens", rotated), start)
+	return rotated, nil
+}
+
+// RedactLogSecrets removes the bot tokens older releases copied into the req meta of the logs
+func (m *Mysql) RedactLogSecrets(ctx context.Context) (int64, error) {
+	fName := "RedactLogSecrets"
+	start := time.Now()
+
+	redacted := int64(0)
+	lastId := uint64(0)
+	for {
+		var ids []uint64
+		err := m.DB.WithContext(ctx).Model(&model.Logs{}).
+			Where("id > ?", lastId).
+			Order("id").Limit(contract.LOG_PURGE_BATCH_SIZE).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			m.LogError(fName,
+				err != nil,
+				fmt.Sprintf("Error: While getting logs after:%v err:%+v", lastId, err),
+				fmt.Sprintf("Success: Redacted %v logs", redacted),
+				start)
+			return redacted, err
+		}
+		lastId = ids[len(ids)-1]
+
+		result := m.DB.WithContext(ctx).Model(&model.Logs{}).
+			Where("id IN ? AND JSON_CONTAINS_PATH(req_meta, 'one', '$.BotToken')", ids).
+			UpdateColumn("req_meta", clause.Expr{SQL: "JSON_REMOVE(req_meta, '$.BotToken')"})
+		if result.Error != nil {
+			m.LogError(fName, true, fmt.Sprintf("Error: While redacting logs after:%v err:%+v", lastId, result.Error), "", start)
+			return redacted, result.Error
+		}
+		redacted += result.RowsAffected
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/devShahriar/H"
 	"github.com/devshahriar/notification-manager/contract"
 	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/secrets"
 )
 
 // This will return bot_token,message_template,channel_id etc
@@ -22,7 +23,7 @@ func (m *Mysql) GetBotNotificationMeta(ctx context.Context, userConfigId, eventT
 
 	var results []model.BotNotificationMeta
 	err := m.DB.WithContext(ctx).Table("bot_configs bc").
-		Select("uc.first_name, bc.bot_token, cc.channel_id, nc.event_type, nc.message_template, nc.subject, nc.notification_type").
+		Select("bc.id as bot_config_id, uc.first_name, bc.bot_token, cc.channel_id, nc.event_type, nc.message_template, nc.subject, nc.notification_type").
 		Joins("join bot_events_rules ber on bc.id = ber.bot_config_id").
 		Joins("join channel_rules cr on ber.id = cr.bot_event_rules_id").
 		Joins("join notification_configs nc on ber.notification_config_id = nc.id").
@@ -30,6 +31,9 @@ func (m *Mysql) GetBotNotificationMeta(ctx context.Context, userConfigId, eventT
 		Joins("join user_configs uc on uc.id = bc.user_config").
 		Where("bc.enabled = true AND nc.enabled = true AND cc.enabled = true AND bc.user_config = ? AND nc.event_type = ? AND nc.notification_type = ?",
 			userConfigId, eventType, notificationType).Scan(&results).Error
+	if err == nil {
+		results = m.decryptBotTokens(ctx, fName, results)
+	}
 
 	m.LogError(fName,
 		err != nil,
@@ -55,25 +59,31 @@ func (m *Mysql) AddBot(ctx context.Context, req *nm.AddBotReq) error {
 		return fmt.Errorf("bot name conflict with default bot name:%v", contract.DefaultTelegramBot)
 	}
 
+	botToken, err := m.Secrets.Encrypt(ctx, req.BotToken)
+	if err != nil {
+		m.LogError(fName, true, fmt.Sprintf("Error: While encrypting bot token for userId:%v err:%+v", req.UserId, err), "", start)
+		return err
+	}
+
 	botMeta := model.BotConfigs{
 		UserConfig:       *userConfig,
 		BotName:          req.BotName,
-		BotToken:         req.BotToken,
+		BotToken:         botToken,
 		BotDescription:   req.BotDescription,
 		NotificationType: req.NotificationType,
 		Enabled:          true,
 	}
 
-	result := m.DB.WithContext(ctx).Where(botMeta).Attrs(botMeta).FirstOrCreate(&model.BotConfigs{})
+	// The encrypted token differs on every call so bots are matched by name.
+	// Adding a bot again replaces its token and description
+	lookup := model.BotConfigs{UserConfig: *userConfig, BotName: req.BotName, NotificationType: req.NotificationType}
+	result := m.DB.WithContext(ctx).Where(lookup).Attrs(botMeta).
+		Assign(map[string]interface{}{"bot_token": botToken, "bot_description": req.BotDescription}).
+		FirstOrCreate(&model.BotConfigs{})
 
-	if result.Error == nil && result.RowsAffected > 0 {
-		m.Log.Info("New Bot config added")
-	} else {
-		m.Log.Errorw("Error: Bot Config already exists")
-	}
 	m.LogError(fName,
-		err != nil,
-		fmt.Sprintf("Error: While adding BotConfig for userId:%v err:%+v", req.UserId, err),
+		result.Error != nil,
+		fmt.Sprintf("Error: While adding BotConfig for userId:%v err:%+v", req.UserId, result.Error),
 		fmt.Sprintf("Success: Added new bot config for userId:%v", req.UserId),
 		start)
 	return result.Error
@@ -88,14 +98,27 @@ func (m *Mysql) EditBot(ctx context.Context, meta *nm.EditBotReq) error {
 		return fmt.Errorf("bot name conflict with default bot name:%v", contract.DefaultTelegramBot)
 	}
 
+	// GetBots returns masked tokens. Sending one back keeps the stored token
+	columns := []string{"bot_name", "bot_description"}
+	botToken := ""
+	if meta.BotToken != "" && !secrets.IsMasked(meta.BotToken) {
+		var err error
+		botToken, err = m.Secrets.Encrypt(ctx, meta.BotToken)
+		if err != nil {
+			m.LogError(fName, true, fmt.Sprintf("Error: While encrypting bot token for botConfigId:%v err:%+v", meta.BotConfigId, err), "", start)
+			return err
+		}
+		columns = append(columns, "bot_token")
+	}
+
 	result := m.DB.WithContext(ctx).Model(&model.BotConfigs{}).
 		Where("id = ?", meta.BotConfigId).
-		Select("bot_name", "bot_description", "bot_token").
+		Select(columns).
 		Updates(
 			&model.BotConfigs{
 				BotName:        meta.BotName,
 				BotDescription: meta.BotDescription,
-				BotToken:       meta.BotToken,
+				BotToken:       botToken,
 			},
 		)
 
@@ -142,7 +165,7 @@ func (m *Mysql) GetBot(ctx context.Context, meta *nm.GetBotsReq) (*nm.GetBotsRep
 			BotConfigId:    v.ID,
 			BotName:        v.BotName,
 			BotDescription: v.BotDescription,
-			BotToken:       H.If(v.BotName == contract.DefaultTelegramBot, "", v.BotToken),
+			BotToken:       H.If(v.BotName == contract.DefaultTelegramBot, "", m.maskBotToken(ctx, v.BotToken)),
 			Enabled:        v.Enabled,
 		}
 
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"errors"
+
+	"go.opencensus.io/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// RegisterTracing adds a span to every query run with the context of a traced task or request
+func RegisterTracing(db *gorm.DB) error {
+	callbacks := db.Callback()
+	errs := []error{
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", startSpan("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", endSpan),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", startSpan("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", endSpan),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", startSpan("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", endSpan),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", startSpan("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", endSpan),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", startSpan("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", endSpan),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", startSpan("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", endSpan),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		if ctx == nil || trace.FromContext(ctx) == nil {
+			return
+		}
+		_, span := trace.StartSpan(ctx, "db."+operation, trace.WithSpanKind(trace.SpanKindClient))
+		tx.InstanceSet(spanKey, span)
+	}
+}
+
+func endSpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(*trace.Span)
+	span.AddAttributes(
+		trace.StringAttribute("db.table", tx.Statement.Table),
+		trace.Int64Attribute("db.rows_affected", tx.Statement.RowsAffected),
+	)
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: tx.Error.Error()})
+	}
+	span.End()
+}
@@ -0,0 +1,306 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Filter expressions are evaluated against the decoded event data.
+//
+//	COPIER_MASTER_SYMBOL == "XAUUSD"
+//	COPIER_MASTER in ("1234", "5678") && !(COPIER_ERROR contains "timeout")
+//
+// Supported operators are == != > >= < <= contains in && || ! and parentheses.
+// > >= < <= compare numbers, the rest compare strings. Missing keys are empty strings.
+
+type Node interface {
+	Eval(data map[string]string) bool
+}
+
+type and struct{ left, right Node }
+type or struct{ left, right Node }
+type not struct{ node Node }
+
+type comparison struct {
+	key    string
+	op     string
+	values []string
+}
+
+func (n and) Eval(data map[string]string) bool { return n.left.Eval(data) && n.right.Eval(data) }
+func (n or) Eval(data map[string]string) bool  { return n.left.Eval(data) || n.right.Eval(data) }
+func (n not) Eval(data map[string]string) bool { return !n.node.Eval(data) }
+
+func (c comparison) Eval(data map[string]string) bool {
+	value := data[c.key]
+
+	switch c.op {
+	case "==":
+		return equal(value, c.values[0])
+	case "!=":
+		return !equal(value, c.values[0])
+	case "contains":
+		return strings.Contains(value, c.values[0])
+	case "in":
+		for _, v := range c.values {
+			if equal(value, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	left, err1 := strconv.ParseFloat(value, 64)
+	right, err2 := strconv.ParseFloat(c.values[0], 64)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+
+	switch c.op {
+	case ">":
+		return left > right
+	case ">=":
+		return left >= right
+	case "<":
+		return left < right
+	case "<=":
+		return left <= right
+	}
+	return false
+}
+
+// equal compares numbers by value so 1234 matches "1234.0"
+func equal(a, b string) bool {
+	if a == b {
+		return true
+	}
+	x, err1 := strconv.ParseFloat(a, 64)
+	y, err2 := strconv.ParseFloat(b, 64)
+	return err1 == nil && err2 == nil && x == y
+}
+
+// Parse parses a filter expression. Empty expression is parsed to nil
+func Parse(expr string) (Node, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].value, p.tokens[p.pos].pos)
+	}
+	return node, nil
+}
+
+// Validate checks the syntax of the expression. When keys is not empty
+// the expression may only use those keys
+func Validate(expr string, keys []string) error {
+	node, err := Parse(expr)
+	if err != nil || node == nil || len(keys) == 0 {
+		return err
+	}
+
+	allowed := map[string]bool{}
+	for _, k := range keys {
+		allowed[k] = true
+	}
+	for _, k := range Keys(node) {
+		if !allowed[k] {
+			return fmt.Errorf("unknown key %v. Allowed keys are %v", k, strings.Join(keys, ", "))
+		}
+	}
+	return nil
+}
+
+// Match evaluates the expression against data. Empty expression always matches
+func Match(expr string, data map[string]string) (bool, error) {
+	node, err := Parse(expr)
+	if err != nil {
+		return false, err
+	}
+	if node == nil {
+		return true, nil
+	}
+	return node.Eval(data), nil
+}
+
+// Keys returns the data keys used in the expression
+func Keys(node Node) []string {
+	switch n := node.(type) {
+	case and:
+		return append(Keys(n.left), Keys(n.right)...)
+	case or:
+		return append(Keys(n.left), Keys(n.right)...)
+	case not:
+		return Keys(n.node)
+	case comparison:
+		return []string{n.key}
+	}
+	return nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *parser) next() *token {
+	t := p.peek()
+	if t != nil {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind, value string) error {
+	t := p.next()
+	if t == nil {
+		return fmt.Errorf("expected %q at end of expression", value)
+	}
+	if t.kind != kind || t.value != value {
+		return fmt.Errorf("expected %q got %q at position %d", value, t.value, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t != nil && t.kind == tokenOperator && t.value == "||"; t = p.peek() {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t != nil && t.kind == tokenOperator && t.value == "&&"; t = p.peek() {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = and{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	if t.kind == tokenOperator && t.value == "!" {
+		p.next()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return not{node}, nil
+	}
+
+	if t.kind == tokenOperator && t.value == "(" {
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(tokenOperator, ")")
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	key := p.next()
+	if key == nil {
+		return nil, fmt.Errorf("expected key at end of expression")
+	}
+	if key.kind != tokenIdent {
+		return nil, fmt.Errorf("expected key got %q at position %d", key.value, key.pos)
+	}
+
+	op := p.next()
+	if op == nil || (op.kind != tokenOperator && op.kind != tokenIdent) || !isComparison(op.value) {
+		return nil, fmt.Errorf("expected comparison operator after %v", key.value)
+	}
+
+	if op.value != "in" {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return comparison{key: key.value, op: op.value, values: []string{value}}, nil
+	}
+
+	if err := p.expect(tokenOperator, "("); err != nil {
+		return nil, err
+	}
+	values := []string{}
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		t := p.next()
+		if t == nil {
+			return nil, fmt.Errorf("expected \")\" at end of expression")
+		}
+		if t.kind == tokenOperator && t.value == ")" {
+			break
+		}
+		if t.kind != tokenOperator || t.value != "," {
+			return nil, fmt.Errorf("expected \",\" got %q at position %d", t.value, t.pos)
+		}
+	}
+	return comparison{key: key.value, op: op.value, values: values}, nil
+}
+
+func (p *parser) parseValue() (string, error) {
+	t := p.next()
+	if t == nil {
+		return "", fmt.Errorf("expected value at end of expression")
+	}
+	if t.kind != tokenString && t.kind != tokenNumber {
+		return "", fmt.Errorf("expected quoted string or number got %q at position %d", t.value, t.pos)
+	}
+	return t.value, nil
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", ">", ">=", "<", "<=", "contains", "in":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/streadway/amqp"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check reports whether a dependency can be used
+type Check func(ctx context.Context) error
+
+// Checker polls the dependencies of a service and keeps their last status so health probes
+// don't reach the dependencies themselves. Watchers are notified when a status changes
+type Checker struct {
+	Interval time.Duration
+	Timeout  time.Duration
+	Logger   *zap.SugaredLogger
+
+	checks map[string]Check
+
+	mu       sync.Mutex
+	errs     map[string]error
+	checked  bool
+	watchers map[chan struct{}]bool
+}
+
+func NewChecker(interval, timeout time.Duration, logger *zap.SugaredLogger) *Checker {
+	return &Checker{
+		Interval: interval,
+		Timeout:  timeout,
+		Logger:   logger,
+		checks:   map[string]Check{},
+		errs:     map[string]error{},
+		watchers: map[chan struct{}]bool{},
+	}
+}
+
+// Add checks the dependency name. Dependencies are added before Start
+func (c *Checker) Add(name string, check Check) {
+	c.checks[name] = check
+}
+
+// Start checks the dependencies once and keeps polling them every interval until ctx is done
+func (c *Checker) Start(ctx context.Context) {
+	c.Refresh(ctx)
+	go func() {
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.Refresh(ctx)
+			}
+		}
+	}()
+}
+
+// Refresh checks every dependency concurrently and notifies the watchers when a status changed
+func (c *Checker) Refresh(ctx context.Context) {
+	errs := make(map[string]error, len(c.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range c.checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+			defer cancel()
+			err := check(checkCtx)
+			mu.Lock()
+			errs[name] = err
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	changed := false
+	for name, err := range errs {
+		last, checked := c.errs[name]
+		if checked && (err == nil) == (last == nil) {
+			continue
+		}
+		changed = true
+		if err != nil {
+			c.Logger.Errorw("Dependency is not serving", "dependency", name, "error", err)
+		} else if checked {
+			c.Logger.Infow("Dependency is serving again", "dependency", name)
+		}
+	}
+	c.errs = errs
+	c.checked = true
+
+	if !changed {
+		return
+	}
+	for w := range c.watchers {
+		select {
+		case w <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Status returns the status of the dependency service, or of every dependency for the empty service.
+// It reports false for a service that isn't checked
+func (c *Checker) Status(service string) (grpc_health_v1.HealthCheckResponse_ServingStatus, bool) {
+	if c == nil && service == "" {
+		return grpc_health_v1.HealthCheckResponse_SERVING, true
+	}
+	if c == nil {
+		return grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN, false
+	}
+	if _, ok := c.checks[service]; !ok && service != "" {
+		return grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checked {
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING, true
+	}
+	for name, err := range c.errs {
+		if err != nil && (service == "" || service == name) {
+			return grpc_health_v1.HealthCheckResponse_NOT_SERVING, true
+		}
+	}
+	return grpc_health_v1.HealthCheckResponse_SERVING, true
+}
+
+// Errors returns the status of every dependency, or the error that made it stop serving
+func (c *Checker) Errors() map[string]string {
+	statuses := map[string]string{}
+	if c == nil {
+		return statuses
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name := range c.checks {
+		err, checked := c.errs[name]
+		switch {
+		case !checked:
+			statuses[name] = grpc_health_v1.HealthCheckResponse_NOT_SERVING.String()
+		case err != nil:
+			statuses[name] = err.Error()
+		default:
+			statuses[name] = grpc_health_v1.HealthCheckResponse_SERVING.String()
+		}
+	}
+	return statuses
+}
+
+// Watch returns a channel receiving a value whenever a status may have changed. stop releases it
+func (c *Checker) Watch() (changes <-chan struct{}, stop func()) {
+	w := make(chan struct{}, 1)
+	if c == nil {
+		return w, func() {}
+	}
+
+	c.mu.Lock()
+	c.watchers[w] = true
+	c.mu.Unlock()
+	return w, func() {
+		c.mu.Lock()
+		delete(c.watchers, w)
+		c.mu.Unlock()
+	}
+}
+
+// ServeReady answers 200 while every dependency is serving and 503 otherwise, with the status of each dependency
+func (c *Checker) ServeReady(rw http.ResponseWriter, r *http.Request) {
+	status, _ := c.Status("")
+	rw.Header().Set("Content-Type", "application/json")
+	if status != grpc_health_v1.HealthCheckResponse_SERVING {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(rw).Encode(map[string]interface{}{
+		"status":       status.String(),
+		"dependencies": c.Errors(),
+	})
+}
+
+// AMQPCheck keeps one connection to the broker at uri and checks it by opening a channel on it.
+// The connection is only dialled again once it is closed or fails
+func AMQPCheck(uri string) Check {
+	var mu sync.Mutex
+	var conn *amqp.Connection
+
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if conn == nil || conn.IsClosed() {
+			timeout := 30 * time.Second
+			if deadline, ok := ctx.Deadline(); ok {
+				timeout = time.Until(deadline)
+			}
+			c, err := amqp.DialConfig(uri, amqp.Config{Dial: amqp.DefaultDial(timeout), Locale: "en_US"})
+			if err != nil {
+				return err
+			}
+			conn = c
+		}
+
+		done := make(chan error, 1)
+		go func(conn *amqp.Connection) {
+			ch, err := conn.Channel()
+			if err == nil {
+				err = ch.Close()
+			}
+			done <- err
+		}(conn)
+
+		select {
+		case err := <-done:
+			if err != nil {
+				_ = conn.Close()
+				conn = nil
+			}
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// RedisCheck pings the redis client
+func RedisCheck(client redis.UniversalClient) Check {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
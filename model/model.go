@@ -3,6 +3,8 @@ package model
 import (
 	"time"
 
+	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/filter"
 	"gorm.io/datatypes"
 	_ "gorm.io/gorm"
 )
@@ -16,6 +18,11 @@ type WorkerMeta struct {
 	ExchangeType     string
 	BindingKey       string // Routing key
 	NotificationType string
+
+	Concurrency            int
+	PrefetchCount          int
+	ProviderTimeoutSeconds int
+	RateLimit              int // Provider calls per second of each replica. 0 is unlimited
 }
 
 type EmailNotificationMeta struct {
@@ -44,6 +51,8 @@ type UserConfig struct {
 	NotificationConfigs []NotificationConfig `gorm:"foreignKey:UserConfig;references:ID;constraint:OnDelete:CASCADE"`
 	BotConfigs          []BotConfigs         `gorm:"foreignKey:UserConfig;references:ID;constraint:OnDelete:CASCADE"`
 	ChannelConfig       []ChannelConfig      `gorm:"foreignKey:UserConfig;references:ID;constraint:OnDelete:CASCADE"`
+	FallbackRules       []FallbackRules      `gorm:"foreignKey:UserConfig;references:ID;constraint:OnDelete:CASCADE"`
+	FlapSettings        []FlapSettings       `gorm:"foreignKey:UserConfig;references:ID;constraint:OnDelete:CASCADE"`
 }
 
 type AccountConfig struct {
@@ -54,6 +63,7 @@ type AccountConfig struct {
 	AccountId                string  `gorm:"type:varchar(100);uniqueIndex:idx_account_id"`
 	Email                    *string `gorm:"null"`
 	AccountName              string
+	Platform                 string                     `gorm:"type:varchar(20);index:idx_account_platform"`
 	AccountNotificationRules []AccountNotificationRules `gorm:"foreignKey:AccountConfigId;references:ID;constraint:OnDelete:CASCADE"`
 }
 
@@ -68,23 +78,33 @@ type NotificationConfig struct {
 	Subject                  string
 	UserConfig               uint64 `gorm:"type:bigint(20)"`
 	UuId                     string
+	FilterExpression         string                     `gorm:"type:text"`
 	AccountNotificationRules []AccountNotificationRules `gorm:"foreignKey:NotificationConfigId;references:ID;constraint:OnDelete:CASCADE"`
 	BotEventsRules           []BotEventsRules           `gorm:"foreignKey:NotificationConfigId;references:ID;constraint:OnDelete:CASCADE"`
+	EscalationPolicies       []EscalationPolicies       `gorm:"foreignKey:NotificationConfigId;references:ID;constraint:OnDelete:CASCADE"`
 }
 
 type Logs struct {
 	Id               uint64 `gorm:"primaryKey;autoIncrement;type:bigint(20)"`
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
-	UserConfig       string
+	UserConfig       string `gorm:"type:varchar(64);index:idx_log_user_config"`
 	AccountId        string
 	EventType        string
 	NotificationType string
 	ReqMeta          datatypes.JSON `gorm:"type:json"`
 	Status           string
+	ChainId          string `gorm:"type:varchar(64);index:idx_chain_id"`
+	FallbackFrom     string
+	BroadcastId      string         `gorm:"type:varchar(64);index:idx_broadcast_id"`
+	TraceId          string         `gorm:"type:varchar(32);index:idx_trace_id"`
+	NotificationId   string         `gorm:"type:varchar(64);index:idx_log_notification_id"`
+	Payload          datatypes.JSON `gorm:"type:json"` // Event data the delivery was rendered from
+	ResendOf         uint64         `gorm:"index:idx_log_resend_of"`
 }
 
 type BotNotificationMeta struct {
+	BotConfigId      uint64 `gorm:"column:bot_config_id"`
 	FirstName        string `gorm:"column:first_name"`
 	BotToken         string `gorm:"column:bot_token"`
 	ChannelId        string `gorm:"column:channel_id"`
@@ -93,6 +113,30 @@ type BotNotificationMeta struct {
 	Subject          string `gorm:"column:subject"`
 	NotificationType string `gorm:"column:notification_type"`
 }
+
+// BotRouteCandidate is a bot chat a notification config routes to. Channel fields are empty when the bot has no channel
+type BotRouteCandidate struct {
+	BotConfigId      uint64 `gorm:"column:bot_config_id"`
+	BotName          string `gorm:"column:bot_name"`
+	BotEnabled       bool   `gorm:"column:bot_enabled"`
+	FilterExpression string `gorm:"column:filter_expression"`
+	ChannelName      string `gorm:"column:channel_name"`
+	ChannelId        string `gorm:"column:channel_id"`
+	ChannelEnabled   bool   `gorm:"column:channel_enabled"`
+}
+
+// RoutingPlan is everything the master reads to route an event of an account.
+// The master caches it per account and event type
+type RoutingPlan struct {
+	UserId      string
+	Configs     []NotificationConfig // Enabled notification configs of the event
+	Blocked     map[uint64]bool      // Notification configs the account disabled
+	Integration *nm.IntegrationStatusReply
+	Chain       []FallbackRules
+	BotFilters  map[uint64][]BotEventsRules // Bot event rules of the bot notification configs
+	Filters     map[string]filter.Node      // Parsed filter expressions of the plan. An invalid one is nil and doesn't filter
+}
+
 type DefaultConfigs struct {
 	Id               uint64 `gorm:"primaryKey"`
 	EventType        string
@@ -130,6 +174,7 @@ type BotEventsRules struct {
 	UpdatedAt            time.Time
 	BotConfigId          uint64         `gorm:"type:bigint(20)"`
 	NotificationConfigId uint64         `gorm:"type:bigint(20)"`
+	FilterExpression     string         `gorm:"type:text"`
 	ChannelRules         []ChannelRules `gorm:"foreignKey:BotEventRulesId;references:ID;constraint:OnDelete:CASCADE"`
 }
 
@@ -152,3 +197,170 @@ type ChannelConfig struct {
 	UserConfig         uint64         `gorm:"type:bigint(20)"`
 	ChannelRules       []ChannelRules `gorm:"foreignKey:ChannelConfigId;references:ID;constraint:OnDelete:CASCADE"`
 }
+
+// FallbackRules orders the notification types an event falls through when delivery fails.
+// The rule with the lowest priority is the primary channel
+type FallbackRules struct {
+	ID               uint64 `gorm:"primaryKey;autoIncrement;type:bigint(20)"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	UserConfig       uint64 `gorm:"type:bigint(20);index:idx_fallback_user_config_event_type"`
+	EventType        string `gorm:"type:varchar(100);index:idx_fallback_user_config_event_type"`
+	NotificationType string
+	Priority         int
+	Enabled          bool
+}
+
+// EscalationPolicies are the steps an unacknowledged notification escalates through.
+// Each step is sent DelayMinutes after the previous one
+type EscalationPolicies struct {
+	ID                   uint64 `gorm:"primaryKey;autoIncrement;type:bigint(20)"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	NotificationConfigId uint64 `gorm:"type:bigint(20);index:idx_escalation_notification_config"`
+	Step                 int
+	NotificationType     string
+	DelayMinutes         int
+	Enabled              bool
+}
+
+// Escalations tracks a running escalation until it is acknowledged, resolved or exhausted
+type Escalations struct {
+	ID                   uint64 `gorm:"primaryKey;autoIncrement;type:bigint(20)"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	UuId                 string `gorm:"type:varchar(64);uniqueIndex:idx_escalation_uuid"`
+	NotificationConfigId uint64 `gorm:"type:bigint(20)"`
+	UserConfig           uint64 `gorm:"type:bigint(20);index:idx_escalation_user_config_account"`
+	AccountId            string `gorm:"type:varchar(100);index:idx_escalation_user_config_account"`
+	EventType            string
+	Step                 int
+	Status               string
+	AcknowledgedAt       *time.Time
+	AcknowledgedBy       string
+}
+
+// FlapSettings are the connection flap suppression thresholds of a user
+type FlapSettings struct {
+	ID                 uint64 `gorm:"primaryKey;autoIncrement;type:bigint(20)"`
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	UserConfig         uint64 `gorm:"type:bigint(20);uniqueIndex:idx_flap_settings_user_config"`
+	Enabled            bool
+	GracePeriodSeconds int
+	FlapThreshold      int
+	FlapWindowSeconds  int
+}
+
+// ConnectionStates is the last connection state of an account the master has seen
+type ConnectionStates struct {
+	ID                  uint64 `gorm:"primaryKey;autoIncrement;type:bigint(20)"`
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	AccountId           string `gorm:"type:varchar(100);uniqueIndex:idx_connection_state_account"`
+	State               string
+	PendingDisconnectId string
+	Transitions         int
+	WindowStart         time.Time
+	UnstableNotified    bool
+}
+
+// ScheduledNotifications are notifications producers asked to send later.
+// Notification types are resolved when the notification is sent so preference changes apply
+type ScheduledNotifications struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement;type:bigint(20)"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UuId      string `gorm:"type:varchar(64);uniqueIndex:idx_scheduled_uuid"`
+	UserId    string `gorm:"type:varchar(100);index:idx_scheduled_user_id"`
+	AccountId string `gorm:"type:varchar(100);index:idx_scheduled_account_id"`
+	EventType string
+	Data      datatypes.JSON `gorm:"type:json"`
+	SendAt    time.Time      `gorm:"index:idx_scheduled_send_at"`
+	Status    string
+}
+
+// Broadcasts are announcements sent to a segment of users in throttled batches.
+// LastUserConfig is the last user config sent and Generation invalidates batch tasks queued before a pause
+type Broadcasts struct {
+	ID                   uint64 `gorm:"primaryKey;autoIncrement;type:bigint(20)"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	UuId                 string `gorm:"type:varchar(64);uniqueIndex:idx_broadcast_uuid"`
+	Name                 string
+	Subject              string
+	MessageTemplate      string `gorm:"type:text"`
+	NotificationTypes    string
+	Segment              datatypes.JSON `gorm:"type:json"`
+	BatchSize            int
+	BatchIntervalSeconds int
+	Status               string
+	LastUserConfig       uint64
+	Generation           int
+	TotalUsers           int64
+	ProcessedUsers       int64
+}
+
+// NotificationTransitions records the lifecycle of a notification and of each of its destinations.
+// NotificationType is empty for the transitions of the notification itself
+type NotificationTransitions struct {
+	Id               uint64 `gorm:"primaryKey;autoIncrement;type:bigint(20)"`
+	CreatedAt        time.Time
+	NotificationId   string `gorm:"type:varchar(64);index:idx_transition_notification_id"`
+	NotificationType string `gorm:"type:varchar(32)"`
+	State            string `gorm:"type:varchar(16)"`
+	Reason           string
+	UserId           string `gorm:"type:varchar(64)"`
+	UserConfig       string `gorm:"type:varchar(64)"`
+	AccountId        string `gorm:"type:varchar(64)"`
+	EventType        string `gorm:"type:varchar(64)"`
+}
+
+// WorkerHeartbeats has a row per running worker process. LastSeenAt is refreshed every heartbeat interval
+type WorkerHeartbeats struct {
+	ID               uint64 `gorm:"primaryKey;autoIncrement;type:bigint(20)"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	InstanceId       string `gorm:"type:varchar(100);uniqueIndex:idx_heartbeat_instance_id"`
+	WorkerName       string `gorm:"type:varchar(100);index:idx_heartbeat_worker_name"`
+	WorkerType       string
+	NotificationType string
+	Version          string
+	Capacity         int
+	LastSeenAt       time.Time
+}
+
+// DeadLetters are the tasks that failed after their last retry. Signature is the machinery signature
+// the task is replayed with and WorkerName the worker it was meant for
+type DeadLetters struct {
+	Id               uint64    `gorm:"primaryKey;autoIncrement;type:bigint(20)"`
+	CreatedAt        time.Time `gorm:"index:idx_dead_letter_created_at"`
+	UpdatedAt        time.Time
+	UuId             string         `gorm:"type:varchar(64);uniqueIndex:idx_dead_letter_uuid"`
+	WorkerName       string         `gorm:"type:varchar(100);index:idx_dead_letter_worker_name"`
+	TaskName         string         `gorm:"type:varchar(100)"`
+	NotificationId   string         `gorm:"type:varchar(64);index:idx_dead_letter_notification_id"`
+	NotificationType string         `gorm:"type:varchar(32)"`
+	UserConfig       string         `gorm:"type:varchar(64)"`
+	AccountId        string         `gorm:"type:varchar(64)"`
+	EventType        string         `gorm:"type:varchar(64)"`
+	Signature        datatypes.JSON `gorm:"type:json"`
+	LastError        string         `gorm:"type:text"`
+	Status           string         `gorm:"type:varchar(16)"`
+	ReplayCount      int
+	ReplayedAt       *time.Time
+}
+
+// FanOuts count the destinations of a fanned out slave task that finished. The row is deleted
+// once every destination finished
+type FanOuts struct {
+	Id               uint64    `gorm:"primaryKey;autoIncrement;type:bigint(20)"`
+	CreatedAt        time.Time `gorm:"index:idx_fan_out_created_at"`
+	UpdatedAt        time.Time
+	FanOutId         string `gorm:"type:varchar(191);uniqueIndex:idx_fan_out_id"`
+	NotificationId   string `gorm:"type:varchar(64)"`
+	NotificationType string `gorm:"type:varchar(32)"`
+	Total            int
+	Done             int
+	Sent             int
+}
@@ -0,0 +1,1421 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// NotificationManagerExtClient is the client API for NotificationManagerExt service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type NotificationManagerExtClient interface {
+	// Fallback
+	SetFallbackChain(ctx context.Context, in *FallbackChain, opts ...grpc.CallOption) (*SetFallbackChainReply, error)
+	GetFallbackChains(ctx context.Context, in *GetFallbackChainsReq, opts ...grpc.CallOption) (*GetFallbackChainsReply, error)
+	// Escalation
+	SetEscalationPolicy(ctx context.Context, in *EscalationPolicy, opts ...grpc.CallOption) (*SetEscalationPolicyReply, error)
+	GetEscalationPolicy(ctx context.Context, in *GetEscalationPolicyReq, opts ...grpc.CallOption) (*EscalationPolicy, error)
+	AcknowledgeEscalation(ctx context.Context, in *AcknowledgeEscalationReq, opts ...grpc.CallOption) (*AcknowledgeEscalationReply, error)
+	// Filter expressions
+	SetFilterExpression(ctx context.Context, in *FilterExpression, opts ...grpc.CallOption) (*SetFilterExpressionReply, error)
+	GetFilterExpression(ctx context.Context, in *GetFilterExpressionReq, opts ...grpc.CallOption) (*FilterExpression, error)
+	// Flap suppression
+	SetFlapSettings(ctx context.Context, in *FlapSettings, opts ...grpc.CallOption) (*SetFlapSettingsReply, error)
+	GetFlapSettings(ctx context.Context, in *GetFlapSettingsReq, opts ...grpc.CallOption) (*FlapSettings, error)
+	// Delivery history of the user
+	ListNotificationLogs(ctx context.Context, in *ListNotificationLogsReq, opts ...grpc.CallOption) (*ListNotificationLogsReply, error)
+	ExportNotificationLogs(ctx context.Context, in *ListNotificationLogsReq, opts ...grpc.CallOption) (NotificationManagerExt_ExportNotificationLogsClient, error)
+}
+
+type notificationManagerExtClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNotificationManagerExtClient(cc grpc.ClientConnInterface) NotificationManagerExtClient {
+	return &notificationManagerExtClient{cc}
+}
+
+func (c *notificationManagerExtClient) SetFallbackChain(ctx context.Context, in *FallbackChain, opts ...grpc.CallOption) (*SetFallbackChainReply, error) {
+	out := new(SetFallbackChainReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerExt/SetFallbackChain", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerExtClient) GetFallbackChains(ctx context.Context, in *GetFallbackChainsReq, opts ...grpc.CallOption) (*GetFallbackChainsReply, error) {
+	out := new(GetFallbackChainsReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerExt/GetFallbackChains", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerExtClient) SetEscalationPolicy(ctx context.Context, in *EscalationPolicy, opts ...grpc.CallOption) (*SetEscalationPolicyReply, error) {
+	out := new(SetEscalationPolicyReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerExt/SetEscalationPolicy", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerExtClient) GetEscalationPolicy(ctx context.Context, in *GetEscalationPolicyReq, opts ...grpc.CallOption) (*EscalationPolicy, error) {
+	out := new(EscalationPolicy)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerExt/GetEscalationPolicy", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerExtClient) AcknowledgeEscalation(ctx context.Context, in *AcknowledgeEscalationReq, opts ...grpc.CallOption) (*AcknowledgeEscalationReply, error) {
+	out := new(AcknowledgeEscalationReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerExt/AcknowledgeEscalation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerExtClient) SetFilterExpression(ctx context.Context, in *FilterExpression, opts ...grpc.CallOption) (*SetFilterExpressionReply, error) {
+	out := new(SetFilterExpressionReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerExt/SetFilterExpression", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerExtClient) GetFilterExpression(ctx context.Context, in *GetFilterExpressionReq, opts ...grpc.CallOption) (*FilterExpression, error) {
+	out := new(FilterExpression)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerExt/GetFilterExpression", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerExtClient) SetFlapSettings(ctx context.Context, in *FlapSettings, opts ...grpc.CallOption) (*SetFlapSettingsReply, error) {
+	out := new(SetFlapSettingsReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerExt/SetFlapSettings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerExtClient) GetFlapSettings(ctx context.Context, in *GetFlapSettingsReq, opts ...grpc.CallOption) (*FlapSettings, error) {
+	out := new(FlapSettings)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerExt/GetFlapSettings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerExtClient) ListNotificationLogs(ctx context.Context, in *ListNotificationLogsReq, opts ...grpc.CallOption) (*ListNotificationLogsReply, error) {
+	out := new(ListNotificationLogsReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerExt/ListNotificationLogs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerExtClient) ExportNotificationLogs(ctx context.Context, in *ListNotificationLogsReq, opts ...grpc.CallOption) (NotificationManagerExt_ExportNotificationLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &NotificationManagerExt_ServiceDesc.Streams[0], "/notificationmanager.NotificationManagerExt/ExportNotificationLogs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &notificationManagerExtExportNotificationLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type NotificationManagerExt_ExportNotificationLogsClient interface {
+	Recv() (*NotificationLogChunk, error)
+	grpc.ClientStream
+}
+
+type notificationManagerExtExportNotificationLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *notificationManagerExtExportNotificationLogsClient) Recv() (*NotificationLogChunk, error) {
+	m := new(NotificationLogChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NotificationManagerExtServer is the server API for NotificationManagerExt service.
+// All implementations must embed UnimplementedNotificationManagerExtServer
+// for forward compatibility
+type NotificationManagerExtServer interface {
+	// Fallback
+	SetFallbackChain(context.Context, *FallbackChain) (*SetFallbackChainReply, error)
+	GetFallbackChains(context.Context, *GetFallbackChainsReq) (*GetFallbackChainsReply, error)
+	// Escalation
+	SetEscalationPolicy(context.Context, *EscalationPolicy) (*SetEscalationPolicyReply, error)
+	GetEscalationPolicy(context.Context, *GetEscalationPolicyReq) (*EscalationPolicy, error)
+	AcknowledgeEscalation(context.Context, *AcknowledgeEscalationReq) (*AcknowledgeEscalationReply, error)
+	// Filter expressions
+	SetFilterExpression(context.Context, *FilterExpression) (*SetFilterExpressionReply, error)
+	GetFilterExpression(context.Context, *GetFilterExpressionReq) (*FilterExpression, error)
+	// Flap suppression
+	SetFlapSettings(context.Context, *FlapSettings) (*SetFlapSettingsReply, error)
+	GetFlapSettings(context.Context, *GetFlapSettingsReq) (*FlapSettings, error)
+	// Delivery history of the user
+	ListNotificationLogs(context.Context, *ListNotificationLogsReq) (*ListNotificationLogsReply, error)
+	ExportNotificationLogs(*ListNotificationLogsReq, NotificationManagerExt_ExportNotificationLogsServer) error
+	mustEmbedUnimplementedNotificationManagerExtServer()
+}
+
+// UnimplementedNotificationManagerExtServer must be embedded to have forward compatible implementations.
+type UnimplementedNotificationManagerExtServer struct {
+}
+
+func (UnimplementedNotificationManagerExtServer) SetFallbackChain(context.Context, *FallbackChain) (*SetFallbackChainReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFallbackChain not implemented")
+}
+func (UnimplementedNotificationManagerExtServer) GetFallbackChains(context.Context, *GetFallbackChainsReq) (*GetFallbackChainsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFallbackChains not implemented")
+}
+func (UnimplementedNotificationManagerExtServer) SetEscalationPolicy(context.Context, *EscalationPolicy) (*SetEscalationPolicyReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetEscalationPolicy not implemented")
+}
+func (UnimplementedNotificationManagerExtServer) GetEscalationPolicy(context.Context, *GetEscalationPolicyReq) (*EscalationPolicy, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEscalationPolicy not implemented")
+}
+func (UnimplementedNotificationManagerExtServer) AcknowledgeEscalation(context.Context, *AcknowledgeEscalationReq) (*AcknowledgeEscalationReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcknowledgeEscalation not implemented")
+}
+func (UnimplementedNotificationManagerExtServer) SetFilterExpression(context.Context, *FilterExpression) (*SetFilterExpressionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFilterExpression not implemented")
+}
+func (UnimplementedNotificationManagerExtServer) GetFilterExpression(context.Context, *GetFilterExpressionReq) (*FilterExpression, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFilterExpression not implemented")
+}
+func (UnimplementedNotificationManagerExtServer) SetFlapSettings(context.Context, *FlapSettings) (*SetFlapSettingsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFlapSettings not implemented")
+}
+func (UnimplementedNotificationManagerExtServer) GetFlapSettings(context.Context, *GetFlapSettingsReq) (*FlapSettings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFlapSettings not implemented")
+}
+func (UnimplementedNotificationManagerExtServer) ListNotificationLogs(context.Context, *ListNotificationLogsReq) (*ListNotificationLogsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNotificationLogs not implemented")
+}
+func (UnimplementedNotificationManagerExtServer) ExportNotificationLogs(*ListNotificationLogsReq, NotificationManagerExt_ExportNotificationLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportNotificationLogs not implemented")
+}
+func (UnimplementedNotificationManagerExtServer) mustEmbedUnimplementedNotificationManagerExtServer() {
+}
+
+// UnsafeNotificationManagerExtServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NotificationManagerExtServer will
+// result in compilation errors.
+type UnsafeNotificationManagerExtServer interface {
+	mustEmbedUnimplementedNotificationManagerExtServer()
+}
+
+func RegisterNotificationManagerExtServer(s grpc.ServiceRegistrar, srv NotificationManagerExtServer) {
+	s.RegisterService(&NotificationManagerExt_ServiceDesc, srv)
+}
+
+func _NotificationManagerExt_SetFallbackChain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FallbackChain)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerExtServer).SetFallbackChain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerExt/SetFallbackChain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerExtServer).SetFallbackChain(ctx, req.(*FallbackChain))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerExt_GetFallbackChains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFallbackChainsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerExtServer).GetFallbackChains(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerExt/GetFallbackChains",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerExtServer).GetFallbackChains(ctx, req.(*GetFallbackChainsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerExt_SetEscalationPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EscalationPolicy)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerExtServer).SetEscalationPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerExt/SetEscalationPolicy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerExtServer).SetEscalationPolicy(ctx, req.(*EscalationPolicy))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerExt_GetEscalationPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEscalationPolicyReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerExtServer).GetEscalationPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerExt/GetEscalationPolicy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerExtServer).GetEscalationPolicy(ctx, req.(*GetEscalationPolicyReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerExt_AcknowledgeEscalation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcknowledgeEscalationReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerExtServer).AcknowledgeEscalation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerExt/AcknowledgeEscalation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerExtServer).AcknowledgeEscalation(ctx, req.(*AcknowledgeEscalationReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerExt_SetFilterExpression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FilterExpression)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerExtServer).SetFilterExpression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerExt/SetFilterExpression",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerExtServer).SetFilterExpression(ctx, req.(*FilterExpression))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerExt_GetFilterExpression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFilterExpressionReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerExtServer).GetFilterExpression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerExt/GetFilterExpression",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerExtServer).GetFilterExpression(ctx, req.(*GetFilterExpressionReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerExt_SetFlapSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FlapSettings)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerExtServer).SetFlapSettings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerExt/SetFlapSettings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerExtServer).SetFlapSettings(ctx, req.(*FlapSettings))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerExt_GetFlapSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFlapSettingsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerExtServer).GetFlapSettings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerExt/GetFlapSettings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerExtServer).GetFlapSettings(ctx, req.(*GetFlapSettingsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerExt_ListNotificationLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNotificationLogsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerExtServer).ListNotificationLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerExt/ListNotificationLogs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerExtServer).ListNotificationLogs(ctx, req.(*ListNotificationLogsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerExt_ExportNotificationLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListNotificationLogsReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NotificationManagerExtServer).ExportNotificationLogs(m, &notificationManagerExtExportNotificationLogsServer{stream})
+}
+
+type NotificationManagerExt_ExportNotificationLogsServer interface {
+	Send(*NotificationLogChunk) error
+	grpc.ServerStream
+}
+
+type notificationManagerExtExportNotificationLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *notificationManagerExtExportNotificationLogsServer) Send(m *NotificationLogChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// NotificationManagerExt_ServiceDesc is the grpc.ServiceDesc for NotificationManagerExt service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NotificationManagerExt_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "notificationmanager.NotificationManagerExt",
+	HandlerType: (*NotificationManagerExtServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SetFallbackChain",
+			Handler:    _NotificationManagerExt_SetFallbackChain_Handler,
+		},
+		{
+			MethodName: "GetFallbackChains",
+			Handler:    _NotificationManagerExt_GetFallbackChains_Handler,
+		},
+		{
+			MethodName: "SetEscalationPolicy",
+			Handler:    _NotificationManagerExt_SetEscalationPolicy_Handler,
+		},
+		{
+			MethodName: "GetEscalationPolicy",
+			Handler:    _NotificationManagerExt_GetEscalationPolicy_Handler,
+		},
+		{
+			MethodName: "AcknowledgeEscalation",
+			Handler:    _NotificationManagerExt_AcknowledgeEscalation_Handler,
+		},
+		{
+			MethodName: "SetFilterExpression",
+			Handler:    _NotificationManagerExt_SetFilterExpression_Handler,
+		},
+		{
+			MethodName: "GetFilterExpression",
+			Handler:    _NotificationManagerExt_GetFilterExpression_Handler,
+		},
+		{
+			MethodName: "SetFlapSettings",
+			Handler:    _NotificationManagerExt_SetFlapSettings_Handler,
+		},
+		{
+			MethodName: "GetFlapSettings",
+			Handler:    _NotificationManagerExt_GetFlapSettings_Handler,
+		},
+		{
+			MethodName: "ListNotificationLogs",
+			Handler:    _NotificationManagerExt_ListNotificationLogs_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportNotificationLogs",
+			Handler:       _NotificationManagerExt_ExportNotificationLogs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pb/notification_ext.proto",
+}
+
+// NotificationManagerInternalExtClient is the client API for NotificationManagerInternalExt service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type NotificationManagerInternalExtClient interface {
+	// Scheduled notifications
+	ListScheduledNotifications(ctx context.Context, in *ListScheduledNotificationsReq, opts ...grpc.CallOption) (*ListScheduledNotificationsReply, error)
+	CancelScheduledNotification(ctx context.Context, in *CancelScheduledNotificationReq, opts ...grpc.CallOption) (*CancelScheduledNotificationReply, error)
+	// Sends a single notification and returns its id
+	SendNotification(ctx context.Context, in *SendNotificationReq, opts ...grpc.CallOption) (*SendNotificationReply, error)
+	// Batch ingestion
+	IntSendNotifications(ctx context.Context, in *IntSendNotificationsReq, opts ...grpc.CallOption) (*IntSendNotificationsReply, error)
+	StreamNotifications(ctx context.Context, opts ...grpc.CallOption) (NotificationManagerInternalExt_StreamNotificationsClient, error)
+	// Support
+	ExplainRoute(ctx context.Context, in *ExplainRouteReq, opts ...grpc.CallOption) (*ExplainRouteReply, error)
+	ListWorkers(ctx context.Context, in *ListWorkersReq, opts ...grpc.CallOption) (*ListWorkersReply, error)
+	SetWorkerSettings(ctx context.Context, in *WorkerSettings, opts ...grpc.CallOption) (*WorkerSettings, error)
+	GetNotificationStatus(ctx context.Context, in *GetNotificationStatusReq, opts ...grpc.CallOption) (*NotificationStatus, error)
+	ResendNotification(ctx context.Context, in *ResendNotificationReq, opts ...grpc.CallOption) (*ResendNotificationReply, error)
+	// Delivery history of every user. user_id is optional
+	IntListNotificationLogs(ctx context.Context, in *ListNotificationLogsReq, opts ...grpc.CallOption) (*ListNotificationLogsReply, error)
+	IntExportNotificationLogs(ctx context.Context, in *ListNotificationLogsReq, opts ...grpc.CallOption) (NotificationManagerInternalExt_IntExportNotificationLogsClient, error)
+	// Broadcasts
+	Broadcast(ctx context.Context, in *BroadcastReq, opts ...grpc.CallOption) (*BroadcastStatus, error)
+	GetBroadcast(ctx context.Context, in *BroadcastIdReq, opts ...grpc.CallOption) (*BroadcastStatus, error)
+	PauseBroadcast(ctx context.Context, in *BroadcastIdReq, opts ...grpc.CallOption) (*BroadcastStatus, error)
+	ResumeBroadcast(ctx context.Context, in *BroadcastIdReq, opts ...grpc.CallOption) (*BroadcastStatus, error)
+	CancelBroadcast(ctx context.Context, in *BroadcastIdReq, opts ...grpc.CallOption) (*BroadcastStatus, error)
+	// Sets the trading platform of existing accounts for the platforms of broadcast segments
+	SetAccountPlatforms(ctx context.Context, in *SetAccountPlatformsReq, opts ...grpc.CallOption) (*SetAccountPlatformsReply, error)
+	// Dead letters
+	ListDeadLetters(ctx context.Context, in *ListDeadLettersReq, opts ...grpc.CallOption) (*ListDeadLettersReply, error)
+	GetDeadLetter(ctx context.Context, in *DeadLetterIdReq, opts ...grpc.CallOption) (*DeadLetter, error)
+	ReplayDeadLetters(ctx context.Context, in *ReplayDeadLettersReq, opts ...grpc.CallOption) (*ReplayDeadLettersReply, error)
+	PurgeDeadLetters(ctx context.Context, in *PurgeDeadLettersReq, opts ...grpc.CallOption) (*PurgeDeadLettersReply, error)
+}
+
+type notificationManagerInternalExtClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNotificationManagerInternalExtClient(cc grpc.ClientConnInterface) NotificationManagerInternalExtClient {
+	return &notificationManagerInternalExtClient{cc}
+}
+
+func (c *notificationManagerInternalExtClient) ListScheduledNotifications(ctx context.Context, in *ListScheduledNotificationsReq, opts ...grpc.CallOption) (*ListScheduledNotificationsReply, error) {
+	out := new(ListScheduledNotificationsReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/ListScheduledNotifications", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) CancelScheduledNotification(ctx context.Context, in *CancelScheduledNotificationReq, opts ...grpc.CallOption) (*CancelScheduledNotificationReply, error) {
+	out := new(CancelScheduledNotificationReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/CancelScheduledNotification", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) SendNotification(ctx context.Context, in *SendNotificationReq, opts ...grpc.CallOption) (*SendNotificationReply, error) {
+	out := new(SendNotificationReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/SendNotification", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) IntSendNotifications(ctx context.Context, in *IntSendNotificationsReq, opts ...grpc.CallOption) (*IntSendNotificationsReply, error) {
+	out := new(IntSendNotificationsReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/IntSendNotifications", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) StreamNotifications(ctx context.Context, opts ...grpc.CallOption) (NotificationManagerInternalExt_StreamNotificationsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &NotificationManagerInternalExt_ServiceDesc.Streams[0], "/notificationmanager.NotificationManagerInternalExt/StreamNotifications", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &notificationManagerInternalExtStreamNotificationsClient{stream}
+	return x, nil
+}
+
+type NotificationManagerInternalExt_StreamNotificationsClient interface {
+	Send(*NotificationEvent) error
+	CloseAndRecv() (*IntSendNotificationsReply, error)
+	grpc.ClientStream
+}
+
+type notificationManagerInternalExtStreamNotificationsClient struct {
+	grpc.ClientStream
+}
+
+func (x *notificationManagerInternalExtStreamNotificationsClient) Send(m *NotificationEvent) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *notificationManagerInternalExtStreamNotificationsClient) CloseAndRecv() (*IntSendNotificationsReply, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(IntSendNotificationsReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *notificationManagerInternalExtClient) ExplainRoute(ctx context.Context, in *ExplainRouteReq, opts ...grpc.CallOption) (*ExplainRouteReply, error) {
+	out := new(ExplainRouteReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/ExplainRoute", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) ListWorkers(ctx context.Context, in *ListWorkersReq, opts ...grpc.CallOption) (*ListWorkersReply, error) {
+	out := new(ListWorkersReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/ListWorkers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) SetWorkerSettings(ctx context.Context, in *WorkerSettings, opts ...grpc.CallOption) (*WorkerSettings, error) {
+	out := new(WorkerSettings)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/SetWorkerSettings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) GetNotificationStatus(ctx context.Context, in *GetNotificationStatusReq, opts ...grpc.CallOption) (*NotificationStatus, error) {
+	out := new(NotificationStatus)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/GetNotificationStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) ResendNotification(ctx context.Context, in *ResendNotificationReq, opts ...grpc.CallOption) (*ResendNotificationReply, error) {
+	out := new(ResendNotificationReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/ResendNotification", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) IntListNotificationLogs(ctx context.Context, in *ListNotificationLogsReq, opts ...grpc.CallOption) (*ListNotificationLogsReply, error) {
+	out := new(ListNotificationLogsReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/IntListNotificationLogs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) IntExportNotificationLogs(ctx context.Context, in *ListNotificationLogsReq, opts ...grpc.CallOption) (NotificationManagerInternalExt_IntExportNotificationLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &NotificationManagerInternalExt_ServiceDesc.Streams[1], "/notificationmanager.NotificationManagerInternalExt/IntExportNotificationLogs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &notificationManagerInternalExtIntExportNotificationLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type NotificationManagerInternalExt_IntExportNotificationLogsClient interface {
+	Recv() (*NotificationLogChunk, error)
+	grpc.ClientStream
+}
+
+type notificationManagerInternalExtIntExportNotificationLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *notificationManagerInternalExtIntExportNotificationLogsClient) Recv() (*NotificationLogChunk, error) {
+	m := new(NotificationLogChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *notificationManagerInternalExtClient) Broadcast(ctx context.Context, in *BroadcastReq, opts ...grpc.CallOption) (*BroadcastStatus, error) {
+	out := new(BroadcastStatus)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/Broadcast", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) GetBroadcast(ctx context.Context, in *BroadcastIdReq, opts ...grpc.CallOption) (*BroadcastStatus, error) {
+	out := new(BroadcastStatus)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/GetBroadcast", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) PauseBroadcast(ctx context.Context, in *BroadcastIdReq, opts ...grpc.CallOption) (*BroadcastStatus, error) {
+	out := new(BroadcastStatus)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/PauseBroadcast", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) ResumeBroadcast(ctx context.Context, in *BroadcastIdReq, opts ...grpc.CallOption) (*BroadcastStatus, error) {
+	out := new(BroadcastStatus)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/ResumeBroadcast", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) CancelBroadcast(ctx context.Context, in *BroadcastIdReq, opts ...grpc.CallOption) (*BroadcastStatus, error) {
+	out := new(BroadcastStatus)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/CancelBroadcast", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) SetAccountPlatforms(ctx context.Context, in *SetAccountPlatformsReq, opts ...grpc.CallOption) (*SetAccountPlatformsReply, error) {
+	out := new(SetAccountPlatformsReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/SetAccountPlatforms", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) ListDeadLetters(ctx context.Context, in *ListDeadLettersReq, opts ...grpc.CallOption) (*ListDeadLettersReply, error) {
+	out := new(ListDeadLettersReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/ListDeadLetters", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) GetDeadLetter(ctx context.Context, in *DeadLetterIdReq, opts ...grpc.CallOption) (*DeadLetter, error) {
+	out := new(DeadLetter)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/GetDeadLetter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) ReplayDeadLetters(ctx context.Context, in *ReplayDeadLettersReq, opts ...grpc.CallOption) (*ReplayDeadLettersReply, error) {
+	out := new(ReplayDeadLettersReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/ReplayDeadLetters", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationManagerInternalExtClient) PurgeDeadLetters(ctx context.Context, in *PurgeDeadLettersReq, opts ...grpc.CallOption) (*PurgeDeadLettersReply, error) {
+	out := new(PurgeDeadLettersReply)
+	err := c.cc.Invoke(ctx, "/notificationmanager.NotificationManagerInternalExt/PurgeDeadLetters", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NotificationManagerInternalExtServer is the server API for NotificationManagerInternalExt service.
+// All implementations must embed UnimplementedNotificationManagerInternalExtServer
+// for forward compatibility
+type NotificationManagerInternalExtServer interface {
+	// Scheduled notifications
+	ListScheduledNotifications(context.Context, *ListScheduledNotificationsReq) (*ListScheduledNotificationsReply, error)
+	CancelScheduledNotification(context.Context, *CancelScheduledNotificationReq) (*CancelScheduledNotificationReply, error)
+	// Sends a single notification and returns its id
+	SendNotification(context.Context, *SendNotificationReq) (*SendNotificationReply, error)
+	// Batch ingestion
+	IntSendNotifications(context.Context, *IntSendNotificationsReq) (*IntSendNotificationsReply, error)
+	StreamNotifications(NotificationManagerInternalExt_StreamNotificationsServer) error
+	// Support
+	ExplainRoute(context.Context, *ExplainRouteReq) (*ExplainRouteReply, error)
+	ListWorkers(context.Context, *ListWorkersReq) (*ListWorkersReply, error)
+	SetWorkerSettings(context.Context, *WorkerSettings) (*WorkerSettings, error)
+	GetNotificationStatus(context.Context, *GetNotificationStatusReq) (*NotificationStatus, error)
+	ResendNotification(context.Context, *ResendNotificationReq) (*ResendNotificationReply, error)
+	// Delivery history of every user. user_id is optional
+	IntListNotificationLogs(context.Context, *ListNotificationLogsReq) (*ListNotificationLogsReply, error)
+	IntExportNotificationLogs(*ListNotificationLogsReq, NotificationManagerInternalExt_IntExportNotificationLogsServer) error
+	// Broadcasts
+	Broadcast(context.Context, *BroadcastReq) (*BroadcastStatus, error)
+	GetBroadcast(context.Context, *BroadcastIdReq) (*BroadcastStatus, error)
+	PauseBroadcast(context.Context, *BroadcastIdReq) (*BroadcastStatus, error)
+	ResumeBroadcast(context.Context, *BroadcastIdReq) (*BroadcastStatus, error)
+	CancelBroadcast(context.Context, *BroadcastIdReq) (*BroadcastStatus, error)
+	// Sets the trading platform of existing accounts for the platforms of broadcast segments
+	SetAccountPlatforms(context.Context, *SetAccountPlatformsReq) (*SetAccountPlatformsReply, error)
+	// Dead letters
+	ListDeadLetters(context.Context, *ListDeadLettersReq) (*ListDeadLettersReply, error)
+	GetDeadLetter(context.Context, *DeadLetterIdReq) (*DeadLetter, error)
+	ReplayDeadLetters(context.Context, *ReplayDeadLettersReq) (*ReplayDeadLettersReply, error)
+	PurgeDeadLetters(context.Context, *PurgeDeadLettersReq) (*PurgeDeadLettersReply, error)
+	mustEmbedUnimplementedNotificationManagerInternalExtServer()
+}
+
+// UnimplementedNotificationManagerInternalExtServer must be embedded to have forward compatible implementations.
+type UnimplementedNotificationManagerInternalExtServer struct {
+}
+
+func (UnimplementedNotificationManagerInternalExtServer) ListScheduledNotifications(context.Context, *ListScheduledNotificationsReq) (*ListScheduledNotificationsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListScheduledNotifications not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) CancelScheduledNotification(context.Context, *CancelScheduledNotificationReq) (*CancelScheduledNotificationReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelScheduledNotification not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) SendNotification(context.Context, *SendNotificationReq) (*SendNotificationReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendNotification not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) IntSendNotifications(context.Context, *IntSendNotificationsReq) (*IntSendNotificationsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IntSendNotifications not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) StreamNotifications(NotificationManagerInternalExt_StreamNotificationsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamNotifications not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) ExplainRoute(context.Context, *ExplainRouteReq) (*ExplainRouteReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExplainRoute not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) ListWorkers(context.Context, *ListWorkersReq) (*ListWorkersReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWorkers not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) SetWorkerSettings(context.Context, *WorkerSettings) (*WorkerSettings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetWorkerSettings not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) GetNotificationStatus(context.Context, *GetNotificationStatusReq) (*NotificationStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNotificationStatus not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) ResendNotification(context.Context, *ResendNotificationReq) (*ResendNotificationReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResendNotification not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) IntListNotificationLogs(context.Context, *ListNotificationLogsReq) (*ListNotificationLogsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IntListNotificationLogs not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) IntExportNotificationLogs(*ListNotificationLogsReq, NotificationManagerInternalExt_IntExportNotificationLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method IntExportNotificationLogs not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) Broadcast(context.Context, *BroadcastReq) (*BroadcastStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Broadcast not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) GetBroadcast(context.Context, *BroadcastIdReq) (*BroadcastStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBroadcast not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) PauseBroadcast(context.Context, *BroadcastIdReq) (*BroadcastStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseBroadcast not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) ResumeBroadcast(context.Context, *BroadcastIdReq) (*BroadcastStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeBroadcast not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) CancelBroadcast(context.Context, *BroadcastIdReq) (*BroadcastStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelBroadcast not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) SetAccountPlatforms(context.Context, *SetAccountPlatformsReq) (*SetAccountPlatformsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetAccountPlatforms not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) ListDeadLetters(context.Context, *ListDeadLettersReq) (*ListDeadLettersReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeadLetters not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) GetDeadLetter(context.Context, *DeadLetterIdReq) (*DeadLetter, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeadLetter not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) ReplayDeadLetters(context.Context, *ReplayDeadLettersReq) (*ReplayDeadLettersReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplayDeadLetters not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) PurgeDeadLetters(context.Context, *PurgeDeadLettersReq) (*PurgeDeadLettersReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeDeadLetters not implemented")
+}
+func (UnimplementedNotificationManagerInternalExtServer) mustEmbedUnimplementedNotificationManagerInternalExtServer() {
+}
+
+// UnsafeNotificationManagerInternalExtServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NotificationManagerInternalExtServer will
+// result in compilation errors.
+type UnsafeNotificationManagerInternalExtServer interface {
+	mustEmbedUnimplementedNotificationManagerInternalExtServer()
+}
+
+func RegisterNotificationManagerInternalExtServer(s grpc.ServiceRegistrar, srv NotificationManagerInternalExtServer) {
+	s.RegisterService(&NotificationManagerInternalExt_ServiceDesc, srv)
+}
+
+func _NotificationManagerInternalExt_ListScheduledNotifications_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListScheduledNotificationsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).ListScheduledNotifications(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/ListScheduledNotifications",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).ListScheduledNotifications(ctx, req.(*ListScheduledNotificationsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_CancelScheduledNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelScheduledNotificationReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).CancelScheduledNotification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/CancelScheduledNotification",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).CancelScheduledNotification(ctx, req.(*CancelScheduledNotificationReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_SendNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendNotificationReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).SendNotification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/SendNotification",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).SendNotification(ctx, req.(*SendNotificationReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_IntSendNotifications_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IntSendNotificationsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).IntSendNotifications(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/IntSendNotifications",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).IntSendNotifications(ctx, req.(*IntSendNotificationsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_StreamNotifications_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(NotificationManagerInternalExtServer).StreamNotifications(&notificationManagerInternalExtStreamNotificationsServer{stream})
+}
+
+type NotificationManagerInternalExt_StreamNotificationsServer interface {
+	SendAndClose(*IntSendNotificationsReply) error
+	Recv() (*NotificationEvent, error)
+	grpc.ServerStream
+}
+
+type notificationManagerInternalExtStreamNotificationsServer struct {
+	grpc.ServerStream
+}
+
+func (x *notificationManagerInternalExtStreamNotificationsServer) SendAndClose(m *IntSendNotificationsReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *notificationManagerInternalExtStreamNotificationsServer) Recv() (*NotificationEvent, error) {
+	m := new(NotificationEvent)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _NotificationManagerInternalExt_ExplainRoute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExplainRouteReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).ExplainRoute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/ExplainRoute",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).ExplainRoute(ctx, req.(*ExplainRouteReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_ListWorkers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWorkersReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).ListWorkers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/ListWorkers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).ListWorkers(ctx, req.(*ListWorkersReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_SetWorkerSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkerSettings)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).SetWorkerSettings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/SetWorkerSettings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).SetWorkerSettings(ctx, req.(*WorkerSettings))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_GetNotificationStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNotificationStatusReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).GetNotificationStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/GetNotificationStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).GetNotificationStatus(ctx, req.(*GetNotificationStatusReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_ResendNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResendNotificationReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).ResendNotification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/ResendNotification",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).ResendNotification(ctx, req.(*ResendNotificationReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_IntListNotificationLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNotificationLogsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).IntListNotificationLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/IntListNotificationLogs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).IntListNotificationLogs(ctx, req.(*ListNotificationLogsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_IntExportNotificationLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListNotificationLogsReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NotificationManagerInternalExtServer).IntExportNotificationLogs(m, &notificationManagerInternalExtIntExportNotificationLogsServer{stream})
+}
+
+type NotificationManagerInternalExt_IntExportNotificationLogsServer interface {
+	Send(*NotificationLogChunk) error
+	grpc.ServerStream
+}
+
+type notificationManagerInternalExtIntExportNotificationLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *notificationManagerInternalExtIntExportNotificationLogsServer) Send(m *NotificationLogChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _NotificationManagerInternalExt_Broadcast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BroadcastReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).Broadcast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/Broadcast",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).Broadcast(ctx, req.(*BroadcastReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_GetBroadcast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BroadcastIdReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).GetBroadcast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/GetBroadcast",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).GetBroadcast(ctx, req.(*BroadcastIdReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_PauseBroadcast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BroadcastIdReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).PauseBroadcast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/PauseBroadcast",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).PauseBroadcast(ctx, req.(*BroadcastIdReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_ResumeBroadcast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BroadcastIdReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).ResumeBroadcast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/ResumeBroadcast",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).ResumeBroadcast(ctx, req.(*BroadcastIdReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_CancelBroadcast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BroadcastIdReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).CancelBroadcast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/CancelBroadcast",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).CancelBroadcast(ctx, req.(*BroadcastIdReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_SetAccountPlatforms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAccountPlatformsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).SetAccountPlatforms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/SetAccountPlatforms",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).SetAccountPlatforms(ctx, req.(*SetAccountPlatformsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_ListDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDeadLettersReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).ListDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/ListDeadLetters",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).ListDeadLetters(ctx, req.(*ListDeadLettersReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_GetDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeadLetterIdReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).GetDeadLetter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/GetDeadLetter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).GetDeadLetter(ctx, req.(*DeadLetterIdReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_ReplayDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplayDeadLettersReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).ReplayDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/ReplayDeadLetters",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).ReplayDeadLetters(ctx, req.(*ReplayDeadLettersReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationManagerInternalExt_PurgeDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeDeadLettersReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationManagerInternalExtServer).PurgeDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notificationmanager.NotificationManagerInternalExt/PurgeDeadLetters",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationManagerInternalExtServer).PurgeDeadLetters(ctx, req.(*PurgeDeadLettersReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// NotificationManagerInternalExt_ServiceDesc is the grpc.ServiceDesc for NotificationManagerInternalExt service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NotificationManagerInternalExt_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "notificationmanager.NotificationManagerInternalExt",
+	HandlerType: (*NotificationManagerInternalExtServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListScheduledNotifications",
+			Handler:    _NotificationManagerInternalExt_ListScheduledNotifications_Handler,
+		},
+		{
+			MethodName: "CancelScheduledNotification",
+			Handler:    _NotificationManagerInternalExt_CancelScheduledNotification_Handler,
+		},
+		{
+			MethodName: "SendNotification",
+			Handler:    _NotificationManagerInternalExt_SendNotification_Handler,
+		},
+		{
+			MethodName: "IntSendNotifications",
+			Handler:    _NotificationManagerInternalExt_IntSendNotifications_Handler,
+		},
+		{
+			MethodName: "ExplainRoute",
+			Handler:    _NotificationManagerInternalExt_ExplainRoute_Handler,
+		},
+		{
+			MethodName: "ListWorkers",
+			Handler:    _NotificationManagerInternalExt_ListWorkers_Handler,
+		},
+		{
+			MethodName: "SetWorkerSettings",
+			Handler:    _NotificationManagerInternalExt_SetWorkerSettings_Handler,
+		},
+		{
+			MethodName: "GetNotificationStatus",
+			Handler:    _NotificationManagerInternalExt_GetNotificationStatus_Handler,
+		},
+		{
+			MethodName: "ResendNotification",
+			Handler:    _NotificationManagerInternalExt_ResendNotification_Handler,
+		},
+		{
+			MethodName: "IntListNotificationLogs",
+			Handler:    _NotificationManagerInternalExt_IntListNotificationLogs_Handler,
+		},
+		{
+			MethodName: "Broadcast",
+			Handler:    _NotificationManagerInternalExt_Broadcast_Handler,
+		},
+		{
+			MethodName: "GetBroadcast",
+			Handler:    _NotificationManagerInternalExt_GetBroadcast_Handler,
+		},
+		{
+			MethodName: "PauseBroadcast",
+			Handler:    _NotificationManagerInternalExt_PauseBroadcast_Handler,
+		},
+		{
+			MethodName: "ResumeBroadcast",
+			Handler:    _NotificationManagerInternalExt_ResumeBroadcast_Handler,
+		},
+		{
+			MethodName: "CancelBroadcast",
+			Handler:    _NotificationManagerInternalExt_CancelBroadcast_Handler,
+		},
+		{
+			MethodName: "SetAccountPlatforms",
+			Handler:    _NotificationManagerInternalExt_SetAccountPlatforms_Handler,
+		},
+		{
+			MethodName: "ListDeadLetters",
+			Handler:    _NotificationManagerInternalExt_ListDeadLetters_Handler,
+		},
+		{
+			MethodName: "GetDeadLetter",
+			Handler:    _NotificationManagerInternalExt_GetDeadLetter_Handler,
+		},
+		{
+			MethodName: "ReplayDeadLetters",
+			Handler:    _NotificationManagerInternalExt_ReplayDeadLetters_Handler,
+		},
+		{
+			MethodName: "PurgeDeadLetters",
+			Handler:    _NotificationManagerInternalExt_PurgeDeadLetters_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamNotifications",
+			Handler:       _NotificationManagerInternalExt_StreamNotifications_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "IntExportNotificationLogs",
+			Handler:       _NotificationManagerInternalExt_IntExportNotificationLogs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pb/notification_ext.proto",
+}
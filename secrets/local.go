@@ -0,0 +1,74 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// LocalKeyProvider wraps data keys with AES-256 keys from the config
+type LocalKeyProvider struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewLocalKeyProvider parses keys as id:base64 key pairs separated by commas. Every key is 32 bytes.
+// An empty primary uses the first key. Old keys stay in the list until their values are rotated
+func NewLocalKeyProvider(keys, primary string) (*LocalKeyProvider, error) {
+	p := &LocalKeyProvider{primary: primary, keys: map[string][]byte{}}
+	for _, v := range strings.Split(keys, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		parts := strings.SplitN(v, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("secret key %q is not id:base64 key", parts[0])
+		}
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("secret key %v must be 32 base64 encoded bytes", parts[0])
+		}
+		p.keys[parts[0]] = key
+		if p.primary == "" {
+			p.primary = parts[0]
+		}
+	}
+	if len(p.keys) == 0 {
+		return nil, fmt.Errorf("no secret keys")
+	}
+	if _, ok := p.keys[p.primary]; !ok {
+		return nil, fmt.Errorf("primary secret key %v is not configured", p.primary)
+	}
+	return p, nil
+}
+
+func (p *LocalKeyProvider) PrimaryKeyId() string {
+	return p.primary
+}
+
+func (p *LocalKeyProvider) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	wrapped, err := seal(p.keys[p.primary], dataKey)
+	return p.primary, wrapped, err
+}
+
+func (p *LocalKeyProvider) UnwrapKey(ctx context.Context, keyId string, wrapped []byte) ([]byte, error) {
+	key, ok := p.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("secret key %v is not configured", keyId)
+	}
+	return open(key, wrapped)
+}
+
+// NewCipherFromConfig returns a cipher with the local keys. No keys disable encryption
+func NewCipherFromConfig(keys, primary string) (*Cipher, error) {
+	if strings.TrimSpace(keys) == "" {
+		return NewCipher(nil), nil
+	}
+	provider, err := NewLocalKeyProvider(keys, primary)
+	if err != nil {
+		return nil, err
+	}
+	return NewCipher(provider), nil
+}
@@ -0,0 +1,263 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// PREFIX marks an encrypted value. The rest is key id:wrapped data key:nonce and ciphertext, base64 encoded
+const PREFIX = "enc:v1:"
+
+const MASK = "****"
+
+// KeyProvider wraps the data keys with a key encryption key. It is implemented by LocalKeyProvider
+// with keys from the config and can be backed by a KMS
+type KeyProvider interface {
+	// PrimaryKeyId is the id of the key new data keys are wrapped with
+	PrimaryKeyId() string
+	WrapKey(ctx context.Context, dataKey []byte) (keyId string, wrapped []byte, err error)
+	UnwrapKey(ctx context.Context, keyId string, wrapped []byte) ([]byte, error)
+}
+
+// Cipher envelope encrypts values with a new data key each. Unwrapped data keys are cached
+// so a KMS is only asked once per stored value. A cipher without provider stores values as they are
+type Cipher struct {
+	provider KeyProvider
+
+	mu       sync.Mutex
+	dataKeys map[string][]byte
+}
+
+func NewCipher(provider KeyProvider) *Cipher {
+	return &Cipher{provider: provider, dataKeys: map[string][]byte{}}
+}
+
+func (c *Cipher) Enabled() bool {
+	return c != nil && c.provider != nil
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, PREFIX)
+}
+
+func (c *Cipher) Encrypt(ctx context.Context, plaintext string) (string, error) {
+	if !c.Enabled() || plaintext == "" {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	keyId, wrapped, err := c.provider.WrapKey(ctx, dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return PREFIX + strings.Join([]string{
+		keyId,
+		base64.RawStdEncoding.EncodeToString(wrapped),
+		base64.RawStdEncoding.EncodeToString(sealed),
+	}, ":"), nil
+}
+
+// Decrypt returns plaintext values as they are so rows written before encryption was enabled keep working
+func (c *Cipher) Decrypt(ctx context.Context, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if !c.Enabled() {
+		return "", errors.New("encrypted value but no secret keys are configured")
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, PREFIX), ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed encrypted value")
+	}
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := c.unwrap(ctx, parts[0], parts[1], wrapped)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation tells if the value is plaintext or wrapped with a key other than the primary key
+func (c *Cipher) NeedsRotation(value string) bool {
+	if !c.Enabled() || value == "" {
+		return false
+	}
+	if !IsEncrypted(value) {
+		return true
+	}
+	keyId := strings.SplitN(strings.TrimPrefix(value, PREFIX), ":", 2)[0]
+	return keyId != c.provider.PrimaryKeyId()
+}
+
+func (c *Cipher) unwrap(ctx context.Context, keyId, encoded string, wrapped []byte) ([]byte, error) {
+	cacheKey := keyId + ":" + encoded
+
+	c.mu.Lock()
+	dataKey, ok := c.dataKeys[cacheKey]
+	c.mu.Unlock()
+	if ok {
+		return dataKey, nil
+	}
+
+	dataKey, err := c.provider.UnwrapKey(ctx, keyId, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.dataKeys[cacheKey] = dataKey
+	c.mu.Unlock()
+	return dataKey, nil
+}
+
+// seal encrypts with AES-256-GCM and prepends the nonce
+func seal(key, plaintext []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, sealed []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted value too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Mask keeps the last 4 characters of a secret so users can tell their credentials apart
+func Mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 8 {
+		return MASK
+	}
+	return MASK + secret[len(secret)-4:]
+}
+
+func IsMasked(value string) bool {
+	return strings.HasPrefix(value, MASK)
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// Redact masks every occurrence of the secrets in the message of err. Client errors can carry request urls with tokens.
+// The original error stays reachable with errors.As so it can still be classified
+func Redact(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	redacted := msg
+	for _, v := range secrets {
+		if v != "" {
+			redacted = strings.ReplaceAll(redacted, v, Mask(v))
+		}
+	}
+	if redacted == msg {
+		return err
+	}
+	return &redactedError{msg: redacted, err: err}
+}
+
+// secretFields are the lower case json keys whose values are masked by RedactJSON
+var secretFields = []string{"token", "apikey", "api_key", "secret", "password"}
+
+// RedactJSON masks the string values of the secret fields of a json object at any depth. Other json is returned as is
+func RedactJSON(data []byte) []byte {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return data
+	}
+	if !redactMap(obj) {
+		return data
+	}
+	redacted, err := json.Marshal(obj)
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+func redactMap(obj map[string]interface{}) bool {
+	changed := false
+	for k, v := range obj {
+		switch v := v.(type) {
+		case string:
+			if isSecretField(k) && v != "" && !IsMasked(v) {
+				obj[k] = Mask(v)
+				changed = true
+			}
+		case map[string]interface{}:
+			changed = redactMap(v) || changed
+		}
+	}
+	return changed
+}
+
+func isSecretField(key string) bool {
+	key = strings.ToLower(key)
+	for _, v := range secretFields {
+		if strings.Contains(key, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,11 +3,26 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/RichardKnop/machinery/v2/tasks"
 	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devShahriar/H"
 	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/tracing"
+	"github.com/devshahriar/notification-manager/worker"
+	"github.com/google/uuid"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 func (n *NotificationService) IntAddUserConfig(ctx context.Context, payload *nm.UserMetaReq) (*nm.IntAddUserConfigReply, error) {
@@ -22,6 +37,7 @@ func (n *NotificationService) IntAddUserConfig(ctx context.Context, payload *nm.
 	if err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.IntAddUserConfigReply{}, nil
 }
 
@@ -29,6 +45,12 @@ func (n *NotificationService) IntAddAccountConfig(ctx context.Context, payload *
 	if err := n.Db.SetAccountConfig(ctx, payload); err != nil {
 		return nil, err
 	}
+	if platform := GetPlatform(ctx); platform != "" && payload.AccountMeta != nil {
+		if err := n.Db.SetAccountPlatform(ctx, payload.AccountMeta.AccountId, platform); err != nil {
+			return nil, err
+		}
+	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.IntAddAccountConfigReply{}, nil
 }
 
@@ -45,6 +67,7 @@ func (n *NotificationService) IntDeleteAccountConfig(ctx context.Context, payloa
 		n.Log.Errorw("error:", err)
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 
 	_, ntErr := n.IntSendNotification(ctx, ntPayload)
 	n.Log.Errorw("Error", ntErr)
@@ -52,55 +75,423 @@ func (n *NotificationService) IntDeleteAccountConfig(ctx context.Context, payloa
 }
 
 func (n *NotificationService) IntSendNotification(ctx context.Context, payload *nm.NotificationReq) (*nm.IntSendNotificationReply, error) {
+	_, _, err := n.PublishNotification(ctx, payload.UserId, payload.AccountId, payload.EventType, payload.Data, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &nm.IntSendNotificationReply{}, nil
+}
 
-	if payload.AccountId == "" || payload.EventType == "" {
-		return nil, fmt.Errorf("AccountId or eventType is empty")
+// SendNotification sends a single notification like IntSendNotification, later when send_at or delay_seconds is set, and returns its id
+func (n *NotificationService) SendNotification(ctx context.Context, req *pb.SendNotificationReq) (*pb.SendNotificationReply, error) {
+	if req.Event == nil {
+		return nil, status.Error(codes.InvalidArgument, "event is empty")
+	}
+	sendAt, err := ParseSendAt(req.SendAt, req.DelaySeconds, time.Now())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	notificationId, scheduledId, err := n.PublishNotification(ctx, req.Event.UserId, req.Event.AccountId, req.Event.EventType, req.Event.Data, sendAt)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SendNotificationReply{NotificationId: notificationId, ScheduledNotificationId: scheduledId}, nil
+}
+
+// PublishNotification publishes the notification to the master, at sendAt when it's set, and returns its id and the id of the scheduled notification.
+// Fails with Unavailable when the broker doesn't take it
+func (n *NotificationService) PublishNotification(ctx context.Context, userId, accountId, eventType string, data map[string]string, sendAt *time.Time) (notificationId string, scheduledId string, err error) {
 
-	n.Logger.Infof("SendNotification req body %+v", payload)
+	if accountId == "" || eventType == "" {
+		return "", "", status.Error(codes.InvalidArgument, "AccountId or eventType is empty")
+	}
+
+	ctx, span := tracing.StartSpan(ctx, "IntSendNotification", GetTraceContext(ctx))
+	defer span.End()
+	logger := tracing.Logger(ctx, n.Logger)
 
-	dataBytes, err := json.Marshal(payload.Data)
+	logger.Infof("SendNotification req userId:%v accountId:%v eventType:%v data:%+v", userId, accountId, eventType, data)
+
+	dataBytes, err := json.Marshal(data)
 	if err != nil {
-		n.Logger.Errorw("Error: while converting payload.Data into bytes", err)
-		return nil, err
+		logger.Errorw("Error: while converting payload.Data into bytes", err)
+		return "", "", err
 	}
-	n.Logger.Info(contract.GetWorkerArgs().WorkerConfig.AMQP.BindingKey)
-	taskSignature := &tasks.Signature{
-		Name:       "task_route_notification",
-		RoutingKey: contract.GetWorkerArgs().WorkerConfig.AMQP.BindingKey,
-		Args: []tasks.Arg{
-			{
-				Name:  "userId",
-				Type:  "string",
-				Value: payload.UserId,
-			},
-			{
-				Name:  "accId",
-				Type:  "string",
-				Value: payload.AccountId,
-			},
-			{
-				Name:  "eventType",
-				Type:  "string",
-				Value: payload.EventType,
-			},
-			{
-				Name:  "dataBytes",
-				Type:  "[]byte",
-				Value: dataBytes,
-			},
-		},
-		RetryCount:   1,
-		RetryTimeout: 100,
+
+	logger.Info(contract.GetWorkerArgs().WorkerConfig.AMQP.BindingKey)
+	envelope := worker.NewEnvelope(userId, accountId, eventType, dataBytes)
+	envelope.TraceContext = tracing.Inject(ctx)
+	span.AddAttributes(trace.StringAttribute("notification.id", envelope.NotificationId))
+	taskSignature, err := worker.GetEnvelopeTask(contract.TASK_ROUTE_ENVELOPE, contract.GetWorkerArgs().WorkerConfig.AMQP.BindingKey, envelope)
+	if err != nil {
+		return "", "", err
 	}
 
 	if n.MachinaryServer == nil {
-		return nil, fmt.Errorf("MachinaryServer is null")
+		return "", "", status.Error(codes.Unavailable, "MachinaryServer is null")
+	}
+
+	if sendAt != nil {
+		scheduledId = uuid.New().String()
+		scheduled := &model.ScheduledNotifications{
+			UuId:      scheduledId,
+			UserId:    userId,
+			AccountId: accountId,
+			EventType: eventType,
+			Data:      dataBytes,
+			SendAt:    *sendAt,
+			Status:    contract.SCHEDULED_PENDING,
+		}
+		err = n.Db.CreateScheduledNotification(ctx, scheduled)
+		if err != nil {
+			return "", "", err
+		}
+		//The master resolves the channels when the task runs so preference changes until then are applied
+		taskSignature.ETA = sendAt
+		taskSignature.Headers = tasks.Headers{contract.HEADER_SCHEDULED_ID: scheduledId}
 	}
 
 	_, err = n.MachinaryServer.SendTask(taskSignature)
 	if err != nil {
-		n.Logger.Info(err)
+		logger.Errorw("Error while publishing notification to the master", "notificationId", envelope.NotificationId, "error", err)
+		n.RecordTransitions(ctx, worker.NewTransition(envelope, "", contract.NOTIFICATION_FAILED, "publishing to the master failed"))
+		if scheduledId != "" {
+			//Nothing will send it so it mustn't be listed as pending
+			_ = n.Db.MarkScheduledNotificationFailed(ctx, scheduledId)
+		}
+		return "", "", status.Errorf(codes.Unavailable, "publishing notification failed: %v", err)
 	}
-	return &nm.IntSendNotificationReply{}, nil
+	n.RecordTransitions(ctx, worker.NewTransition(envelope, "", contract.NOTIFICATION_ACCEPTED, H.If(sendAt != nil, "scheduled", "")))
+	return envelope.NotificationId, scheduledId, nil
+}
+
+func (n *NotificationService) ListScheduledNotifications(ctx context.Context, req *pb.ListScheduledNotificationsReq) (*pb.ListScheduledNotificationsReply, error) {
+	if req.UserId == "" && req.AccountId == "" {
+		return nil, status.Error(codes.InvalidArgument, "userId or accountId is required")
+	}
+	return n.Db.ListScheduledNotifications(ctx, req)
+}
+
+func (n *NotificationService) CancelScheduledNotification(ctx context.Context, req *pb.CancelScheduledNotificationReq) (*pb.CancelScheduledNotificationReply, error) {
+	if req.ScheduledNotificationId == "" {
+		return nil, status.Error(codes.InvalidArgument, "scheduledNotificationId is empty")
+	}
+	reply, err := n.Db.CancelScheduledNotification(ctx, req)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if reply.Status != contract.SCHEDULED_CANCELLED {
+		return reply, status.Errorf(codes.FailedPrecondition, "scheduled notification is already %v", reply.Status)
+	}
+	return reply, nil
+}
+
+func (n *NotificationService) Broadcast(ctx context.Context, req *pb.BroadcastReq) (*pb.BroadcastStatus, error) {
+	if req.Name == "" || req.MessageTemplate == "" || len(req.NotificationTypes) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "name, messageTemplate and notificationTypes are required")
+	}
+
+	notificationTypes := []string{}
+	for _, v := range req.NotificationTypes {
+		ntType := strings.ToLower(v)
+		if !contract.IsValidNotificationType(ntType) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid notification type %v", v)
+		}
+		notificationTypes = append(notificationTypes, ntType)
+	}
+
+	broadcast := &model.Broadcasts{
+		UuId:                 uuid.New().String(),
+		Name:                 req.Name,
+		Subject:              H.If(req.Subject != "", req.Subject, req.Name),
+		MessageTemplate:      req.MessageTemplate,
+		NotificationTypes:    strings.Join(notificationTypes, ","),
+		BatchSize:            H.If(req.BatchSize > 0, int(req.BatchSize), contract.DEFAULT_BROADCAST_BATCH_SIZE),
+		BatchIntervalSeconds: H.If(req.BatchIntervalSeconds > 0, int(req.BatchIntervalSeconds), contract.DEFAULT_BROADCAST_BATCH_INTERVAL_SECONDS),
+		Status:               contract.BROADCAST_RUNNING,
+	}
+
+	err := n.Db.CreateBroadcast(ctx, broadcast, req.Segment)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := n.SendBroadcastBatch(broadcast.UuId, broadcast.Generation); err != nil {
+		return nil, err
+	}
+	return n.Db.GetBroadcastStatus(ctx, broadcast.UuId)
+}
+
+func (n *NotificationService) GetBroadcast(ctx context.Context, req *pb.BroadcastIdReq) (*pb.BroadcastStatus, error) {
+	reply, err := n.Db.GetBroadcastStatus(ctx, req.BroadcastId)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return reply, nil
+}
+
+func (n *NotificationService) PauseBroadcast(ctx context.Context, req *pb.BroadcastIdReq) (*pb.BroadcastStatus, error) {
+	return n.SetBroadcastStatus(ctx, req.BroadcastId, []string{contract.BROADCAST_RUNNING}, contract.BROADCAST_PAUSED)
+}
+
+func (n *NotificationService) ResumeBroadcast(ctx context.Context, req *pb.BroadcastIdReq) (*pb.BroadcastStatus, error) {
+	return n.SetBroadcastStatus(ctx, req.BroadcastId, []string{contract.BROADCAST_PAUSED}, contract.BROADCAST_RUNNING)
+}
+
+func (n *NotificationService) CancelBroadcast(ctx context.Context, req *pb.BroadcastIdReq) (*pb.BroadcastStatus, error) {
+	return n.SetBroadcastStatus(ctx, req.BroadcastId, []string{contract.BROADCAST_RUNNING, contract.BROADCAST_PAUSED}, contract.BROADCAST_CANCELLED)
+}
+
+func (n *NotificationService) SetBroadcastStatus(ctx context.Context, broadcastId string, from []string, to string) (*pb.BroadcastStatus, error) {
+	broadcast, err := n.Db.SetBroadcastStatus(ctx, broadcastId, from, to)
+	if err != nil {
+		if broadcast.UuId == "" {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	if to == contract.BROADCAST_RUNNING {
+		if err := n.SendBroadcastBatch(broadcast.UuId, broadcast.Generation); err != nil {
+			return nil, err
+		}
+	}
+	return n.Db.GetBroadcastStatus(ctx, broadcastId)
+}
+
+// SetAccountPlatforms backfills the platform of the accounts added before IntAddAccountConfig carried it
+func (n *NotificationService) SetAccountPlatforms(ctx context.Context, req *pb.SetAccountPlatformsReq) (*pb.SetAccountPlatformsReply, error) {
+	if len(req.Accounts) > contract.MAX_INGEST_BATCH_SIZE {
+		return nil, status.Errorf(codes.InvalidArgument, "request has %d accounts. Max is %d", len(req.Accounts), contract.MAX_INGEST_BATCH_SIZE)
+	}
+	for _, v := range req.Accounts {
+		if v.AccountId == "" || v.Platform == "" {
+			return nil, status.Error(codes.InvalidArgument, "accountId and platform are required")
+		}
+		v.Platform = strings.ToUpper(v.Platform)
+	}
+
+	notFound, err := n.Db.SetAccountPlatforms(ctx, req.Accounts)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SetAccountPlatformsReply{Updated: int32(len(req.Accounts) - len(notFound)), NotFound: notFound}, nil
+}
+
+// SendBroadcastBatch asks the master to send the next batch of the broadcast
+func (n *NotificationService) SendBroadcastBatch(broadcastId string, generation int) error {
+	if n.MachinaryServer == nil {
+		return fmt.Errorf("MachinaryServer is null")
+	}
+	task := worker.GetBroadcastBatchTask(contract.GetWorkerArgs().WorkerConfig.AMQP.BindingKey, broadcastId, generation, nil)
+	_, err := n.MachinaryServer.SendTask(task)
+	if err != nil {
+		n.Logger.Errorw("Error while sending broadcast batch task", "broadcastId", broadcastId, "error", err)
+	}
+	return err
+}
+
+func (n *NotificationService) IntSendNotifications(ctx context.Context, req *pb.IntSendNotificationsReq) (*pb.IntSendNotificationsReply, error) {
+	if len(req.Notifications) > contract.MAX_INGEST_BATCH_SIZE {
+		return nil, status.Errorf(codes.InvalidArgument, "batch has %d notifications. Max is %d", len(req.Notifications), contract.MAX_INGEST_BATCH_SIZE)
+	}
+	return n.IngestNotifications(req.Notifications, 0), nil
+}
+
+// StreamNotifications ingests the stream in chunks and replies with the result of every event once the client closes it
+func (n *NotificationService) StreamNotifications(stream pb.NotificationManagerInternalExt_StreamNotificationsServer) error {
+	reply := &pb.IntSendNotificationsReply{}
+	chunk := []*pb.NotificationEvent{}
+	offset := 0
+
+	flush := func() {
+		result := n.IngestNotifications(chunk, offset)
+		reply.Accepted += result.Accepted
+		reply.Rejected += result.Rejected
+		reply.Results = append(reply.Results, result.Results...)
+		offset += len(chunk)
+		chunk = []*pb.NotificationEvent{}
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			flush()
+			return stream.SendAndClose(reply)
+		}
+		if err != nil {
+			return err
+		}
+		chunk = append(chunk, event)
+		if len(chunk) == contract.INGEST_CHUNK_SIZE*contract.INGEST_PUBLISH_CONCURRENCY {
+			flush()
+		}
+	}
+}
+
+// IngestNotifications validates the events and publishes the accepted ones to the master in chunks.
+// offset is the index of the first event in the request
+func (n *NotificationService) IngestNotifications(events []*pb.NotificationEvent, offset int) *pb.IntSendNotificationsReply {
+	results := make([]*pb.NotificationResult, len(events))
+	accepted := []contract.NotificationEvent{}
+	indexes := []int{}
+
+	for i, v := range events {
+		results[i] = &pb.NotificationResult{Index: int32(offset + i), Accepted: true}
+		if err := ValidateNotificationEvent(v); err != nil {
+			results[i].Accepted = false
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].NotificationId = uuid.New().String()
+		accepted = append(accepted, contract.NotificationEvent{
+			NotificationId: results[i].NotificationId,
+			UserId:         v.UserId,
+			AccountId:      v.AccountId,
+			EventType:      v.EventType,
+			Data:           v.Data,
+		})
+		indexes = append(indexes, i)
+	}
+
+	reject := func(from, to int, err error) {
+		for _, i := range indexes[from:to] {
+			results[i].Accepted = false
+			results[i].Error = err.Error()
+		}
+	}
+
+	var wg sync.WaitGroup
+	inFlight := make(chan struct{}, contract.INGEST_PUBLISH_CONCURRENCY)
+	for from := 0; from < len(accepted); from += contract.INGEST_CHUNK_SIZE {
+		to := from + contract.INGEST_CHUNK_SIZE
+		if to > len(accepted) {
+			to = len(accepted)
+		}
+
+		eventsBytes, err := json.Marshal(accepted[from:to])
+		if err != nil {
+			reject(from, to, err)
+			continue
+		}
+
+		wg.Add(1)
+		inFlight <- struct{}{}
+		go func(from, to int, eventsBytes []byte) {
+			defer wg.Done()
+			defer func() { <-inFlight }()
+
+			err := n.SendNotificationChunk(eventsBytes)
+			if err != nil {
+				reject(from, to, err)
+				return
+			}
+
+			transitions := []model.NotificationTransitions{}
+			for _, v := range accepted[from:to] {
+				transitions = append(transitions, model.NotificationTransitions{
+					NotificationId: v.NotificationId,
+					State:          contract.NOTIFICATION_ACCEPTED,
+					UserId:         v.UserId,
+					AccountId:      v.AccountId,
+					EventType:      v.EventType,
+				})
+			}
+			n.RecordTransitions(context.Background(), transitions...)
+		}(from, to, eventsBytes)
+	}
+	wg.Wait()
+
+	reply := &pb.IntSendNotificationsReply{Results: results}
+	for _, v := range results {
+		if v.Accepted {
+			reply.Accepted++
+		} else {
+			reply.Rejected++
+		}
+	}
+	n.Logger.Infof("Ingested notifications accepted:%d rejected:%d", reply.Accepted, reply.Rejected)
+	return reply
+}
+
+func (n *NotificationService) SendNotificationChunk(eventsBytes []byte) error {
+	if n.MachinaryServer == nil {
+		return fmt.Errorf("MachinaryServer is null")
+	}
+	task := worker.GetRouteNotificationsTask(contract.GetWorkerArgs().WorkerConfig.AMQP.BindingKey, eventsBytes)
+	_, err := n.MachinaryServer.SendTask(task)
+	if err != nil {
+		n.Logger.Errorw("Error while sending notification chunk", "error", err)
+	}
+	return err
+}
+
+func ValidateNotificationEvent(event *pb.NotificationEvent) error {
+	if event == nil {
+		return fmt.Errorf("notification is empty")
+	}
+	if event.EventType == "" {
+		return fmt.Errorf("eventType is empty")
+	}
+	if _, ok := nm.EventType_value[event.EventType]; !ok {
+		return fmt.Errorf("unknown eventType %v", event.EventType)
+	}
+	if event.EventType == nm.EventType_ACCOUNT_DELETED.String() {
+		if event.UserId == "" {
+			return fmt.Errorf("userId is empty")
+		}
+		return nil
+	}
+	if event.AccountId == "" {
+		return fmt.Errorf("accountId is empty")
+	}
+	return nil
+}
+
+func (n *NotificationService) ExplainRoute(ctx context.Context, req *pb.ExplainRouteReq) (*pb.ExplainRouteReply, error) {
+	if req.EventType == "" || (req.UserId == "" && req.AccountId == "") {
+		return nil, status.Error(codes.InvalidArgument, "eventType and userId or accountId are required")
+	}
+	reply, err := worker.ExplainRoute(ctx, n.Db, req)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return reply, nil
+}
+
+func (n *NotificationService) ListWorkers(ctx context.Context, req *pb.ListWorkersReq) (*pb.ListWorkersReply, error) {
+	workerMeta, err := n.Db.GetWorkerMeta()
+	if err != nil {
+		return nil, err
+	}
+	heartbeats, err := n.Db.GetWorkerHeartbeats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ListWorkersReply{Workers: worker.GetWorkerHealth(workerMeta, heartbeats, time.Now())}, nil
+}
+
+// SetWorkerSettings changes the settings of a registered worker. The running slaves apply them within the settings refresh interval
+func (n *NotificationService) SetWorkerSettings(ctx context.Context, req *pb.WorkerSettings) (*pb.WorkerSettings, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if req.Concurrency < 0 || req.PrefetchCount < 0 || req.ProviderTimeoutSeconds < 0 {
+		return nil, status.Error(codes.InvalidArgument, "concurrency, prefetchCount and providerTimeoutSeconds can't be negative")
+	}
+
+	settings, err := n.Db.SetWorkerSettings(ctx, req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, status.Errorf(codes.NotFound, "worker %v is not registered", req.Name)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.WorkerSettings{
+		Name:                   req.Name,
+		Concurrency:            int32(settings.Concurrency),
+		PrefetchCount:          int32(settings.PrefetchCount),
+		ProviderTimeoutSeconds: int32(settings.ProviderTimeoutSeconds),
+		RateLimit:              int32(settings.RateLimit),
+	}, nil
 }
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Traders-Connect/utils"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/worker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+const deadLetterCursorName = "dead_letters"
+
+func (n *NotificationService) ListDeadLetters(ctx context.Context, req *pb.ListDeadLettersReq) (*pb.ListDeadLettersReply, error) {
+	filter, err := worker.ParseDeadLetterFilter(req.Filter)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = contract.DEFAULT_LOG_PAGE_SIZE
+	}
+	if limit > contract.MAX_LOG_PAGE_SIZE {
+		limit = contract.MAX_LOG_PAGE_SIZE
+	}
+	var afterId uint64
+	if req.Cursor != "" {
+		cursor, err := utils.ParseCursor(req.Cursor)
+		if err != nil || cursor.Name != deadLetterCursorName {
+			return nil, status.Error(codes.InvalidArgument, "invalid cursor")
+		}
+		afterId = uint64(cursor.ID)
+	}
+
+	letters, err := n.Db.ListDeadLetters(ctx, filter, afterId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &pb.ListDeadLettersReply{NextCursor: GetDeadLetterCursor(letters, limit)}
+	for _, v := range letters {
+		reply.DeadLetters = append(reply.DeadLetters, worker.ToDeadLetter(v))
+	}
+	return reply, nil
+}
+
+func (n *NotificationService) GetDeadLetter(ctx context.Context, req *pb.DeadLetterIdReq) (*pb.DeadLetter, error) {
+	if req.DeadLetterId == "" {
+		return nil, status.Error(codes.InvalidArgument, "deadLetterId is required")
+	}
+	letter, err := n.Db.GetDeadLetter(ctx, req.DeadLetterId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, status.Errorf(codes.NotFound, "dead letter %v not found", req.DeadLetterId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return worker.ToDeadLetter(letter), nil
+}
+
+// ReplayDeadLetters publishes the dead letters back to their workers. Bulk replays are throttled with rate
+func (n *NotificationService) ReplayDeadLetters(ctx context.Context, req *pb.ReplayDeadLettersReq) (*pb.ReplayDeadLettersReply, error) {
+	filter, err := worker.ParseDeadLetterFilter(req.Filter)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	replayer, err := worker.NewDeadLetterReplayer(n.Db, n.Logger)
+	if err != nil {
+		return nil, err
+	}
+	return replayer.ReplayDeadLetters(ctx, req.DeadLetterIds, filter, int(req.Limit), int(req.Rate))
+}
+
+func (n *NotificationService) PurgeDeadLetters(ctx context.Context, req *pb.PurgeDeadLettersReq) (*pb.PurgeDeadLettersReply, error) {
+	filter, err := worker.ParseDeadLetterFilter(req.Filter)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if len(req.DeadLetterIds) == 0 && filter.IsEmpty() {
+		return nil, status.Error(codes.InvalidArgument, "deadLetterIds or a filter is required")
+	}
+
+	purged, err := n.Db.PurgeDeadLetters(ctx, req.DeadLetterIds, filter)
+	if err != nil {
+		return nil, err
+	}
+	n.Logger.Infow("Purged dead letters", "ids", req.DeadLetterIds, "filter", filter, "purged", purged)
+	return &pb.PurgeDeadLettersReply{Purged: purged}, nil
+}
+
+// GetDeadLetterCursor returns the cursor of the page after letters. It is empty when letters is the last page
+func GetDeadLetterCursor(letters []model.DeadLetters, limit int) string {
+	if len(letters) == 0 || len(letters) < limit {
+		return ""
+	}
+	last := letters[len(letters)-1]
+	cursor := utils.Cursor{ID: uint(last.Id), Name: deadLetterCursorName, TimeStamp: last.CreatedAt.UTC().Truncate(time.Second)}
+	return cursor.ToBase64String()
+}
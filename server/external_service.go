@@ -2,8 +2,14 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 func (n *NotificationService) GetIntegrationStatus(ctx context.Context, payload *nm.IntegrationStatusReq) (*nm.IntegrationStatusReply, error) {
@@ -18,13 +24,18 @@ func (n *NotificationService) GetIntegrationStatus(ctx context.Context, payload
 func (n *NotificationService) InstallIntegration(ctx context.Context, payload *nm.InstallIntegrationReq) (*nm.InstallIntegrationReply, error) {
 
 	err := n.Db.InstallIntegration(ctx, payload)
-	return &nm.InstallIntegrationReply{}, err
+	if err != nil {
+		return nil, err
+	}
+	n.InvalidateRouting(ctx, payload.UserId)
+	return &nm.InstallIntegrationReply{}, nil
 }
 
 func (n *NotificationService) AddConfig(ctx context.Context, payload *nm.NotificationConfig) (*nm.AddConfigReply, error) {
 	if err := n.Db.AddConfig(ctx, payload); err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.AddConfigReply{}, nil
 }
 
@@ -34,6 +45,7 @@ func (n *NotificationService) EditConfig(ctx context.Context, payload *nm.EditCo
 	if err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.EditConfigReply{}, nil
 }
 
@@ -65,6 +77,7 @@ func (n *NotificationService) DeleteConfig(ctx context.Context, payload *nm.Dele
 		n.Log.Errorw("Error: while fetching UserConfig for userId: %v", payload.UserConfigId)
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.DeleteConfigReply{}, nil
 }
 
@@ -76,6 +89,7 @@ func (n *NotificationService) EditAccountMeta(ctx context.Context, payload *nm.A
 		n.Log.Errorw("Error: while fetching UserConfig for userId: %v", payload.AccountId)
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.EditAccountMetaReply{}, nil
 }
 
@@ -83,6 +97,7 @@ func (n *NotificationService) EditConfigStatus(ctx context.Context, payload *nm.
 	if err := n.Db.EditConfigStatus(ctx, payload); err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.EditConfigStatusReply{}, nil
 }
 
@@ -92,6 +107,7 @@ func (n *NotificationService) AddBot(ctx context.Context, payload *nm.AddBotReq)
 	if err := n.Db.AddBot(ctx, payload); err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.AddBotReply{}, nil
 }
 
@@ -100,6 +116,7 @@ func (n *NotificationService) EditBot(ctx context.Context, payload *nm.EditBotRe
 	if err := n.Db.EditBot(ctx, payload); err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.EditBotReply{}, nil
 }
 
@@ -118,6 +135,7 @@ func (n *NotificationService) EditBotStatus(ctx context.Context, payload *nm.Edi
 	if err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.EditBotStatusReply{}, nil
 }
 
@@ -128,6 +146,7 @@ func (n *NotificationService) AddChannel(ctx context.Context, payload *nm.AddCha
 	if err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.AddChannelReply{}, nil
 }
 
@@ -138,6 +157,7 @@ func (n *NotificationService) EditChannel(ctx context.Context, payload *nm.EditC
 	if err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.EditChannelReply{}, nil
 }
 
@@ -156,6 +176,7 @@ func (n *NotificationService) EditChannelStatus(ctx context.Context, payload *nm
 	if err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.EditChannelStatusReply{}, nil
 }
 
@@ -166,6 +187,7 @@ func (n *NotificationService) DeleteChannel(ctx context.Context, payload *nm.Del
 		return nil, err
 	}
 
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.DeleteChannelReply{}, nil
 }
 
@@ -188,10 +210,12 @@ func (n *NotificationService) GetBotEventDetails(ctx context.Context, payload *n
 }
 
 func (n *NotificationService) EditBotEventDetails(ctx context.Context, payload *nm.EditBotEventDetailsReq) (*nm.EditBotEventDetailsReply, error) {
+
 	err := n.Db.EditBotEventDetails(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.EditBotEventDetailsReply{}, nil
 }
 
@@ -200,6 +224,7 @@ func (n *NotificationService) EditBotEventStatus(ctx context.Context, payload *n
 	if err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.EditBotEventStatusReply{}, nil
 }
 
@@ -208,6 +233,7 @@ func (n *NotificationService) DeleteBots(ctx context.Context, payload *nm.Delete
 	if err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.DeleteBotReply{}, nil
 }
 
@@ -216,5 +242,136 @@ func (n *NotificationService) UninstallIntegration(ctx context.Context, payload
 	if err != nil {
 		return nil, err
 	}
+	n.InvalidateRouting(ctx, payload.UserId)
 	return &nm.UninstallIntegrationReply{}, nil
 }
+
+// Fallback
+func (n *NotificationService) SetFallbackChain(ctx context.Context, payload *pb.FallbackChain) (*pb.SetFallbackChainReply, error) {
+	err := n.Db.SetFallbackChain(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	n.InvalidateRouting(ctx, payload.UserId)
+	return &pb.SetFallbackChainReply{}, nil
+}
+
+func (n *NotificationService) GetFallbackChains(ctx context.Context, payload *pb.GetFallbackChainsReq) (*pb.GetFallbackChainsReply, error) {
+	reply, err := n.Db.GetFallbackChains(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// Escalation
+func (n *NotificationService) SetEscalationPolicy(ctx context.Context, payload *pb.EscalationPolicy) (*pb.SetEscalationPolicyReply, error) {
+	err := n.Db.SetEscalationPolicy(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	n.InvalidateRouting(ctx, payload.UserId)
+	return &pb.SetEscalationPolicyReply{}, nil
+}
+
+func (n *NotificationService) GetEscalationPolicy(ctx context.Context, payload *pb.GetEscalationPolicyReq) (*pb.EscalationPolicy, error) {
+	reply, err := n.Db.GetEscalationPolicy(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (n *NotificationService) AcknowledgeEscalation(ctx context.Context, payload *pb.AcknowledgeEscalationReq) (*pb.AcknowledgeEscalationReply, error) {
+	escalation, err := n.Db.GetEscalation(ctx, payload.EscalationId)
+	if err != nil {
+		return nil, err
+	}
+
+	userConfigId, err := n.Db.GetUserConfigId(ctx, payload.UserId)
+	if err != nil || *userConfigId != escalation.UserConfig {
+		return nil, fmt.Errorf("invalid request for userId:%v , escalationId: %v", payload.UserId, payload.EscalationId)
+	}
+
+	status, err := n.Db.AcknowledgeEscalation(ctx, payload.EscalationId, payload.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.AcknowledgeEscalationReply{Status: status}, nil
+}
+
+// Filter expressions
+func (n *NotificationService) SetFilterExpression(ctx context.Context, payload *pb.FilterExpression) (*pb.SetFilterExpressionReply, error) {
+	if payload.NotificationConfigId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "notificationConfigId is empty")
+	}
+	userConfigId, err := n.Db.GetUserConfigId(ctx, payload.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "user %v not found", payload.UserId)
+	}
+	if err := n.Db.ValidateFilterExpression(ctx, payload.NotificationConfigId, payload.FilterExpression); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if payload.BotConfigId == 0 {
+		err = n.Db.EditConfigFilter(ctx, payload.NotificationConfigId, *userConfigId, payload.FilterExpression)
+	} else {
+		err = n.Db.EditBotEventFilter(ctx, *userConfigId, payload.BotConfigId, payload.NotificationConfigId, payload.FilterExpression)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, status.Errorf(codes.NotFound, "notification config %v not found", payload.NotificationConfigId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	n.InvalidateRouting(ctx, payload.UserId)
+	return &pb.SetFilterExpressionReply{}, nil
+}
+
+func (n *NotificationService) GetFilterExpression(ctx context.Context, payload *pb.GetFilterExpressionReq) (*pb.FilterExpression, error) {
+	if payload.NotificationConfigId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "notificationConfigId is empty")
+	}
+	userConfigId, err := n.Db.GetUserConfigId(ctx, payload.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "user %v not found", payload.UserId)
+	}
+
+	var expr string
+	if payload.BotConfigId == 0 {
+		expr, err = n.Db.GetConfigFilter(ctx, *userConfigId, payload.NotificationConfigId)
+	} else {
+		expr, err = n.Db.GetBotEventFilter(ctx, *userConfigId, payload.BotConfigId, payload.NotificationConfigId)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, status.Errorf(codes.NotFound, "notification config %v not found", payload.NotificationConfigId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.FilterExpression{
+		UserId:               payload.UserId,
+		UserConfigId:         *userConfigId,
+		NotificationConfigId: payload.NotificationConfigId,
+		BotConfigId:          payload.BotConfigId,
+		FilterExpression:     expr,
+	}, nil
+}
+
+// Flap suppression
+func (n *NotificationService) SetFlapSettings(ctx context.Context, payload *pb.FlapSettings) (*pb.SetFlapSettingsReply, error) {
+	err := n.Db.SetFlapSettings(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	n.InvalidateRouting(ctx, payload.UserId)
+	return &pb.SetFlapSettingsReply{}, nil
+}
+
+func (n *NotificationService) GetFlapSettings(ctx context.Context, payload *pb.GetFlapSettingsReq) (*pb.FlapSettings, error) {
+	reply, err := n.Db.GetFlapSettings(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
@@ -8,22 +8,34 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-// Check performs a healthcheck
+// Check returns the last status of a dependency, or of every dependency for the empty service name
 func (s *NotificationService) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
-	//err := s.Db.PingContext(ctx)
-	//if err != nil {
-	//	s.Log.Errorw("healthcheck error", "dependency", "database", "error", err)
-	//	return &grpc_health_v1.HealthCheckResponse{
-	//		Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING,
-	//	}, err
-	//}
-
-	return &grpc_health_v1.HealthCheckResponse{
-		Status: grpc_health_v1.HealthCheckResponse_SERVING,
-	}, nil
+	servingStatus, ok := s.Health.Status(req.Service)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %v", req.Service)
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: servingStatus}, nil
 }
 
-// Watch performs a stream healthcheck
+// Watch streams the status of the service, then every change of it until the client goes away
 func (s *NotificationService) Watch(req *grpc_health_v1.HealthCheckRequest, server grpc_health_v1.Health_WatchServer) error {
-	return status.Error(codes.Unimplemented, "unimplemented")
+	changes, stop := s.Health.Watch()
+	defer stop()
+
+	last := grpc_health_v1.HealthCheckResponse_ServingStatus(-1)
+	for {
+		servingStatus, _ := s.Health.Status(req.Service)
+		if servingStatus != last {
+			if err := server.Send(&grpc_health_v1.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return status.Error(codes.Canceled, "stream has ended")
+			}
+			last = servingStatus
+		}
+
+		select {
+		case <-server.Context().Done():
+			return status.Error(codes.Canceled, "stream has ended")
+		case <-changes:
+		}
+	}
 }
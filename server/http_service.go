@@ -0,0 +1,23 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (n *NotificationService) RegisterHttpRoutes() {
+	n.HttpServer.Echo.GET("/escalations/:id/ack", n.AcknowledgeEscalationLink)
+}
+
+// AcknowledgeEscalationLink handles the acknowledgement link embedded in escalated notifications.
+// The escalation id is an unguessable uuid so the link doesn't need a token
+func (n *NotificationService) AcknowledgeEscalationLink(c echo.Context) error {
+	escalationId := c.Param("id")
+
+	status, err := n.Db.AcknowledgeEscalation(c.Request().Context(), escalationId, "link")
+	if err != nil {
+		return c.String(http.StatusNotFound, "Escalation not found")
+	}
+	return c.String(http.StatusOK, "Escalation "+status)
+}
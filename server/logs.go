@@ -0,0 +1,225 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Traders-Connect/utils"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const logCursorName = "logs"
+
+var logCsvHeader = []string{"id", "created_at", "user_config", "account_id", "event_type", "notification_type", "status", "req_meta", "chain_id", "fallback_from", "broadcast_id", "notification_id", "trace_id", "resend_of"}
+
+// ListNotificationLogs returns the delivery logs of the user. Rows of other users are never returned
+func (n *NotificationService) ListNotificationLogs(ctx context.Context, req *pb.ListNotificationLogsReq) (*pb.ListNotificationLogsReply, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "userId is required")
+	}
+	return n.listNotificationLogs(ctx, req)
+}
+
+func (n *NotificationService) IntListNotificationLogs(ctx context.Context, req *pb.ListNotificationLogsReq) (*pb.ListNotificationLogsReply, error) {
+	return n.listNotificationLogs(ctx, req)
+}
+
+func (n *NotificationService) ExportNotificationLogs(req *pb.ListNotificationLogsReq, stream pb.NotificationManagerExt_ExportNotificationLogsServer) error {
+	if req.UserId == "" {
+		return status.Error(codes.InvalidArgument, "userId is required")
+	}
+	return n.exportNotificationLogs(stream.Context(), req, stream.Send)
+}
+
+func (n *NotificationService) IntExportNotificationLogs(req *pb.ListNotificationLogsReq, stream pb.NotificationManagerInternalExt_IntExportNotificationLogsServer) error {
+	return n.exportNotificationLogs(stream.Context(), req, stream.Send)
+}
+
+func (n *NotificationService) listNotificationLogs(ctx context.Context, req *pb.ListNotificationLogsReq) (*pb.ListNotificationLogsReply, error) {
+	filter, err := n.GetLogFilter(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	afterId, limit, err := GetLogPage(req)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := n.Db.ListNotificationLogs(ctx, filter, afterId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &pb.ListNotificationLogsReply{NextCursor: GetLogCursor(logs, limit)}
+	for _, v := range logs {
+		reply.Logs = append(reply.Logs, ToNotificationLog(v))
+	}
+	return reply, nil
+}
+
+// exportNotificationLogs streams the logs of the filter page by page, up to MAX_LOG_EXPORT_ROWS rows
+func (n *NotificationService) exportNotificationLogs(ctx context.Context, req *pb.ListNotificationLogsReq, send func(*pb.NotificationLogChunk) error) error {
+	format := req.Format
+	if format == "" {
+		format = contract.LOG_EXPORT_CSV
+	}
+	if format != contract.LOG_EXPORT_CSV && format != contract.LOG_EXPORT_JSON {
+		return status.Errorf(codes.InvalidArgument, "format must be %v or %v", contract.LOG_EXPORT_CSV, contract.LOG_EXPORT_JSON)
+	}
+
+	filter, err := n.GetLogFilter(ctx, req)
+	if err != nil {
+		return err
+	}
+	afterId, _, err := GetLogPage(req)
+	if err != nil {
+		return err
+	}
+
+	exported := 0
+	for {
+		logs, err := n.Db.ListNotificationLogs(ctx, filter, afterId, contract.MAX_LOG_PAGE_SIZE)
+		if err != nil {
+			return err
+		}
+
+		data, err := EncodeLogs(format, logs, exported == 0)
+		if err != nil {
+			return err
+		}
+		if len(data) > 0 {
+			if err := send(&pb.NotificationLogChunk{Data: data}); err != nil {
+				return err
+			}
+		}
+
+		exported += len(logs)
+		if len(logs) < contract.MAX_LOG_PAGE_SIZE || exported >= contract.MAX_LOG_EXPORT_ROWS {
+			n.Logger.Infof("Exported %v notification logs", exported)
+			return nil
+		}
+		afterId = logs[len(logs)-1].Id
+	}
+}
+
+// GetLogFilter converts the request to a log filter. The user id is resolved to the user config of the logs
+func (n *NotificationService) GetLogFilter(ctx context.Context, req *pb.ListNotificationLogsReq) (contract.LogFilter, error) {
+	filter := contract.LogFilter{
+		AccountId:        req.AccountId,
+		EventType:        req.EventType,
+		NotificationType: req.NotificationType,
+		Status:           req.Status,
+	}
+
+	if req.UserId != "" {
+		userConfigId, err := n.Db.GetUserConfigId(ctx, req.UserId)
+		if err != nil {
+			return filter, status.Errorf(codes.NotFound, "user %v not found", req.UserId)
+		}
+		filter.UserConfig = fmt.Sprintf("%d", *userConfigId)
+	}
+
+	for _, v := range []struct {
+		value string
+		field **time.Time
+	}{{req.From, &filter.From}, {req.To, &filter.To}} {
+		if v.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v.value)
+		if err != nil {
+			return filter, status.Errorf(codes.InvalidArgument, "invalid time %v. Expected RFC3339", v.value)
+		}
+		t = t.UTC()
+		*v.field = &t
+	}
+	return filter, nil
+}
+
+// GetLogPage returns the id the page starts after and the page size of the request
+func GetLogPage(req *pb.ListNotificationLogsReq) (uint64, int, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = contract.DEFAULT_LOG_PAGE_SIZE
+	}
+	if limit > contract.MAX_LOG_PAGE_SIZE {
+		limit = contract.MAX_LOG_PAGE_SIZE
+	}
+
+	if req.Cursor == "" {
+		return 0, limit, nil
+	}
+	cursor, err := utils.ParseCursor(req.Cursor)
+	if err != nil || cursor.Name != logCursorName {
+		return 0, 0, status.Error(codes.InvalidArgument, "invalid cursor")
+	}
+	return uint64(cursor.ID), limit, nil
+}
+
+// GetLogCursor returns the cursor of the page after logs. It is empty when logs is the last page
+func GetLogCursor(logs []model.Logs, limit int) string {
+	if len(logs) == 0 || len(logs) < limit {
+		return ""
+	}
+	last := logs[len(logs)-1]
+	cursor := utils.Cursor{ID: uint(last.Id), Name: logCursorName, TimeStamp: last.CreatedAt.UTC().Truncate(time.Second)}
+	return cursor.ToBase64String()
+}
+
+func ToNotificationLog(log model.Logs) *pb.NotificationLog {
+	return &pb.NotificationLog{
+		Id:               log.Id,
+		CreatedAt:        log.CreatedAt.UTC().Format(time.RFC3339),
+		UserConfig:       log.UserConfig,
+		AccountId:        log.AccountId,
+		EventType:        log.EventType,
+		NotificationType: log.NotificationType,
+		Status:           log.Status,
+		ReqMeta:          string(log.ReqMeta),
+		ChainId:          log.ChainId,
+		FallbackFrom:     log.FallbackFrom,
+		BroadcastId:      log.BroadcastId,
+		NotificationId:   log.NotificationId,
+		TraceId:          log.TraceId,
+		ResendOf:         log.ResendOf,
+	}
+}
+
+// EncodeLogs encodes a page of an export. The csv header is written when header is true
+func EncodeLogs(format string, logs []model.Logs, header bool) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if format == contract.LOG_EXPORT_JSON {
+		encoder := json.NewEncoder(&buf)
+		for _, v := range logs {
+			if err := encoder.Encode(ToNotificationLog(v)); err != nil {
+				return nil, err
+			}
+		}
+		return buf.Bytes(), nil
+	}
+
+	w := csv.NewWriter(&buf)
+	if header {
+		_ = w.Write(logCsvHeader)
+	}
+	for _, v := range logs {
+		l := ToNotificationLog(v)
+		_ = w.Write([]string{
+			strconv.FormatUint(l.Id, 10), l.CreatedAt, l.UserConfig, l.AccountId, l.EventType, l.NotificationType,
+			l.Status, l.ReqMeta, l.ChainId, l.FallbackFrom, l.BroadcastId, l.NotificationId, l.TraceId,
+			strconv.FormatUint(l.ResendOf, 10),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/tracing"
+	"google.golang.org/grpc/metadata"
+)
+
+// ParseSendAt parses an RFC3339 send time or a delay in seconds from now.
+// Times that aren't in the future are sent right away
+func ParseSendAt(sendAt string, delaySeconds int32, now time.Time) (*time.Time, error) {
+	if sendAt != "" && delaySeconds != 0 {
+		return nil, fmt.Errorf("only one of send_at and delay_seconds can be set")
+	}
+
+	var eta time.Time
+	switch {
+	case sendAt != "":
+		t, err := time.Parse(time.RFC3339, sendAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid send_at %q. Expected RFC3339 time", sendAt)
+		}
+		eta = t
+	case delaySeconds < 0:
+		return nil, fmt.Errorf("invalid delay_seconds %v. Expected a non negative number of seconds", delaySeconds)
+	case delaySeconds > 0:
+		eta = now.Add(time.Duration(delaySeconds) * time.Second)
+	default:
+		return nil, nil
+	}
+
+	if !eta.After(now) {
+		return nil, nil
+	}
+	eta = eta.UTC()
+	return &eta, nil
+}
+
+// GetTraceContext reads the W3C traceparent of the caller from the request metadata
+func GetTraceContext(ctx context.Context) map[string]string {
+	md, found := metadata.FromIncomingContext(ctx)
+	if !found {
+		return nil
+	}
+	values := md.Get(tracing.TRACEPARENT)
+	if len(values) == 0 {
+		return nil
+	}
+	return map[string]string{tracing.TRACEPARENT: values[0]}
+}
+
+// GetPlatform reads the trading platform of an account sent as request metadata
+func GetPlatform(ctx context.Context) string {
+	md, found := metadata.FromIncomingContext(ctx)
+	if !found {
+		return ""
+	}
+	values := md.Get(contract.METADATA_PLATFORM)
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.ToUpper(values[0])
+}
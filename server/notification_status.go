@@ -0,0 +1,40 @@
+package server
+
+import (
+	"context"
+
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/worker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecordTransitions records lifecycle transitions. A failure is logged and doesn't fail the request
+func (n *NotificationService) RecordTransitions(ctx context.Context, transitions ...model.NotificationTransitions) {
+	err := n.Db.RecordNotificationTransitions(ctx, transitions)
+	if err != nil {
+		n.Logger.Errorw("Error while recording notification transitions", "error", err)
+	}
+}
+
+// GetNotificationStatus returns the lifecycle of a notification and of each of its destinations
+func (n *NotificationService) GetNotificationStatus(ctx context.Context, req *pb.GetNotificationStatusReq) (*pb.NotificationStatus, error) {
+	if req.NotificationId == "" {
+		return nil, status.Error(codes.InvalidArgument, "notificationId is empty")
+	}
+
+	transitions, err := n.Db.GetNotificationTransitions(ctx, req.NotificationId)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := n.Db.GetNotificationLogs(ctx, req.NotificationId)
+	if err != nil {
+		return nil, err
+	}
+	if len(transitions) == 0 && len(logs) == 0 {
+		return nil, status.Errorf(codes.NotFound, "notification %v not found", req.NotificationId)
+	}
+
+	return worker.GetNotificationStatus(req.NotificationId, transitions, logs), nil
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/tracing"
+	"github.com/devshahriar/notification-manager/worker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// ResendNotification resends logged deliveries as a new notification. The slaves log the deliveries
+// of the resend with the id of the log they resent
+func (n *NotificationService) ResendNotification(ctx context.Context, req *pb.ResendNotificationReq) (*pb.ResendNotificationReply, error) {
+	ctx, span := tracing.StartSpan(ctx, "ResendNotification", GetTraceContext(ctx))
+	defer span.End()
+
+	logs, err := n.GetResendLogs(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Destination != "" && len(logs) > 1 {
+		return nil, status.Error(codes.InvalidArgument, "destination needs a single delivery to resend. Set logId or notificationType")
+	}
+
+	userId, err := n.Db.GetUserIdByConfigId(ctx, logs[0].UserConfig)
+	if err != nil {
+		return nil, err
+	}
+	workerMeta, err := n.Db.GetWorkerMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	parent := worker.NewEnvelope(userId, logs[0].AccountId, logs[0].EventType, nil)
+	parent.TraceContext = tracing.Inject(ctx)
+
+	//Every delivery is checked before anything is sent so a resend is never partial because of the request
+	envelopes := make(map[uint64]*pb.TaskEnvelope, len(logs))
+	for _, v := range logs {
+		envelope, err := worker.GetResendEnvelope(parent, v, req.Template, req.Destination)
+		if errors.Is(err, worker.ErrResendNoMessage) || errors.Is(err, worker.ErrResendNoPayload) {
+			return nil, status.Errorf(codes.FailedPrecondition, "log %v: %v", v.Id, err)
+		}
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		envelopes[v.Id] = envelope
+	}
+
+	reply := &pb.ResendNotificationReply{NotificationId: parent.NotificationId}
+	transitions := []model.NotificationTransitions{}
+	for _, v := range logs {
+		envelope := envelopes[v.Id]
+		reason := fmt.Sprintf("resend of log %d", v.Id)
+
+		meta, ok := worker.GetSlaveMeta(workerMeta, v.NotificationType)
+		if !ok {
+			transitions = append(transitions, worker.NewTransition(envelope, v.NotificationType, contract.NOTIFICATION_FAILED, "no slave worker registered"))
+			continue
+		}
+		signature, err := worker.GetEnvelopeTask(worker.GetEnvelopeTaskName(v.NotificationType), meta.BindingKey, envelope)
+		if err == nil {
+			_, err = worker.GetPublisher(meta).MachineryServer.SendTask(signature)
+		}
+		if err != nil {
+			n.Logger.Errorw("Error while sending resend task", "logId", v.Id, "notificationType", v.NotificationType, "error", err)
+			transitions = append(transitions, worker.NewTransition(envelope, v.NotificationType, contract.NOTIFICATION_FAILED, err.Error()))
+			continue
+		}
+
+		transitions = append(transitions, worker.NewTransition(envelope, v.NotificationType, contract.NOTIFICATION_ROUTED, reason))
+		reply.ResentLogIds = append(reply.ResentLogIds, v.Id)
+	}
+
+	if len(reply.ResentLogIds) == 0 {
+		n.RecordTransitions(ctx, append(transitions, worker.NewTransition(parent, "", contract.NOTIFICATION_FAILED, "resend failed"))...)
+		return nil, status.Error(codes.Unavailable, "no delivery could be resent")
+	}
+	accepted := worker.NewTransition(parent, "", contract.NOTIFICATION_ACCEPTED, fmt.Sprintf("resend of %v", reply.ResentLogIds))
+	n.RecordTransitions(ctx, append([]model.NotificationTransitions{accepted}, transitions...)...)
+
+	n.Logger.Infow("Resent notification", "notificationId", parent.NotificationId, "logIds", reply.ResentLogIds, "template", req.Template)
+	return reply, nil
+}
+
+// GetResendLogs returns the log of req.LogId or the last delivery logs of req.NotificationId
+func (n *NotificationService) GetResendLogs(ctx context.Context, req *pb.ResendNotificationReq) ([]model.Logs, error) {
+	if req.LogId != 0 {
+		log, err := n.Db.GetLog(ctx, req.LogId)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "log %v not found", req.LogId)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if log.Status != contract.STATUS_SUCCESS && log.Status != contract.STATUS_FAILED {
+			return nil, status.Errorf(codes.FailedPrecondition, "log %v is a %v entry, not a delivery", log.Id, log.Status)
+		}
+		return []model.Logs{log}, nil
+	}
+
+	if req.NotificationId == "" {
+		return nil, status.Error(codes.InvalidArgument, "logId or notificationId is required")
+	}
+	logs, err := n.Db.GetDeliveryLogs(ctx, req.NotificationId)
+	if err != nil {
+		return nil, err
+	}
+	logs = worker.GetLastDeliveries(logs, req.NotificationType)
+	if len(logs) == 0 {
+		return nil, status.Errorf(codes.NotFound, "notification %v has no delivery to resend", req.NotificationId)
+	}
+	return logs, nil
+}
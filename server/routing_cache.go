@@ -0,0 +1,27 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devshahriar/notification-manager/cache"
+	"github.com/devshahriar/notification-manager/contract"
+)
+
+// InvalidateRouting tells the workers to drop the cached routing plans and meta of the user
+// after a config change. The cache ttl bounds the staleness when the publish fails
+func (n *NotificationService) InvalidateRouting(ctx context.Context, userId string) {
+	if n.Redis == nil {
+		return
+	}
+
+	userConfigId, err := n.Db.GetUserConfigId(ctx, userId)
+	if err != nil {
+		return
+	}
+
+	err = cache.Publish(ctx, n.Redis, contract.ROUTING_INVALIDATION_CHANNEL, fmt.Sprintf("%d", *userConfigId))
+	if err != nil {
+		n.Logger.Errorw("Error while publishing routing cache invalidation", "userId", userId, "error", err)
+	}
+}
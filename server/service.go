@@ -8,28 +8,37 @@ import (
 	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
 	"github.com/Traders-Connect/utils"
 	utilGrpc "github.com/Traders-Connect/utils/grpc"
+	"github.com/go-redis/redis/v8"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/devshahriar/notification-manager/contract"
 	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/health"
+	"github.com/devshahriar/notification-manager/pb"
 	"google.golang.org/grpc/reflection"
 )
 
 type NotificationService struct {
 	*utils.InstrumentedServer
+	HttpServer *utils.InstrumentedHTTPServer
 
 	nm.UnimplementedInternalServer
 	nm.UnimplementedNotificationManagerServer
+	pb.UnimplementedNotificationManagerExtServer
+	pb.UnimplementedNotificationManagerInternalExtServer
 
 	Db              db.DB
 	MachinaryServer *machinery.Server
 	Logger          *zap.SugaredLogger
 	Args            *contract.ServiceArgs
+	Redis           redis.UniversalClient // Publishes routing cache invalidations
+	Health          *health.Checker       // Health of the dependencies served by Check and Watch
 }
 
 func GetEndpointsRules() utilGrpc.RPCRules {
 	servicePath := "/notificationmanager.NotificationManager/"
+	extServicePath := "/notificationmanager.NotificationManagerExt/"
 	return utilGrpc.RPCRules{
 		Rules: map[string]utilGrpc.RPCRule{
 			//NoAuthRequired: true
@@ -61,6 +70,22 @@ func GetEndpointsRules() utilGrpc.RPCRules {
 			servicePath + "EditBotEventStatus":  {AllowedPermissions: []string{"nt-config:editBotEventStatus"}, NoAuthRequired: true},
 
 			servicePath + "UninstallIntegration": {AllowedPermissions: []string{"nt-config:uninstallIntegration"}, NoAuthRequired: true},
+
+			extServicePath + "SetFallbackChain":  {AllowedPermissions: []string{"nt-config:setFallbackChain"}, NoAuthRequired: true},
+			extServicePath + "GetFallbackChains": {AllowedPermissions: []string{"nt-config:getFallbackChains"}, NoAuthRequired: true},
+
+			extServicePath + "SetEscalationPolicy":   {AllowedPermissions: []string{"nt-config:setEscalationPolicy"}, NoAuthRequired: true},
+			extServicePath + "GetEscalationPolicy":   {AllowedPermissions: []string{"nt-config:getEscalationPolicy"}, NoAuthRequired: true},
+			extServicePath + "AcknowledgeEscalation": {AllowedPermissions: []string{"nt-config:acknowledgeEscalation"}, NoAuthRequired: true},
+
+			extServicePath + "SetFilterExpression": {AllowedPermissions: []string{"nt-config:setFilterExpression"}, NoAuthRequired: true},
+			extServicePath + "GetFilterExpression": {AllowedPermissions: []string{"nt-config:getFilterExpression"}, NoAuthRequired: true},
+
+			extServicePath + "SetFlapSettings": {AllowedPermissions: []string{"nt-config:setFlapSettings"}, NoAuthRequired: true},
+			extServicePath + "GetFlapSettings": {AllowedPermissions: []string{"nt-config:getFlapSettings"}, NoAuthRequired: true},
+
+			extServicePath + "ListNotificationLogs":   {AllowedPermissions: []string{"nt-config:listNotificationLogs"}, NoAuthRequired: true},
+			extServicePath + "ExportNotificationLogs": {AllowedPermissions: []string{"nt-config:exportNotificationLogs"}, NoAuthRequired: true},
 		},
 	}
 
@@ -83,8 +108,22 @@ func NewNotificationService(db db.DB, machinaryServer *machinery.Server, logger
 		logger.Errorw("error while creating instrumented server", "error", err)
 		return nil
 	}
+
+	// Serves the acknowledgement links embedded in escalated notifications
+	hs, err := utils.NewInstrumentedHTTPServer(utils.HttpSrvConfig{
+		ServiceName: "notification_manager_http",
+		APIAddr:     args.HttpAddr,
+		MetricsAddr: args.HttpMetricsAddr,
+		Logger:      logger,
+	})
+	if err != nil {
+		logger.Errorw("error while creating instrumented http server", "error", err)
+		return nil
+	}
+
 	return &NotificationService{
 		InstrumentedServer: is,
+		HttpServer:         hs,
 		Db:                 db,
 		MachinaryServer:    machinaryServer,
 		Logger:             logger,
@@ -96,14 +135,19 @@ func (n *NotificationService) Run(ctx context.Context) {
 	wg := new(sync.WaitGroup)
 
 	nm.RegisterInternalServer(n.InstrumentedServer.GRPCServerInternal, n)
+	pb.RegisterNotificationManagerInternalExtServer(n.InstrumentedServer.GRPCServerInternal, n)
 	reflection.Register(n.InstrumentedServer.GRPCServerInternal)
 	grpc_health_v1.RegisterHealthServer(n.InstrumentedServer.GRPCServerInternal, n)
 
 	nm.RegisterNotificationManagerServer(n.InstrumentedServer.GRPCServer, n)
+	pb.RegisterNotificationManagerExtServer(n.InstrumentedServer.GRPCServer, n)
 	reflection.Register(n.InstrumentedServer.GRPCServer)
 	grpc_health_v1.RegisterHealthServer(n.InstrumentedServer.GRPCServer, n)
 
+	n.RegisterHttpRoutes()
+
 	n.InstrumentedServer.Run(ctx, wg)
+	n.HttpServer.Run(ctx, wg)
 	n.Logger.Info("Notification Service running")
 	n.Logger.Info(n.Args.AddrInt)
 	wg.Wait()
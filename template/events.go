@@ -53,3 +53,8 @@ type TradeCopyFailure struct {
 	CopySlave   string `key:"COPIER_SLAVE"`
 	CopierError string `key:"COPIER_ERROR"`
 }
+
+// Broadcast struct For announcements sent with the Broadcast rpc
+type Broadcast struct {
+	BroadcastName string `key:"BROADCAST_NAME"`
+}
@@ -7,21 +7,35 @@ import (
 
 	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
 	"github.com/devShahriar/H"
+	"github.com/devshahriar/notification-manager/contract"
 )
 
 const (
 	EVENT_ACCOUNT_ADDED = "ACCOUNT_ADDED"
 )
 
-func IngestDataIntoMsgBody(firstName, eventName string, template string, data map[string]string) string {
+func IngestDataIntoMsgBody(firstName, eventName string, template string, data map[string]string, notificationType string) string {
 
 	msgStruct := GetMsgStruct(eventName)
 	H.PopulateStructFromMap(msgStruct, data)
 	fmt.Println(msgStruct)
 	body := IngestData(msgStruct, firstName, template)
+	body = IngestAckUrl(body, data[contract.ACK_URL], notificationType)
 	return body
 }
 
+// IngestAckUrl places the acknowledgement link of an escalation into %ACK_URL%.
+// Templates without the placeholder get the link appended on a new line of the notification type
+func IngestAckUrl(body, ackUrl, notificationType string) string {
+	if ackUrl == "" {
+		return strings.ReplaceAll(body, "%ACK_URL%", "")
+	}
+	if strings.Contains(body, "%ACK_URL%") {
+		return strings.ReplaceAll(body, "%ACK_URL%", ackUrl)
+	}
+	return body + H.If(notificationType == contract.EMAIL, "<br>", "\n") + "Acknowledge: " + ackUrl
+}
+
 func IngestData(obj interface{}, firstName, template string) string {
 	objValue := reflect.ValueOf(obj).Elem()
 	objType := objValue.Type()
@@ -42,6 +56,21 @@ func IngestData(obj interface{}, firstName, template string) string {
 	return template
 }
 
+// GetEventKeys returns the data keys an event carries
+func GetEventKeys(eventName string) []string {
+	msgStruct := GetMsgStruct(eventName)
+	if msgStruct == nil {
+		return []string{}
+	}
+
+	keys := []string{}
+	objType := reflect.TypeOf(msgStruct).Elem()
+	for i := 0; i < objType.NumField(); i++ {
+		keys = append(keys, objType.Field(i).Tag.Get("key"))
+	}
+	return keys
+}
+
 func GetMsgStruct(eventName string) interface{} {
 	switch eventName {
 	case nm.EventType_ACCOUNT_ADDED.String(),
@@ -73,6 +102,9 @@ func GetMsgStruct(eventName string) interface{} {
 
 	case nm.EventType_TRADE_COPY_FAILURE.String():
 		return &TradeCopyFailure{}
+
+	case contract.BROADCAST:
+		return &Broadcast{}
 	}
 
 	return nil
@@ -0,0 +1,230 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/worker"
+	"github.com/mailgun/mailgun-go/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+func TestBreakerSettings(t *testing.T) {
+	settings, err := worker.ParseBreakerSettings(contract.DEFAULT_CIRCUIT_BREAKER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.ErrorRate != 0.5 || settings.MinRequests != 20 || settings.Window != time.Minute || settings.OpenFor != 30*time.Second {
+		t.Errorf("unexpected settings %+v", settings)
+	}
+
+	for _, spec := range []string{"50:20:60s", "0:20:60s:30s", "101:20:60s:30s", "50:0:60s:30s", "50:20:1x:30s", "50:20:60s:0s"} {
+		if _, err := worker.ParseBreakerSettings(spec); err == nil {
+			t.Errorf("expected an error for %v", spec)
+		}
+	}
+
+	if names := worker.BotBreakers(contract.TELEGRAM, 12); len(names) != 2 || names[0] != contract.TELEGRAM || names[1] != "telegram:bot-12" {
+		t.Errorf("expected the breakers of the provider and of the bot got %v", names)
+	}
+}
+
+func TestBreakerStates(t *testing.T) {
+	b := worker.NewBreaker("test", worker.BreakerSettings{ErrorRate: 0.5, MinRequests: 4, Window: time.Minute, OpenFor: 30 * time.Second})
+	now := time.Now()
+
+	//Below the min requests the breaker stays closed whatever the error rate
+	for i := 0; i < 3; i++ {
+		b.Record(true, now)
+	}
+	if !b.Allow(now) || b.Status().State != contract.BREAKER_CLOSED {
+		t.Fatalf("expected a closed breaker got %+v", b.Status())
+	}
+
+	//A new window forgets the failures of the last one
+	b.Record(true, now.Add(2*time.Minute))
+	if b.Status().State != contract.BREAKER_CLOSED || b.Status().Requests != 1 {
+		t.Fatalf("expected a new window got %+v", b.Status())
+	}
+
+	now = now.Add(2 * time.Minute)
+	b.Record(false, now)
+	b.Record(true, now)
+	b.Record(false, now)
+	if b.Status().State != contract.BREAKER_OPEN || b.Allow(now.Add(29*time.Second)) {
+		t.Fatalf("expected an open breaker got %+v", b.Status())
+	}
+	if until := b.OpenUntil(); !until.Equal(now.Add(30 * time.Second)) {
+		t.Errorf("expected the breaker to open until %v got %v", now.Add(30*time.Second), until)
+	}
+
+	//A single probe goes through once the breaker was open long enough
+	probe := now.Add(30 * time.Second)
+	if !b.Allow(probe) || b.Allow(probe) || b.Status().State != contract.BREAKER_HALF_OPEN {
+		t.Fatalf("expected a single probe got %+v", b.Status())
+	}
+	b.Record(true, probe)
+	if b.Status().State != contract.BREAKER_OPEN {
+		t.Fatalf("expected a failed probe to open the breaker again got %+v", b.Status())
+	}
+
+	probe = probe.Add(30 * time.Second)
+	if !b.Allow(probe) {
+		t.Fatal("expected a second probe")
+	}
+	b.Record(false, probe)
+	if status := b.Status(); status.State != contract.BREAKER_CLOSED || status.Requests != 0 || !b.Allow(probe) {
+		t.Errorf("expected a successful probe to close the breaker got %+v", status)
+	}
+}
+
+func TestAllowAllBreakers(t *testing.T) {
+	settings := worker.BreakerSettings{ErrorRate: 1, MinRequests: 1, Window: time.Minute, OpenFor: 30 * time.Second}
+	provider := worker.NewBreaker(contract.TELEGRAM, settings)
+	bot := worker.NewBreaker(worker.BreakerName(contract.TELEGRAM, 1), settings)
+	now := time.Now()
+
+	//The provider is half open with a probe that never reported back, the bot opened later
+	provider.Record(true, now)
+	if !provider.Allow(now.Add(30 * time.Second)) {
+		t.Fatal("expected the provider to probe")
+	}
+	bot.Record(true, now.Add(45*time.Second))
+
+	breakers := []*worker.Breaker{provider, bot}
+	probe := now.Add(60 * time.Second)
+	if i := worker.AllowAll(breakers, probe); i != 1 {
+		t.Fatalf("expected the open bot breaker to refuse got %v", i)
+	}
+	if provider.Status().State != contract.BREAKER_HALF_OPEN || !provider.Ready(probe) {
+		t.Fatalf("expected the refused call not to take the probe of the provider got %+v", provider.Status())
+	}
+
+	//A probe taken for a call that was never made is given back
+	if !provider.Allow(probe) || provider.Allow(probe.Add(time.Second)) {
+		t.Fatal("expected a single probe")
+	}
+	worker.ReleaseAll(breakers, probe)
+	if !provider.Allow(probe.Add(time.Second)) {
+		t.Error("expected the released probe to let the next call through")
+	}
+}
+
+func TestDeliverBreaker(t *testing.T) {
+	w := &worker.Worker{
+		RetryPolicies: map[string]worker.RetryPolicy{
+			contract.EMAIL: {MaxAttempts: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		},
+		Breakers: worker.NewBreakers(worker.BreakerSettings{ErrorRate: 1, MinRequests: 2, Window: time.Minute, OpenFor: time.Minute}),
+	}
+	ctx := context.Background()
+
+	//Rejected recipients don't open the breaker
+	for i := 0; i < 3; i++ {
+		_, _ = w.Deliver(ctx, contract.EMAIL, "a@example.com", []string{"rejected"}, func() error {
+			return tgbotapi.Error{Message: "Bad Request: chat not found"}
+		})
+	}
+	if state := w.Breakers.Get("rejected").Status().State; state != contract.BREAKER_CLOSED {
+		t.Fatalf("expected permanent errors to keep the breaker closed got %v", state)
+	}
+
+	breaker := []string{worker.BreakerName(contract.EMAIL, 0)}
+	for i := 0; i < 2; i++ {
+		_, _ = w.Deliver(ctx, contract.EMAIL, "a@example.com", breaker, func() error {
+			return &mailgun.UnexpectedResponseError{Actual: 503}
+		})
+	}
+
+	calls := 0
+	attempts, err := w.Deliver(ctx, contract.EMAIL, "a@example.com", breaker, func() error {
+		calls++
+		return nil
+	})
+	var openErr *worker.BreakerOpenError
+	if calls != 0 || attempts != 0 || !errors.As(err, &openErr) || worker.ClassifyError(err) != contract.ERROR_TRANSIENT {
+		t.Errorf("expected the open breaker to skip the provider got calls:%v attempts:%v err:%v", calls, attempts, err)
+	}
+	if health := w.Health(); health.Status != contract.WORKER_DEGRADED || len(health.Breakers) != 2 {
+		t.Errorf("expected a degraded worker got %+v", health)
+	}
+}
+
+func TestProviderBreaker(t *testing.T) {
+	w := &worker.Worker{
+		RetryPolicies: map[string]worker.RetryPolicy{
+			contract.TELEGRAM: {MaxAttempts: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		},
+		Breakers: worker.NewBreakers(worker.BreakerSettings{ErrorRate: 0.5, MinRequests: 4, Window: time.Minute, OpenFor: time.Minute}),
+	}
+	ctx := context.Background()
+	opened := metricValue(t, "notification_manager_circuit_breaker_transitions_total", map[string]string{"breaker": contract.TELEGRAM, "state": contract.BREAKER_OPEN})
+
+	//Telegram is down. Each bot makes a single call, too few to open its own breaker
+	for bot := uint64(1); bot <= 4; bot++ {
+		_, _ = w.Deliver(ctx, contract.TELEGRAM, "channel 1", worker.BotBreakers(contract.TELEGRAM, bot), func() error {
+			return &mailgun.UnexpectedResponseError{Actual: 502}
+		})
+	}
+	if state := w.Breakers.Get(worker.BreakerName(contract.TELEGRAM, 1)).Status().State; state != contract.BREAKER_CLOSED {
+		t.Fatalf("expected the breaker of a bot to stay closed got %v", state)
+	}
+
+	calls := 0
+	_, err := w.Deliver(ctx, contract.TELEGRAM, "channel 1", worker.BotBreakers(contract.TELEGRAM, 5), func() error {
+		calls++
+		return nil
+	})
+	var openErr *worker.BreakerOpenError
+	if calls != 0 || !errors.As(err, &openErr) || openErr.Name != contract.TELEGRAM {
+		t.Errorf("expected the open breaker of the provider to skip every bot got calls:%v err:%v", calls, err)
+	}
+
+	//Only the breakers of the providers are exported, not one series per bot
+	if v := metricValue(t, "notification_manager_circuit_breaker_transitions_total", map[string]string{"breaker": contract.TELEGRAM, "state": contract.BREAKER_OPEN}) - opened; v != 1 {
+		t.Errorf("expected the provider breaker to be exported got %v", v)
+	}
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "breaker" && strings.Contains(l.GetValue(), ":") {
+					t.Errorf("expected bot breakers not to be exported got %v %v", family.GetName(), l.GetValue())
+				}
+			}
+		}
+	}
+}
+
+func TestParkedTask(t *testing.T) {
+	signature := &tasks.Signature{Name: worker.GetEnvelopeTaskName(contract.TELEGRAM), RoutingKey: "nt-telegram", UUID: "task-1", RetryCount: 1}
+	eta := time.Now().Add(time.Minute)
+
+	parked := worker.GetParkedTask(signature, []string{worker.GetBotDestination(3, "-100"), worker.GetBotDestination(4, "-200")}, eta)
+	if parked.UUID != "" || parked.ETA == nil || !parked.ETA.Equal(eta) || parked.RoutingKey != "nt-telegram" || parked.RetryCount != 1 {
+		t.Errorf("unexpected parked task %+v", parked)
+	}
+	if worker.GetParkCount(parked) != 1 || worker.GetParkCount(worker.GetParkedTask(parked, nil, eta)) != 2 || signature.Headers != nil {
+		t.Errorf("expected the park count to go up without touching the task got %v", parked.Headers)
+	}
+
+	task, err := tasks.NewWithSignature(func(ctx context.Context) error { return nil }, parked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if worker.IsDestinationSent(task.Context, worker.GetBotDestination(3, "-100")) || !worker.IsDestinationSent(task.Context, worker.GetBotDestination(5, "-100")) {
+		t.Error("expected the parked task to only send the parked destinations")
+	}
+	if worker.IsDestinationSent(context.Background(), worker.GetBotDestination(5, "-100")) {
+		t.Error("expected a task that wasn't parked to send every destination")
+	}
+}
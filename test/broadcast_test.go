@@ -0,0 +1,113 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/server"
+	"github.com/devshahriar/notification-manager/template"
+	"github.com/devshahriar/notification-manager/worker"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBroadcastTemplate(t *testing.T) {
+	temp := "Hi %FIRST_NAME%, %BROADCAST_NAME% starts on Saturday 10:00 UTC"
+	data := map[string]string{"BROADCAST_NAME": "Scheduled maintenance"}
+
+	body := template.IngestDataIntoMsgBody("Shahriar", contract.BROADCAST, temp, data, contract.EMAIL)
+	expected := "Hi Shahriar, Scheduled maintenance starts on Saturday 10:00 UTC"
+	if body != expected {
+		t.Errorf("expected %q got %q", expected, body)
+	}
+}
+
+func TestIsChannelEnabled(t *testing.T) {
+	userConfig := model.UserConfig{EmailEnabled: true, TelegramEnabled: false, WhatsAppEnabled: true}
+
+	cases := map[string]bool{
+		contract.EMAIL:    true,
+		contract.TELEGRAM: false,
+		contract.DISCORD:  false,
+		"whatsapp":        true,
+		"sms":             false,
+	}
+	for ntType, expected := range cases {
+		if got := worker.IsChannelEnabled(userConfig, ntType); got != expected {
+			t.Errorf("%v: expected %v got %v", ntType, expected, got)
+		}
+	}
+}
+
+type broadcastDB struct {
+	db.DB
+	broadcast model.Broadcasts
+}
+
+func (d *broadcastDB) GetBroadcast(ctx context.Context, broadcastId string) (model.Broadcasts, error) {
+	return d.broadcast, nil
+}
+
+func (d *broadcastDB) SetBroadcastStatus(ctx context.Context, broadcastId string, from []string, status string) (model.Broadcasts, error) {
+	d.broadcast.Status = status
+	return d.broadcast, nil
+}
+
+func TestBroadcastWithoutSlave(t *testing.T) {
+	database := &broadcastDB{broadcast: model.Broadcasts{UuId: "b1", NotificationTypes: "email,telegram", Status: contract.BROADCAST_RUNNING, BatchSize: 100}}
+	router := &worker.NotificationRouter{Worker: &worker.Worker{Db: database, Logger: zap.NewNop().Sugar()}}
+
+	//The batch isn't read or claimed so no user is dead-lettered
+	if err := router.BroadcastBatch(context.Background(), "b1", 0); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if database.broadcast.Status != contract.BROADCAST_PAUSED {
+		t.Errorf("expected the broadcast to be paused got %v", database.broadcast.Status)
+	}
+}
+
+type platformDB struct {
+	db.DB
+	platforms map[string]string
+}
+
+func (d *platformDB) SetAccountPlatforms(ctx context.Context, accounts []*pb.AccountPlatform) ([]string, error) {
+	notFound := []string{}
+	for _, v := range accounts {
+		if _, ok := d.platforms[v.AccountId]; !ok {
+			notFound = append(notFound, v.AccountId)
+			continue
+		}
+		d.platforms[v.AccountId] = v.Platform
+	}
+	return notFound, nil
+}
+
+func TestSetAccountPlatforms(t *testing.T) {
+	database := &platformDB{platforms: map[string]string{"a1": ""}}
+	n := &server.NotificationService{Db: database, Logger: zap.NewNop().Sugar()}
+
+	reply, err := n.SetAccountPlatforms(context.Background(), &pb.SetAccountPlatformsReq{Accounts: []*pb.AccountPlatform{
+		{AccountId: "a1", Platform: "mt5"},
+		{AccountId: "a2", Platform: "MT4"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if reply.Updated != 1 || len(reply.NotFound) != 1 || reply.NotFound[0] != "a2" {
+		t.Errorf("expected one updated and a2 not found, got %+v", reply)
+	}
+	if database.platforms["a1"] != "MT5" {
+		t.Errorf("expected the platform to be uppercased, got %q", database.platforms["a1"])
+	}
+
+	_, err = n.SetAccountPlatforms(context.Background(), &pb.SetAccountPlatformsReq{Accounts: []*pb.AccountPlatform{{AccountId: "a1"}}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without a platform, got %v", err)
+	}
+}
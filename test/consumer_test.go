@@ -0,0 +1,58 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2"
+	brokersiface "github.com/RichardKnop/machinery/v2/brokers/iface"
+	"github.com/RichardKnop/machinery/v2/config"
+	"github.com/devshahriar/notification-manager/worker"
+	"go.uber.org/zap"
+)
+
+// droppingBroker consumes until the broker connection is dropped, then reconnects like the AMQP broker of machinery
+type droppingBroker struct {
+	brokersiface.Broker
+	started chan struct{}
+	drop    chan error
+}
+
+func (b *droppingBroker) StartConsuming(consumerTag string, concurrency int, taskProcessor brokersiface.TaskProcessor) (bool, error) {
+	b.started <- struct{}{}
+	return true, <-b.drop
+}
+
+func TestConsumerCheck(t *testing.T) {
+	w := &worker.Worker{Logger: zap.NewNop().Sugar(), ShutdownTimeout: time.Second}
+	if err := w.ConsumerCheck(context.Background()); !errors.Is(err, worker.ErrNotConsuming) {
+		t.Fatalf("expected a worker that doesn't consume not to be ready got %v", err)
+	}
+
+	broker := &droppingBroker{started: make(chan struct{}, 1), drop: make(chan error)}
+	server := machinery.NewServerWithBrokerBackendLock(&config.Config{NoUnixSignals: true}, w.ConsumerBroker(broker), &publishBackend{}, nil)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Consume(context.Background(), server.NewWorker("consumer_test", 1))
+	}()
+
+	<-broker.started
+	if err := w.ConsumerCheck(context.Background()); err != nil {
+		t.Fatalf("expected a consuming worker to be ready got %v", err)
+	}
+
+	broker.drop <- errors.New("Exception (320) Reason: \"CONNECTION_FORCED\"")
+	select {
+	case err := <-done:
+		if !errors.Is(err, worker.ErrConsumerLost) {
+			t.Fatalf("expected Consume to return the lost connection got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Consume to return once the connection is lost")
+	}
+	if err := w.ConsumerCheck(context.Background()); !errors.Is(err, worker.ErrConsumerLost) {
+		t.Errorf("expected the lost connection to be reported got %v", err)
+	}
+}
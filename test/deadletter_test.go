@@ -0,0 +1,115 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/worker"
+)
+
+func TestDeadLetterRoundTrip(t *testing.T) {
+	parent := worker.NewEnvelope("user-1", "acc-1", "trade_failed", []byte(`{"symbol":"EURUSD"}`))
+	envelope := worker.GetDeliveryEnvelope(parent, contract.TELEGRAM, "trade_failed", "42", "acc-1", []byte(`{"symbol":"EURUSD"}`))
+	signature, err := worker.GetEnvelopeTask(worker.GetEnvelopeTaskName(contract.TELEGRAM), "nt-telegram", envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Tasks being processed carry the signature decoded from the queue
+	encoded, _ := json.Marshal(signature)
+	received := &tasks.Signature{}
+	if err := json.Unmarshal(encoded, received); err != nil {
+		t.Fatal(err)
+	}
+	received.RetryCount = 0
+
+	letter, err := worker.NewDeadLetter("nt-telegram", received, errors.New("permanent: Bad Request: chat not found"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if letter.NotificationId != parent.NotificationId || letter.NotificationType != contract.TELEGRAM ||
+		letter.UserConfig != "42" || letter.AccountId != "acc-1" || letter.Status != contract.DEAD_LETTER_DEAD {
+		t.Errorf("unexpected dead letter %+v", letter)
+	}
+
+	deadLetter := worker.GetDeadLetterTask(received, letter, "nt-telegram"+contract.DEAD_LETTER_QUEUE_SUFFIX)
+	if deadLetter.Headers[contract.HEADER_DEAD_LETTER_ID] != letter.UuId || deadLetter.Headers[contract.HEADER_LAST_ERROR] != letter.LastError {
+		t.Errorf("unexpected dead letter headers %v", deadLetter.Headers)
+	}
+	if received.RoutingKey != "nt-telegram" || received.Headers[contract.HEADER_DEAD_LETTER_ID] != nil {
+		t.Error("expected the failed task to be left as it is")
+	}
+
+	meta := contract.WorkerMeta{Name: "nt-telegram-2", BindingKey: "nt-telegram-2", NotificationType: contract.TELEGRAM}
+	replay, err := worker.GetReplayTask(*letter, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.RoutingKey != meta.BindingKey || replay.RetryCount != contract.TASK_RETRY_COUNT || replay.UUID != "" {
+		t.Errorf("unexpected replay task %+v", replay)
+	}
+	replayed, err := worker.DecodeEnvelope(worker.GetBytesArg(replay.Args[0]))
+	if err != nil || replayed.IdempotencyKey != envelope.IdempotencyKey {
+		t.Errorf("expected the envelope to survive the replay got %v err:%v", replayed, err)
+	}
+}
+
+func TestDeadLetterReplayWorker(t *testing.T) {
+	metas := []contract.WorkerMeta{
+		{Name: "nt-master", WorkerType: contract.MASTER, NotificationType: contract.MASTER},
+		{Name: "nt-email", WorkerType: contract.EMAIL, NotificationType: contract.EMAIL},
+		{Name: "nt-email-2", WorkerType: contract.EMAIL, NotificationType: contract.EMAIL},
+	}
+
+	letter := func(workerName, notificationType string) model.DeadLetters {
+		return model.DeadLetters{WorkerName: workerName, NotificationType: notificationType}
+	}
+	for _, v := range []struct {
+		letter model.DeadLetters
+		worker string
+	}{
+		{letter("nt-email", contract.EMAIL), "nt-email"},
+		{letter("nt-master", ""), "nt-master"},
+		{letter("nt-email-old", contract.EMAIL), "nt-email-2"},
+		{letter("", contract.EMAIL), "nt-email-2"},
+		{letter("", contract.DISCORD), ""},
+	} {
+		meta, _ := worker.GetReplayWorkerMeta(metas, v.letter)
+		if meta.Name != v.worker {
+			t.Errorf("expected %+v to be replayed on %q got %q", v.letter, v.worker, meta.Name)
+		}
+	}
+}
+
+func TestWithDeadLetter(t *testing.T) {
+	w := &worker.Worker{}
+	calls := 0
+	fn := func(ctx context.Context, envelopeBytes []byte) error {
+		calls++
+		return errors.New("db is down")
+	}
+
+	wrapped, ok := w.WithDeadLetter(fn).(func(context.Context, []byte) error)
+	if !ok {
+		t.Fatal("expected the wrapped task to keep its type")
+	}
+	//No signature in ctx, nothing to dead-letter
+	if err := wrapped(context.Background(), nil); err == nil || calls != 1 {
+		t.Errorf("expected the task error to be returned got %v", err)
+	}
+
+	positional := func(userConfig string) error { return nil }
+	if _, ok := w.WithDeadLetter(positional).(func(string) error); !ok {
+		t.Error("expected tasks without a context to be returned as they are")
+	}
+
+	if state := worker.GetFanOutState([]*pb.DestinationStatus{{State: contract.NOTIFICATION_FAILED}, {State: contract.NOTIFICATION_DEAD_LETTERED}}); state != contract.NOTIFICATION_DEAD_LETTERED {
+		t.Errorf("expected %v got %v", contract.NOTIFICATION_DEAD_LETTERED, state)
+	}
+}
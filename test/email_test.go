@@ -10,6 +10,6 @@ import (
 func TestEmail(t *testing.T) {
 	log, _ := utils.NewLogger("notification-server", "info")
 
-	taskEmail := worker.TaskSendEmail{&worker.Worker{Db: GetTestDbConn(), Logger: log}}
-	taskEmail.SendEmail("1001", "898737", "trade_failed", []byte{})
+	taskEmail := worker.TaskSendEmail{Worker: &worker.Worker{Db: GetTestDbConn(), Logger: log}}
+	taskEmail.SendEmail(ctx, "1001", "898737", "trade_failed", []byte{})
 }
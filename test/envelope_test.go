@@ -0,0 +1,136 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	machineryConf "github.com/RichardKnop/machinery/v2/config"
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/worker"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEnvelopeTask(t *testing.T) {
+	envelope := worker.NewEnvelope("user-1", "acc-1", "ACCOUNT_CONNECTED", []byte(`{"name":"demo"}`))
+	if envelope.NotificationId == "" || envelope.IdempotencyKey != envelope.NotificationId || envelope.CreatedAt == "" {
+		t.Fatalf("envelope metadata not set %+v", envelope)
+	}
+
+	delivery := worker.GetDeliveryEnvelope(envelope, contract.EMAIL, "ACCOUNT_CONNECTED", "10", "acc-1", []byte(`{"name":"demo"}`))
+	if delivery.NotificationId != envelope.NotificationId {
+		t.Errorf("expected notification id %v got %v", envelope.NotificationId, delivery.NotificationId)
+	}
+	if delivery.IdempotencyKey != envelope.NotificationId+":"+contract.EMAIL {
+		t.Errorf("unexpected idempotency key %v", delivery.IdempotencyKey)
+	}
+	if len(delivery.Destinations) != 1 || delivery.Destinations[0] != contract.EMAIL {
+		t.Errorf("unexpected destinations %v", delivery.Destinations)
+	}
+
+	signature, err := worker.GetEnvelopeTask(worker.GetEnvelopeTaskName(contract.EMAIL), "email", delivery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature.Name != "task_deliver_email" || len(signature.Args) != 1 {
+		t.Fatalf("unexpected signature %+v", signature)
+	}
+
+	var got []string
+	task := worker.EnvelopeTask(func(ctx context.Context, userConfig, accId, eventType string, data []byte) error {
+		got = []string{userConfig, accId, eventType, string(data), worker.EnvelopeFromContext(ctx).GetIdempotencyKey()}
+		return nil
+	})
+	if err := task(context.Background(), signature.Args[0].Value.([]byte)); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"10", "acc-1", "ACCOUNT_CONNECTED", `{"name":"demo"}`, delivery.IdempotencyKey}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("expected %v got %v", expected[i], got[i])
+		}
+	}
+}
+
+func TestDecodeEnvelope(t *testing.T) {
+	newer, _ := proto.Marshal(&pb.TaskEnvelope{Version: contract.TASK_ENVELOPE_VERSION + 1, Event: &pb.TaskEvent{}})
+	if _, err := worker.DecodeEnvelope(newer); err == nil {
+		t.Error("expected newer envelope version to be rejected")
+	}
+
+	noEvent, _ := proto.Marshal(&pb.TaskEnvelope{Version: contract.TASK_ENVELOPE_VERSION})
+	if _, err := worker.DecodeEnvelope(noEvent); err == nil {
+		t.Error("expected envelope without event to be rejected")
+	}
+
+	if _, err := worker.DecodeEnvelope([]byte("not an envelope")); err == nil {
+		t.Error("expected invalid envelope to be rejected")
+	}
+}
+
+// deadLetterDB keeps the dead letters the worker stores
+type deadLetterDB struct {
+	db.DB
+	letters []*model.DeadLetters
+}
+
+func (d *deadLetterDB) CreateDeadLetter(ctx context.Context, letter *model.DeadLetters) error {
+	d.letters = append(d.letters, letter)
+	return nil
+}
+
+func TestInvalidEnvelopeTask(t *testing.T) {
+	database := &deadLetterDB{}
+	w := &worker.Worker{
+		Db:           database,
+		Logger:       zap.NewNop().Sugar(),
+		WorkerConfig: &machineryConf.Config{AMQP: &machineryConf.AMQPConfig{}},
+	}
+	calls := 0
+	task := w.WithDeadLetter(worker.EnvelopeTask(func(ctx context.Context, userConfig, accId, eventType string, data []byte) error {
+		calls++
+		return nil
+	})).(func(context.Context, []byte) error)
+
+	//The last attempt of the task, machinery retried it already
+	lastAttempt := func(envelopeBytes []byte) context.Context {
+		signature := &tasks.Signature{Name: worker.GetEnvelopeTaskName(contract.EMAIL), RetryCount: 0,
+			Args: []tasks.Arg{{Name: "envelope", Type: "[]byte", Value: envelopeBytes}}}
+		sigTask, err := tasks.NewWithSignature(task, signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sigTask.Context
+	}
+
+	corrupt := []byte("not an envelope")
+	err := task(lastAttempt(corrupt), corrupt)
+	var retriable tasks.Retriable
+	if err == nil || errors.As(err, &retriable) {
+		t.Fatalf("expected a corrupt envelope to fail without being requeued got %v", err)
+	}
+	if len(database.letters) != 1 || database.letters[0].TaskName != worker.GetEnvelopeTaskName(contract.EMAIL) {
+		t.Fatalf("expected the corrupt envelope to be dead-lettered got %v", database.letters)
+	}
+
+	noEvent, _ := proto.Marshal(&pb.TaskEnvelope{Version: contract.TASK_ENVELOPE_VERSION})
+	if err := task(lastAttempt(noEvent), noEvent); err == nil || errors.As(err, &retriable) || len(database.letters) != 2 {
+		t.Errorf("expected an envelope without event to be dead-lettered got %v", err)
+	}
+
+	//A slave that isn't upgraded yet requeues the task for the upgraded ones
+	newer, _ := proto.Marshal(&pb.TaskEnvelope{Version: contract.TASK_ENVELOPE_VERSION + 1, Event: &pb.TaskEvent{}})
+	err = task(lastAttempt(newer), newer)
+	if !errors.As(err, &retriable) || retriable.RetryIn() < time.Second {
+		t.Fatalf("expected a newer envelope to be requeued with a delay got %v", err)
+	}
+	if len(database.letters) != 2 || calls != 0 {
+		t.Errorf("expected a newer envelope not to be dead-lettered or delivered got %v letters", len(database.letters))
+	}
+}
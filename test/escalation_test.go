@@ -0,0 +1,118 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/template"
+	"github.com/devshahriar/notification-manager/worker"
+	"go.uber.org/zap"
+)
+
+func TestGetAckUrl(t *testing.T) {
+	url := worker.GetAckUrl("https://nt.tradersconnect.com/", "3f2ce7f0")
+	if url != "https://nt.tradersconnect.com/escalations/3f2ce7f0/ack" {
+		t.Errorf("unexpected ack url %v", url)
+	}
+}
+
+func TestAddAckUrl(t *testing.T) {
+	dataBytes := worker.AddAckUrl([]byte(`{"ACCOUNT_NUMBER":"123432"}`), "http://localhost:9032/escalations/1/ack")
+
+	var data map[string]string
+	_ = json.Unmarshal(dataBytes, &data)
+	if data["ACCOUNT_NUMBER"] != "123432" || data[contract.ACK_URL] != "http://localhost:9032/escalations/1/ack" {
+		t.Errorf("unexpected data after adding ack url %v", data)
+	}
+}
+
+func TestIngestAckUrl(t *testing.T) {
+	temp := "Your account %ACCOUNT_NUMBER% lost connection. Acknowledge here %ACK_URL%"
+	data := map[string]string{
+		"ACCOUNT_NUMBER": "123432",
+		contract.ACK_URL: "http://localhost:9032/escalations/1/ack",
+	}
+	body := template.IngestDataIntoMsgBody("shahriar", "ACCOUNT_CONNECTION_ERROR", temp, data, contract.EMAIL)
+	if body != "Your account 123432 lost connection. Acknowledge here http://localhost:9032/escalations/1/ack" {
+		t.Errorf("unexpected body %v", body)
+	}
+
+	body = template.IngestAckUrl("Your account lost connection", "", contract.EMAIL)
+	if body != "Your account lost connection" {
+		t.Errorf("expected body without ack url got %v", body)
+	}
+
+	url := "http://localhost:9032/escalations/1/ack"
+	if body := template.IngestAckUrl("Your account lost connection", url, contract.EMAIL); body != "Your account lost connection<br>Acknowledge: "+url {
+		t.Errorf("expected the ack url on a new html line got %v", body)
+	}
+	for _, notificationType := range []string{contract.TELEGRAM, contract.DISCORD} {
+		if body := template.IngestAckUrl("Your account lost connection", url, notificationType); body != "Your account lost connection\nAcknowledge: "+url {
+			t.Errorf("expected the ack url on a new line for %v got %v", notificationType, body)
+		}
+	}
+}
+
+func TestGetEscalationETA(t *testing.T) {
+	now := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	eta := worker.GetEscalationETA(now, 15)
+	if !eta.Equal(now.Add(15 * time.Minute)) {
+		t.Errorf("unexpected eta %v", eta)
+	}
+}
+
+type escalationDB struct {
+	db.DB
+	configs  []model.NotificationConfig
+	disabled map[string]bool
+}
+
+func (d *escalationDB) GetUserIdByConfigId(ctx context.Context, userConfigId string) (string, error) {
+	return "user", nil
+}
+
+func (d *escalationDB) GetEventNotificationConfigs(ctx context.Context, userConfigId string, eventType string) ([]model.NotificationConfig, error) {
+	return d.configs, nil
+}
+
+func (d *escalationDB) IsAccountNotificationDisabled(ctx context.Context, accountConfigId, notificationConfigId string) (bool, error) {
+	return d.disabled[notificationConfigId], nil
+}
+
+func (d *escalationDB) GetIntegrationStatus(ctx context.Context, req *nm.IntegrationStatusReq) (*nm.IntegrationStatusReply, error) {
+	return &nm.IntegrationStatusReply{EmailEnabled: true, TelegramEnabled: true, DiscordEnabled: true}, nil
+}
+
+func TestShouldEscalate(t *testing.T) {
+	database := &escalationDB{
+		configs: []model.NotificationConfig{
+			{ID: 1, EventType: "ACCOUNT_CONNECTION_ERROR", NotificationType: "EMAIL", Enabled: true},
+			{ID: 2, EventType: "ACCOUNT_CONNECTION_ERROR", NotificationType: "TELEGRAM", Enabled: true},
+			{ID: 3, EventType: "ACCOUNT_CONNECTION_ERROR", NotificationType: "DISCORD", Enabled: false},
+		},
+		disabled: map[string]bool{"2": true},
+	}
+	router := &worker.NotificationRouter{Worker: &worker.Worker{Db: database, Logger: zap.NewNop().Sugar()}}
+	escalation := &model.Escalations{NotificationConfigId: 1, UserConfig: 7, EventType: "ACCOUNT_CONNECTION_ERROR"}
+
+	if !router.ShouldEscalate(context.Background(), escalation, "EMAIL") {
+		t.Errorf("expected the escalated config to be sent")
+	}
+	if router.ShouldEscalate(context.Background(), escalation, "TELEGRAM") {
+		t.Errorf("expected the rules of the telegram config to stop the step")
+	}
+	if router.ShouldEscalate(context.Background(), escalation, "DISCORD") {
+		t.Errorf("expected the disabled discord config to stop the step")
+	}
+
+	database.disabled = map[string]bool{"1": true}
+	if !router.ShouldEscalate(context.Background(), escalation, "TELEGRAM") {
+		t.Errorf("expected the telegram step not to use the rules of the escalated config")
+	}
+}
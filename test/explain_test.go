@@ -0,0 +1,90 @@
+package test
+
+import (
+	"testing"
+
+	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/worker"
+)
+
+func GetTestRouteInput() worker.RouteInput {
+	return worker.RouteInput{
+		Configs: []model.NotificationConfig{
+			{ID: 1, NotificationType: contract.EMAIL, Enabled: true},
+			{ID: 2, NotificationType: contract.TELEGRAM, Enabled: true},
+			{ID: 3, NotificationType: contract.DISCORD, Enabled: false},
+		},
+		Blocked:     map[uint64]bool{},
+		Integration: &nm.IntegrationStatusReply{EmailEnabled: true, TelegramEnabled: true, DiscordEnabled: true},
+		Workers:     map[string]bool{contract.EMAIL: true, contract.TELEGRAM: true, contract.DISCORD: true},
+		Bots: map[uint64][]model.BotRouteCandidate{
+			2: {
+				{BotConfigId: 10, BotName: "alerts", BotEnabled: true, ChannelName: "main", ChannelId: "-100", ChannelEnabled: true},
+				{BotConfigId: 10, BotName: "alerts", BotEnabled: true, ChannelName: "muted", ChannelId: "-200", ChannelEnabled: false},
+				{BotConfigId: 11, BotName: "old", BotEnabled: false, ChannelName: "main", ChannelId: "-100", ChannelEnabled: true},
+			},
+		},
+	}
+}
+
+func GetReasons(input worker.RouteInput) []string {
+	reasons := []string{}
+	for _, v := range worker.GetRouteCandidates(input) {
+		reasons = append(reasons, v.Reason)
+	}
+	return reasons
+}
+
+func AssertReasons(t *testing.T, name string, got, expected []string) {
+	if len(got) != len(expected) {
+		t.Errorf("%v: expected %v got %v", name, expected, got)
+		return
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("%v: expected %v got %v", name, expected, got)
+			return
+		}
+	}
+}
+
+func TestExplainRoute(t *testing.T) {
+	input := GetTestRouteInput()
+	AssertReasons(t, "default", GetReasons(input), []string{
+		contract.ROUTE_SEND, contract.ROUTE_SEND, contract.ROUTE_CHANNEL_BLOCKED, contract.ROUTE_BOT_DISABLED, contract.ROUTE_NO_BOT,
+	})
+
+	input = GetTestRouteInput()
+	input.Blocked[1] = true
+	input.Integration.TelegramEnabled = false
+	input.Configs[2].Enabled = true
+	delete(input.Workers, contract.DISCORD)
+	AssertReasons(t, "blocked", GetReasons(input), []string{
+		contract.ROUTE_ACCOUNT_BLOCKED,
+		contract.ROUTE_INTEGRATION_DISABLED, contract.ROUTE_INTEGRATION_DISABLED, contract.ROUTE_INTEGRATION_DISABLED,
+		contract.ROUTE_NO_BOT,
+	})
+}
+
+func TestExplainRouteFilterAndFallback(t *testing.T) {
+	input := GetTestRouteInput()
+	input.Configs[0].FilterExpression = `COPIER_MASTER_SYMBOL == "XAUUSD"`
+	input.Configs = append(input.Configs, model.NotificationConfig{ID: 4, NotificationType: contract.EMAIL, Enabled: true})
+	input.Data = map[string]string{"COPIER_MASTER_SYMBOL": "EURUSD"}
+	input.Chain = []model.FallbackRules{{NotificationType: contract.TELEGRAM}, {NotificationType: contract.EMAIL}}
+
+	AssertReasons(t, "filter and fallback", GetReasons(input), []string{
+		contract.ROUTE_FILTERED,
+		contract.ROUTE_SEND, contract.ROUTE_CHANNEL_BLOCKED, contract.ROUTE_BOT_DISABLED,
+		contract.ROUTE_NO_BOT,
+		contract.ROUTE_FALLBACK_STANDBY,
+	})
+
+	input.Configs[1].Enabled = false
+	reasons := GetReasons(input)
+	if reasons[len(reasons)-1] != contract.ROUTE_SEND {
+		t.Errorf("expected email to be the primary once telegram is disabled got %v", reasons)
+	}
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/worker"
+)
+
+func GetTestFallbackChain() []model.FallbackRules {
+	return []model.FallbackRules{
+		{NotificationType: contract.TELEGRAM, Priority: 0, Enabled: true},
+		{NotificationType: contract.DISCORD, Priority: 1, Enabled: true},
+		{NotificationType: contract.EMAIL, Priority: 2, Enabled: true},
+	}
+}
+
+func TestGetPrimaryNotificationType(t *testing.T) {
+	chain := GetTestFallbackChain()
+
+	primary := worker.GetPrimaryNotificationType(chain, map[string]bool{contract.DISCORD: true, contract.EMAIL: true})
+	if primary != contract.DISCORD {
+		t.Errorf("expected %v as primary got %v", contract.DISCORD, primary)
+	}
+
+	primary = worker.GetPrimaryNotificationType(chain, map[string]bool{})
+	if primary != "" {
+		t.Errorf("expected no primary got %v", primary)
+	}
+}
+
+func TestGetNextFallbackRules(t *testing.T) {
+	chain := GetTestFallbackChain()
+
+	next := worker.GetNextFallbackRules(chain, contract.TELEGRAM)
+	if len(next) != 2 || next[0].NotificationType != contract.DISCORD || next[1].NotificationType != contract.EMAIL {
+		t.Errorf("unexpected fallback rules after telegram %+v", next)
+	}
+
+	next = worker.GetNextFallbackRules(chain, contract.EMAIL)
+	if len(next) != 0 {
+		t.Errorf("expected chain to be exhausted after email got %+v", next)
+	}
+
+	next = worker.GetNextFallbackRules(chain, "slack")
+	if len(next) != 0 {
+		t.Errorf("expected no fallback for notification type outside the chain got %+v", next)
+	}
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/worker"
+)
+
+func TestFanOutTargets(t *testing.T) {
+	parent := worker.NewEnvelope("user-1", "acc-1", "trade_failed", nil)
+	envelope := worker.GetDeliveryEnvelope(parent, contract.TELEGRAM, "trade_failed", "42", "acc-1", []byte(`{"symbol":"EURUSD"}`))
+
+	signature := &tasks.Signature{Headers: tasks.Headers{contract.HEADER_FILTERED_BOTS: "2"}}
+	task, err := tasks.NewWithSignature(func(ctx context.Context) error { return nil }, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ntMeta := []model.BotNotificationMeta{{BotConfigId: 1, ChannelId: "-100"}, {BotConfigId: 2, ChannelId: "-200"}, {BotConfigId: 1, ChannelId: "-300"}}
+	targets := worker.GetBotTargets(task.Context, ntMeta)
+	if len(targets) != 2 || targets[0].Destination != "-100" || targets[1].Destination != "-300" {
+		t.Fatalf("expected a target per channel of the bots the master didn't filter got %v", targets)
+	}
+
+	child := worker.GetTargetEnvelope(envelope, targets[1])
+	if child.Target != targets[1] || child.IdempotencyKey != envelope.IdempotencyKey+":1:-300" || child.NotificationId != envelope.NotificationId || envelope.Target != nil {
+		t.Errorf("unexpected child envelope %+v", child)
+	}
+	if bots := worker.GetTargetBots(ntMeta, child.Target); len(bots) != 1 || bots[0].ChannelId != "-300" {
+		t.Errorf("expected the child to only send to its channel got %+v", bots)
+	}
+	if bots := worker.GetTargetBots(ntMeta, &pb.TaskTarget{BotConfigId: 1, Destination: "-200"}); len(bots) != 0 {
+		t.Errorf("expected no channel for a target that was removed got %+v", bots)
+	}
+
+	emails := worker.GetEmailTargets([]string{"a@example.com", "b@example.com"})
+	if len(emails) != 2 || worker.GetTargetDestination(emails[1]) != "b@example.com" {
+		t.Errorf("unexpected email targets %v", emails)
+	}
+
+	//Tasks without an envelope, children and single destinations are delivered as they are
+	w := &worker.Worker{}
+	ctx := worker.WithEnvelope(task.Context, child)
+	if w.FanOut(task.Context, contract.TELEGRAM, targets) || w.FanOut(ctx, contract.TELEGRAM, targets) ||
+		w.FanOut(worker.WithEnvelope(task.Context, envelope), contract.TELEGRAM, targets[:1]) {
+		t.Error("expected the task not to be fanned out")
+	}
+	if worker.GetTarget(ctx) != child.Target || worker.GetTarget(task.Context) != nil {
+		t.Error("expected the target of the child only")
+	}
+
+	//Without a fallback chain every destination decides on its own
+	if !w.FinishFanOut(ctx, contract.TELEGRAM, false) || w.FinishFanOut(ctx, contract.TELEGRAM, true) {
+		t.Error("expected the destination to decide on its own")
+	}
+}
+
+func TestFanOutStatus(t *testing.T) {
+	now := time.Now()
+	transitions := []model.NotificationTransitions{
+		{NotificationId: "n-1", NotificationType: contract.TELEGRAM, State: contract.NOTIFICATION_ROUTED, CreatedAt: now},
+		{NotificationId: "n-1", NotificationType: contract.TELEGRAM, State: contract.NOTIFICATION_SENT, CreatedAt: now},
+		{NotificationId: "n-1", NotificationType: contract.TELEGRAM, State: contract.NOTIFICATION_FAILED, Reason: "chat not found", CreatedAt: now},
+	}
+
+	status := worker.GetNotificationStatus("n-1", transitions, nil)
+	if len(status.Destinations) != 1 || status.Destinations[0].State != contract.NOTIFICATION_SENT || len(status.Destinations[0].Transitions) != 3 {
+		t.Errorf("expected the channel to stay sent when another destination failed got %+v", status.Destinations)
+	}
+	if status.State != contract.NOTIFICATION_SENT {
+		t.Errorf("expected %v got %v", contract.NOTIFICATION_SENT, status.State)
+	}
+}
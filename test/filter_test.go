@@ -0,0 +1,213 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Traders-Connect/utils/mysql"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/filter"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/server"
+	"github.com/devshahriar/notification-manager/template"
+	"github.com/devshahriar/notification-manager/worker"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+func TestFilterMatch(t *testing.T) {
+	data := map[string]string{
+		"COPIER_MASTER":        "1234",
+		"COPIER_MASTER_SYMBOL": "XAUUSD",
+		"COPIER_ERROR":         "connection timeout",
+	}
+
+	cases := []struct {
+		expr  string
+		match bool
+	}{
+		{``, true},
+		{`COPIER_MASTER_SYMBOL == "XAUUSD"`, true},
+		{`COPIER_MASTER_SYMBOL != "XAUUSD"`, false},
+		{`COPIER_MASTER == 1234`, true},
+		{`COPIER_MASTER in ("5678", '1234')`, true},
+		{`COPIER_MASTER > 2000`, false},
+		{`COPIER_ERROR contains "timeout" && COPIER_MASTER_SYMBOL == "EURUSD"`, false},
+		{`COPIER_ERROR contains "timeout" || COPIER_MASTER_SYMBOL == "EURUSD"`, true},
+		{`!(COPIER_MASTER_SYMBOL == "XAUUSD")`, false},
+		{`COPIER_SLAVE == ""`, true},
+	}
+
+	for _, c := range cases {
+		match, err := filter.Match(c.expr, data)
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", c.expr, err)
+			continue
+		}
+		if match != c.match {
+			t.Errorf("expected %v for %v got %v", c.match, c.expr, match)
+		}
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	keys := template.GetEventKeys("TRADE_COPIED_SUCCESSFULLY")
+
+	if err := filter.Validate(`COPIER_MASTER_SYMBOL == "XAUUSD"`, keys); err != nil {
+		t.Errorf("expected valid expression got %v", err)
+	}
+
+	invalid := []string{
+		`COPIER_MASTER_SYMBOL == XAUUSD`,
+		`COPIER_MASTER_SYMBOL ==`,
+		`(COPIER_MASTER == "1"`,
+		`COPIER_MASTER in ("1" "2")`,
+		`SYMBOL == "XAUUSD"`,
+		`COPIER_MASTER = "1"`,
+	}
+	for _, expr := range invalid {
+		if err := filter.Validate(expr, keys); err == nil {
+			t.Errorf("expected %v to be invalid", expr)
+		}
+	}
+}
+
+type filterDB struct {
+	db.DB
+	configs   map[uint64]string
+	botEvents map[uint64]string
+}
+
+// The fake user config 1 owns the notification config 3 and the bot 2
+func (d *filterDB) GetUserConfigId(ctx context.Context, userId string) (*uint64, error) {
+	if userId == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	userConfigId := uint64(1)
+	if userId != "user" {
+		userConfigId = 9
+	}
+	return &userConfigId, nil
+}
+
+func (d *filterDB) ValidateFilterExpression(ctx context.Context, notificationConfigId uint64, expr string) error {
+	return filter.Validate(expr, template.GetEventKeys("TRADE_COPIED_SUCCESSFULLY"))
+}
+
+func (d *filterDB) EditConfigFilter(ctx context.Context, notificationConfigId, userConfig uint64, expr string) error {
+	if userConfig != 1 || notificationConfigId != 3 {
+		return gorm.ErrRecordNotFound
+	}
+	d.configs[notificationConfigId] = expr
+	return nil
+}
+
+func (d *filterDB) EditBotEventFilter(ctx context.Context, userConfig, botConfigId, notificationConfigId uint64, expr string) error {
+	if userConfig != 1 || botConfigId != 2 {
+		return gorm.ErrRecordNotFound
+	}
+	d.botEvents[botConfigId] = expr
+	return nil
+}
+
+func (d *filterDB) GetConfigFilter(ctx context.Context, userConfig, notificationConfigId uint64) (string, error) {
+	if userConfig != 1 || notificationConfigId != 3 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return d.configs[notificationConfigId], nil
+}
+
+func (d *filterDB) GetBotEventFilter(ctx context.Context, userConfig, botConfigId, notificationConfigId uint64) (string, error) {
+	if userConfig != 1 || botConfigId != 2 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return d.botEvents[botConfigId], nil
+}
+
+func TestFilterExpressionRpc(t *testing.T) {
+	database := &filterDB{configs: map[uint64]string{}, botEvents: map[uint64]string{}}
+	n := &server.NotificationService{Db: database, Logger: zap.NewNop().Sugar()}
+	ctx := context.Background()
+
+	_, err := n.SetFilterExpression(ctx, &pb.FilterExpression{UserId: "user", NotificationConfigId: 3, FilterExpression: `COPIER_MASTER_SYMBOL == "XAUUSD"`})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, err = n.SetFilterExpression(ctx, &pb.FilterExpression{UserId: "user", NotificationConfigId: 3, BotConfigId: 2, FilterExpression: `COPIER_MASTER in ("1234")`})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	reply, err := n.GetFilterExpression(ctx, &pb.GetFilterExpressionReq{UserId: "user", NotificationConfigId: 3})
+	if err != nil || reply.FilterExpression != `COPIER_MASTER_SYMBOL == "XAUUSD"` {
+		t.Errorf("expected the config filter, got %+v %v", reply, err)
+	}
+	reply, err = n.GetFilterExpression(ctx, &pb.GetFilterExpressionReq{UserId: "user", NotificationConfigId: 3, BotConfigId: 2})
+	if err != nil || reply.FilterExpression != `COPIER_MASTER in ("1234")` {
+		t.Errorf("expected the bot event filter, got %+v %v", reply, err)
+	}
+
+	_, err = n.SetFilterExpression(ctx, &pb.FilterExpression{UserId: "user", NotificationConfigId: 3, FilterExpression: `SYMBOL == "XAUUSD"`})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an unknown key, got %v", err)
+	}
+	if database.configs[3] != `COPIER_MASTER_SYMBOL == "XAUUSD"` {
+		t.Errorf("expected the invalid filter not to be saved, got %v", database.configs[3])
+	}
+
+	_, err = n.SetFilterExpression(ctx, &pb.FilterExpression{UserId: "other", NotificationConfigId: 3, FilterExpression: ""})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for the config of another user, got %v", err)
+	}
+	_, err = n.SetFilterExpression(ctx, &pb.FilterExpression{UserId: "other", NotificationConfigId: 3, BotConfigId: 2, FilterExpression: ""})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for the bot of another user, got %v", err)
+	}
+	_, err = n.GetFilterExpression(ctx, &pb.GetFilterExpressionReq{UserId: "other", NotificationConfigId: 3})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound reading the config of another user, got %v", err)
+	}
+	_, err = n.GetFilterExpression(ctx, &pb.GetFilterExpressionReq{NotificationConfigId: 3})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound without a user, got %v", err)
+	}
+	if database.configs[3] != `COPIER_MASTER_SYMBOL == "XAUUSD"` || database.botEvents[2] != `COPIER_MASTER in ("1234")` {
+		t.Errorf("expected the filters of other users not to change, got %+v %+v", database.configs, database.botEvents)
+	}
+}
+
+func TestFilterRoutingPlan(t *testing.T) {
+	plan := model.RoutingPlan{
+		Configs: []model.NotificationConfig{
+			{ID: 1, NotificationType: contract.EMAIL, FilterExpression: `COPIER_MASTER_SYMBOL == "XAUUSD"`},
+			{ID: 2, NotificationType: contract.TELEGRAM, FilterExpression: `(COPIER_MASTER == "1"`},
+		},
+		BotFilters: map[uint64][]model.BotEventsRules{
+			2: {
+				{BotConfigId: 5, NotificationConfigId: 2, FilterExpression: `COPIER_MASTER in ("1234")`},
+				{BotConfigId: 6, NotificationConfigId: 2, FilterExpression: `COPIER_MASTER in ("9")`},
+			},
+		},
+	}
+	database := &db.Mysql{InstrumentedMysql: &mysql.InstrumentedMysql{Log: zap.NewNop().Sugar()}}
+	database.ParseFilters(&plan)
+
+	if len(plan.Filters) != 4 || plan.Filters[`(COPIER_MASTER == "1"`] != nil {
+		t.Fatalf("expected every expression parsed once and the invalid one nil, got %+v", plan.Filters)
+	}
+
+	router := &worker.NotificationRouter{Worker: &worker.Worker{Logger: zap.NewNop().Sugar()}}
+	data := map[string]string{"COPIER_MASTER": "1234", "COPIER_MASTER_SYMBOL": "EURUSD"}
+
+	if _, ok := router.FilterNotification(plan, plan.Configs[0], data); ok {
+		t.Errorf("expected the email config to be filtered out")
+	}
+	headers, ok := router.FilterNotification(plan, plan.Configs[1], data)
+	if !ok || headers[contract.HEADER_FILTERED_BOTS] != "6" {
+		t.Errorf("expected the invalid filter to be ignored and bot 6 filtered out, got %v %+v", ok, headers)
+	}
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/worker"
+)
+
+var (
+	connected    = nm.EventType_ACCOUNT_CONNECTED.String()
+	disconnected = nm.EventType_ACCOUNT_CONNECTION_ERROR.String()
+)
+
+func GetTestFlapSettings() model.FlapSettings {
+	return model.FlapSettings{Enabled: true, GracePeriodSeconds: 60, FlapThreshold: 4, FlapWindowSeconds: 600}
+}
+
+func TestFlapReconnectWithinGracePeriod(t *testing.T) {
+	state := &model.ConnectionStates{}
+	settings := GetTestFlapSettings()
+	now := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	if d := worker.EvaluateFlap(state, settings, connected, now, "1"); d != worker.FLAP_DISPATCH {
+		t.Errorf("expected first connected to be dispatched got %v", d)
+	}
+	if d := worker.EvaluateFlap(state, settings, disconnected, now.Add(time.Second), "2"); d != worker.FLAP_HOLD || state.PendingDisconnectId != "2" {
+		t.Errorf("expected disconnect to be held got %v pending:%v", d, state.PendingDisconnectId)
+	}
+	if d := worker.EvaluateFlap(state, settings, disconnected, now.Add(2*time.Second), "3"); d != worker.FLAP_SUPPRESS || state.PendingDisconnectId != "2" {
+		t.Errorf("expected repeated disconnect to be suppressed got %v pending:%v", d, state.PendingDisconnectId)
+	}
+	if d := worker.EvaluateFlap(state, settings, connected, now.Add(10*time.Second), "4"); d != worker.FLAP_SUPPRESS || state.PendingDisconnectId != "" {
+		t.Errorf("expected reconnect to cancel the held disconnect got %v pending:%v", d, state.PendingDisconnectId)
+	}
+}
+
+func TestFlapSummary(t *testing.T) {
+	state := &model.ConnectionStates{State: "CONNECTED"}
+	settings := GetTestFlapSettings()
+	now := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	events := []string{disconnected, connected, disconnected, connected, disconnected, connected}
+	expected := []worker.FlapDecision{worker.FLAP_HOLD, worker.FLAP_SUPPRESS, worker.FLAP_HOLD, worker.FLAP_SUMMARY, worker.FLAP_SUPPRESS, worker.FLAP_SUPPRESS}
+
+	for i, e := range events {
+		d := worker.EvaluateFlap(state, settings, e, now.Add(time.Duration(i)*5*time.Second), "id")
+		if d != expected[i] {
+			t.Errorf("event %v: expected %v got %v", i, expected[i], d)
+		}
+	}
+
+	//Window expired so the account is treated as stable again
+	if d := worker.EvaluateFlap(state, settings, disconnected, now.Add(time.Hour), "last"); d != worker.FLAP_HOLD {
+		t.Errorf("expected disconnect to be held after the flap window got %v", d)
+	}
+}
+
+func TestFlapWithoutGracePeriod(t *testing.T) {
+	state := &model.ConnectionStates{State: "CONNECTED"}
+	settings := GetTestFlapSettings()
+	settings.GracePeriodSeconds = 0
+
+	if d := worker.EvaluateFlap(state, settings, disconnected, time.Now(), "1"); d != worker.FLAP_DISPATCH {
+		t.Errorf("expected disconnect to be dispatched without grace period got %v", d)
+	}
+}
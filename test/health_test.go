@@ -0,0 +1,116 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/health"
+	"github.com/devshahriar/notification-manager/server"
+	"github.com/devshahriar/notification-manager/worker"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type brokerCheck struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (b *brokerCheck) set(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = err
+}
+
+func (b *brokerCheck) check(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+type watchServer struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses chan grpc_health_v1.HealthCheckResponse_ServingStatus
+}
+
+func (s *watchServer) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchServer) Send(resp *grpc_health_v1.HealthCheckResponse) error {
+	s.responses <- resp.Status
+	return nil
+}
+
+func TestDependencyHealth(t *testing.T) {
+	broker := &brokerCheck{}
+	checker := health.NewChecker(time.Hour, time.Second, zap.NewNop().Sugar())
+	checker.Add(contract.HEALTH_MYSQL, func(ctx context.Context) error { return nil })
+	checker.Add(contract.HEALTH_BROKER, broker.check)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if status, ok := checker.Status(""); !ok || status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("expected dependencies that weren't checked not to serve got %v", status)
+	}
+	checker.Start(ctx)
+
+	s := &server.NotificationService{Health: checker}
+	resp, err := s.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil || resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("expected the service to serve got %v %v", resp, err)
+	}
+	if _, err := s.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "kafka"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected an unknown service to be not found got %v", err)
+	}
+
+	stream := &watchServer{ctx: ctx, responses: make(chan grpc_health_v1.HealthCheckResponse_ServingStatus, 4)}
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Watch(&grpc_health_v1.HealthCheckRequest{Service: contract.HEALTH_BROKER}, stream)
+	}()
+	if got := <-stream.responses; got != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("expected the current status first got %v", got)
+	}
+
+	//The broker connection is gone
+	broker.set(errors.New("dial tcp: connection refused"))
+	checker.Refresh(ctx)
+	select {
+	case got := <-stream.responses:
+		if got != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("expected the change to be streamed got %v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the change to be streamed")
+	}
+	if status, _ := checker.Status(contract.HEALTH_MYSQL); status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Errorf("expected the database to keep serving got %v", status)
+	}
+
+	w := &worker.Worker{Dependencies: checker}
+	rec := httptest.NewRecorder()
+	checker.ServeReady(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable || w.Health().Status != contract.WORKER_UNAVAILABLE ||
+		w.Health().Dependencies[contract.HEALTH_BROKER] != "dial tcp: connection refused" {
+		t.Errorf("expected the worker not to be ready got %v %+v", rec.Code, w.Health())
+	}
+
+	cancel()
+	if err := <-done; status.Code(err) != codes.Canceled {
+		t.Errorf("expected the stream to end with the client got %v", err)
+	}
+	if len(stream.responses) != 0 {
+		t.Error("expected a status to be streamed once per change")
+	}
+}
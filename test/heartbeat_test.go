@@ -0,0 +1,52 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/worker"
+)
+
+func TestGetWorkerHealth(t *testing.T) {
+	now := time.Now()
+	workerMeta := []contract.WorkerMeta{
+		{Name: "nt-master", WorkerType: contract.MASTER, NotificationType: contract.MASTER},
+		{Name: "nt-email", WorkerType: contract.EMAIL, NotificationType: contract.EMAIL},
+		{Name: "nt-telegram", WorkerType: contract.TELEGRAM, NotificationType: contract.TELEGRAM},
+		{Name: "nt-discord", WorkerType: contract.DISCORD, NotificationType: contract.DISCORD},
+	}
+	heartbeats := []model.WorkerHeartbeats{
+		{InstanceId: "master-1", WorkerName: "nt-master", Version: "v1.2.0", Capacity: 10, LastSeenAt: now.Add(-5 * time.Second)},
+		{InstanceId: "email-1", WorkerName: "nt-email", Version: "v1.2.0", Capacity: 10, LastSeenAt: now.Add(-5 * time.Minute)},
+		{InstanceId: "email-2", WorkerName: "nt-email", Version: "v1.2.0", Capacity: 10, LastSeenAt: now.Add(-10 * time.Second)},
+		{InstanceId: "telegram-1", WorkerName: "nt-telegram", Version: "v1.1.0", Capacity: 5, LastSeenAt: now.Add(-time.Minute)},
+	}
+
+	workers := worker.GetWorkerHealth(workerMeta, heartbeats, now)
+	if len(workers) != len(workerMeta) {
+		t.Fatalf("expected %v workers got %v", len(workerMeta), len(workers))
+	}
+
+	expected := []string{contract.WORKER_HEALTHY, contract.WORKER_HEALTHY, contract.WORKER_UNAVAILABLE, contract.WORKER_UNKNOWN}
+	for i, v := range workers {
+		if v.Name != workerMeta[i].Name {
+			t.Errorf("expected worker %v got %v", workerMeta[i].Name, v.Name)
+		}
+		if v.Status != expected[i] {
+			t.Errorf("worker %v expected %v got %v", v.Name, expected[i], v.Status)
+		}
+	}
+
+	email := workers[1]
+	if len(email.Instances) != 2 || email.Instances[0].Alive || !email.Instances[1].Alive {
+		t.Errorf("expected one stopped and one alive email instance got %+v", email.Instances)
+	}
+	if workers[2].Instances[0].Version != "v1.1.0" || workers[2].Instances[0].Capacity != 5 {
+		t.Errorf("expected version and capacity of the telegram instance got %+v", workers[2].Instances[0])
+	}
+	if len(workers[3].Instances) != 0 {
+		t.Errorf("expected no discord instances got %v", len(workers[3].Instances))
+	}
+}
@@ -0,0 +1,32 @@
+package test
+
+import (
+	"testing"
+
+	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/server"
+)
+
+func TestValidateNotificationEvent(t *testing.T) {
+	cases := []struct {
+		name  string
+		event *pb.NotificationEvent
+		valid bool
+	}{
+		{name: "valid", event: &pb.NotificationEvent{AccountId: "acc", EventType: nm.EventType_TRADE_COPY_FAILURE.String()}, valid: true},
+		{name: "nil", event: nil, valid: false},
+		{name: "missing event type", event: &pb.NotificationEvent{AccountId: "acc"}, valid: false},
+		{name: "unknown event type", event: &pb.NotificationEvent{AccountId: "acc", EventType: "TRADE_EXPLODED"}, valid: false},
+		{name: "missing account", event: &pb.NotificationEvent{EventType: nm.EventType_ACCOUNT_ADDED.String()}, valid: false},
+		{name: "account deleted by user", event: &pb.NotificationEvent{UserId: "user", EventType: nm.EventType_ACCOUNT_DELETED.String()}, valid: true},
+		{name: "account deleted without user", event: &pb.NotificationEvent{AccountId: "acc", EventType: nm.EventType_ACCOUNT_DELETED.String()}, valid: false},
+	}
+
+	for _, c := range cases {
+		err := server.ValidateNotificationEvent(c.event)
+		if (err == nil) != c.valid {
+			t.Errorf("%v: expected valid %v got error %v", c.name, c.valid, err)
+		}
+	}
+}
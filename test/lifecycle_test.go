@@ -0,0 +1,78 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/worker"
+)
+
+func TestGetNotificationStatus(t *testing.T) {
+	now := time.Now()
+	transition := func(notificationType, state, reason string) model.NotificationTransitions {
+		return model.NotificationTransitions{
+			CreatedAt:        now,
+			NotificationId:   "n-1",
+			NotificationType: notificationType,
+			State:            state,
+			Reason:           reason,
+			UserId:           "user-1",
+			AccountId:        "acc-1",
+			EventType:        "ACCOUNT_DISCONNECTED",
+		}
+	}
+	transitions := []model.NotificationTransitions{
+		transition("", contract.NOTIFICATION_ACCEPTED, ""),
+		transition(contract.TELEGRAM, contract.NOTIFICATION_SKIPPED, "filtered out"),
+		transition(contract.EMAIL, contract.NOTIFICATION_ROUTED, ""),
+		transition(contract.DISCORD, contract.NOTIFICATION_ROUTED, ""),
+		transition("", contract.NOTIFICATION_ROUTED, "email,discord"),
+		transition(contract.EMAIL, contract.NOTIFICATION_SENT, "sent to 1 of 1 recipients"),
+	}
+	logs := []model.Logs{{CreatedAt: now, NotificationType: contract.EMAIL, Status: contract.STATUS_SUCCESS}}
+
+	status := worker.GetNotificationStatus("n-1", transitions, logs)
+	if status.UserId != "user-1" || status.AccountId != "acc-1" || status.EventType != "ACCOUNT_DISCONNECTED" {
+		t.Errorf("unexpected notification %+v", status)
+	}
+	if len(status.Transitions) != 2 || status.Reason != "email,discord" {
+		t.Errorf("unexpected notification transitions %+v", status.Transitions)
+	}
+	//Discord is still in flight
+	if status.State != contract.NOTIFICATION_ROUTED {
+		t.Errorf("expected %v got %v", contract.NOTIFICATION_ROUTED, status.State)
+	}
+
+	expected := map[string]string{
+		contract.TELEGRAM: contract.NOTIFICATION_SKIPPED,
+		contract.EMAIL:    contract.NOTIFICATION_SENT,
+		contract.DISCORD:  contract.NOTIFICATION_ROUTED,
+	}
+	if len(status.Destinations) != len(expected) {
+		t.Fatalf("expected %v destinations got %v", len(expected), len(status.Destinations))
+	}
+	for _, v := range status.Destinations {
+		if v.State != expected[v.NotificationType] {
+			t.Errorf("%v expected %v got %v", v.NotificationType, expected[v.NotificationType], v.State)
+		}
+		if v.NotificationType == contract.EMAIL && (len(v.Transitions) != 2 || len(v.Attempts) != 1) {
+			t.Errorf("unexpected email destination %+v", v)
+		}
+	}
+
+	transitions = append(transitions, transition(contract.DISCORD, contract.NOTIFICATION_FAILED, "no recipient"))
+	if state := worker.GetNotificationStatus("n-1", transitions, logs).State; state != contract.NOTIFICATION_SENT {
+		t.Errorf("expected %v got %v", contract.NOTIFICATION_SENT, state)
+	}
+
+	skipped := []model.NotificationTransitions{
+		transition("", contract.NOTIFICATION_ACCEPTED, ""),
+		transition("", contract.NOTIFICATION_SKIPPED, "no user config for the account"),
+	}
+	status = worker.GetNotificationStatus("n-1", skipped, nil)
+	if status.State != contract.NOTIFICATION_SKIPPED || status.Reason != "no user config for the account" || len(status.Destinations) != 0 {
+		t.Errorf("unexpected skipped notification %+v", status)
+	}
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/server"
+)
+
+func TestLogPagination(t *testing.T) {
+	afterId, limit, err := server.GetLogPage(&pb.ListNotificationLogsReq{})
+	if err != nil || afterId != 0 || limit != contract.DEFAULT_LOG_PAGE_SIZE {
+		t.Fatalf("unexpected first page afterId:%v limit:%v err:%v", afterId, limit, err)
+	}
+
+	logs := []model.Logs{
+		{Id: 42, CreatedAt: time.Date(2023, 5, 1, 10, 0, 0, 123000000, time.UTC)},
+		{Id: 40, CreatedAt: time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC)},
+	}
+	if cursor := server.GetLogCursor(logs, 3); cursor != "" {
+		t.Errorf("expected no cursor on the last page got %v", cursor)
+	}
+
+	cursor := server.GetLogCursor(logs, 2)
+	afterId, limit, err = server.GetLogPage(&pb.ListNotificationLogsReq{Cursor: cursor, Limit: 10000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if afterId != 40 || limit != contract.MAX_LOG_PAGE_SIZE {
+		t.Errorf("expected afterId:40 limit:%v got afterId:%v limit:%v", contract.MAX_LOG_PAGE_SIZE, afterId, limit)
+	}
+
+	if _, _, err := server.GetLogPage(&pb.ListNotificationLogsReq{Cursor: "not a cursor"}); err == nil {
+		t.Error("expected invalid cursor to be rejected")
+	}
+}
+
+func TestEncodeLogs(t *testing.T) {
+	logs := []model.Logs{
+		{Id: 7, CreatedAt: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC), UserConfig: "3", EventType: "TRADE_FAILED", NotificationType: contract.EMAIL, Status: contract.STATUS_SUCCESS, ReqMeta: []byte(`{"Subject":"a, b"}`)},
+		{Id: 6, CreatedAt: time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC), UserConfig: "3", EventType: "TRADE_FAILED", NotificationType: contract.TELEGRAM, Status: contract.STATUS_FAILED},
+	}
+
+	data, err := server.EncodeLogs(contract.LOG_EXPORT_CSV, logs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][0] != "id" || rows[1][0] != "7" || rows[1][7] != `{"Subject":"a, b"}` || rows[2][6] != contract.STATUS_FAILED {
+		t.Errorf("unexpected csv %q", rows)
+	}
+
+	data, _ = server.EncodeLogs(contract.LOG_EXPORT_CSV, logs, false)
+	if strings.HasPrefix(string(data), "id,") {
+		t.Error("expected no header on later pages")
+	}
+
+	data, err = server.EncodeLogs(contract.LOG_EXPORT_JSON, logs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a json object per line got %v", lines)
+	}
+	var log pb.NotificationLog
+	if err := json.Unmarshal([]byte(lines[1]), &log); err != nil || log.Id != 6 || log.NotificationType != contract.TELEGRAM {
+		t.Errorf("unexpected json line %v err:%v", lines[1], err)
+	}
+}
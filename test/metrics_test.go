@@ -0,0 +1,133 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/worker"
+	"github.com/mailgun/mailgun-go/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricValue sums the counters, gauges or histogram sample counts of the series of name matching labels
+func metricValue(t *testing.T, name string, labels map[string]string) float64 {
+	value := 0.0
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range family.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if v, ok := labels[l.GetName()]; ok && v != l.GetValue() {
+					continue metrics
+				}
+			}
+			switch {
+			case m.GetCounter() != nil:
+				value += m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				value += m.GetGauge().GetValue()
+			case m.GetHistogram() != nil:
+				value += float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return value
+}
+
+func TestDeliveryMetrics(t *testing.T) {
+	w := &worker.Worker{
+		RetryPolicies: map[string]worker.RetryPolicy{
+			contract.DISCORD: {MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		},
+	}
+	ctx := context.Background()
+	sent := map[string]string{"notification_type": contract.DISCORD, "status": contract.STATUS_SUCCESS}
+	transient := map[string]string{"notification_type": contract.DISCORD, "status": contract.STATUS_FAILED, "error_class": contract.ERROR_TRANSIENT}
+	permanent := map[string]string{"notification_type": contract.DISCORD, "status": contract.STATUS_FAILED, "error_class": contract.ERROR_PERMANENT}
+	latency := map[string]string{"notification_type": contract.DISCORD}
+
+	before := map[string]float64{
+		"sent":      metricValue(t, "notification_manager_deliveries_total", sent),
+		"transient": metricValue(t, "notification_manager_deliveries_total", transient),
+		"permanent": metricValue(t, "notification_manager_deliveries_total", permanent),
+		"latency":   metricValue(t, "notification_manager_provider_latency_seconds", latency),
+	}
+
+	_, _ = w.Deliver(ctx, contract.DISCORD, "-100", nil, func() error { return nil })
+	_, _ = w.Deliver(ctx, contract.DISCORD, "-100", nil, func() error { return &mailgun.UnexpectedResponseError{Actual: 503} })
+	_, _ = w.Deliver(ctx, contract.DISCORD, "-100", nil, func() error { return errors.New("403 Forbidden") })
+
+	//A destination is counted once whatever its retries, each call to the provider is timed
+	if v := metricValue(t, "notification_manager_deliveries_total", sent) - before["sent"]; v != 1 {
+		t.Errorf("expected 1 sent delivery got %v", v)
+	}
+	if v := metricValue(t, "notification_manager_deliveries_total", transient) - before["transient"]; v != 1 {
+		t.Errorf("expected 1 transient failure got %v", v)
+	}
+	if v := metricValue(t, "notification_manager_deliveries_total", permanent) - before["permanent"]; v != 1 {
+		t.Errorf("expected 1 permanent failure got %v", v)
+	}
+	if v := metricValue(t, "notification_manager_provider_latency_seconds", latency) - before["latency"]; v != 4 {
+		t.Errorf("expected 4 provider calls got %v", v)
+	}
+
+	skipped := map[string]string{"reason": "no recipient"}
+	before["skipped"] = metricValue(t, "notification_manager_events_skipped_total", skipped)
+	w.RecordDelivery(ctx, contract.DISCORD, 0, 0, nil)
+	if v := metricValue(t, "notification_manager_events_skipped_total", skipped) - before["skipped"]; v != 1 {
+		t.Errorf("expected the skip to be counted without an envelope got %v", v)
+	}
+}
+
+func TestTaskMetrics(t *testing.T) {
+	inFlight := map[string]string{"task": "task_metrics_test"}
+	var running float64
+	task := worker.WithInFlight("task_metrics_test", func(ctx context.Context, data []byte) error {
+		running = metricValue(t, "notification_manager_tasks_in_flight", inFlight)
+		return nil
+	})
+	fn, ok := task.(func(ctx context.Context, data []byte) error)
+	if !ok {
+		t.Fatalf("expected the wrapper to keep the type of the task got %T", task)
+	}
+	if err := fn(context.Background(), nil); err != nil || running != 1 || metricValue(t, "notification_manager_tasks_in_flight", inFlight) != 0 {
+		t.Errorf("expected the task to be in flight while it runs only got %v", running)
+	}
+
+	eta := time.Now().Add(-time.Second)
+	signature := &tasks.Signature{Name: "task_metrics_test", ETA: &eta}
+	sigTask, err := tasks.NewWithSignature(func(ctx context.Context) error { return nil }, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope := worker.NewEnvelope("user-1", "acc-1", "trade_failed", nil)
+	lag := metricValue(t, "notification_manager_queue_lag_seconds", inFlight)
+	worker.ObserveQueueLag(sigTask.Context, envelope)
+	if v := metricValue(t, "notification_manager_queue_lag_seconds", inFlight) - lag; v != 1 {
+		t.Errorf("expected the lag of the task to be observed got %v", v)
+	}
+
+	renderErrors := map[string]string{"event_type": "NOT_AN_EVENT"}
+	failed := metricValue(t, "notification_manager_template_render_errors_total", renderErrors)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the render of an unknown event to fail the task")
+			}
+		}()
+		worker.RenderMessage(context.Background(), contract.EMAIL, "Jane", "NOT_AN_EVENT", "%FIRST_NAME%", nil)
+	}()
+	if v := metricValue(t, "notification_manager_template_render_errors_total", renderErrors) - failed; v != 1 {
+		t.Errorf("expected the render error to be counted got %v", v)
+	}
+}
@@ -0,0 +1,141 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2"
+	backendsiface "github.com/RichardKnop/machinery/v2/backends/iface"
+	brokersiface "github.com/RichardKnop/machinery/v2/brokers/iface"
+	"github.com/RichardKnop/machinery/v2/config"
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/server"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type publishBroker struct {
+	brokersiface.Broker
+	err       error
+	published []*tasks.Signature
+}
+
+func (b *publishBroker) Publish(ctx context.Context, signature *tasks.Signature) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.published = append(b.published, signature)
+	return nil
+}
+
+type publishBackend struct {
+	backendsiface.Backend
+}
+
+func (b *publishBackend) SetStatePending(signature *tasks.Signature) error {
+	return nil
+}
+
+type publishDB struct {
+	db.DB
+	transitions []model.NotificationTransitions
+	scheduled   map[string]string
+}
+
+func (d *publishDB) CreateScheduledNotification(ctx context.Context, scheduled *model.ScheduledNotifications) error {
+	d.scheduled[scheduled.UuId] = scheduled.Status
+	return nil
+}
+
+func (d *publishDB) MarkScheduledNotificationFailed(ctx context.Context, scheduledId string) error {
+	d.scheduled[scheduledId] = contract.SCHEDULED_FAILED
+	return nil
+}
+
+func (d *publishDB) RecordNotificationTransitions(ctx context.Context, transitions []model.NotificationTransitions) error {
+	d.transitions = append(d.transitions, transitions...)
+	return nil
+}
+
+func newPublishService(brokerErr error) (*server.NotificationService, *publishBroker, *publishDB) {
+	broker := &publishBroker{err: brokerErr}
+	database := &publishDB{scheduled: map[string]string{}}
+	n := &server.NotificationService{
+		Db:              database,
+		MachinaryServer: machinery.NewServerWithBrokerBackendLock(&config.Config{}, broker, &publishBackend{}, nil),
+		Logger:          zap.NewNop().Sugar(),
+	}
+	return n, broker, database
+}
+
+func TestSendNotification(t *testing.T) {
+	event := &pb.NotificationEvent{UserId: "user", AccountId: "account", EventType: "ADD_ACCOUNT"}
+
+	n, broker, database := newPublishService(nil)
+	reply, err := n.SendNotification(context.Background(), &pb.SendNotificationReq{Event: event})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if reply.NotificationId == "" || len(broker.published) != 1 {
+		t.Fatalf("expected the notification to be published with an id, got %q and %d tasks", reply.NotificationId, len(broker.published))
+	}
+	if len(database.transitions) != 1 || database.transitions[0].State != contract.NOTIFICATION_ACCEPTED || database.transitions[0].NotificationId != reply.NotificationId {
+		t.Errorf("expected the notification to be accepted, got %+v", database.transitions)
+	}
+
+	n, _, database = newPublishService(errors.New("connection refused"))
+	_, err = n.SendNotification(context.Background(), &pb.SendNotificationReq{Event: event})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable when publishing fails, got %v", err)
+	}
+	if len(database.transitions) != 1 || database.transitions[0].State != contract.NOTIFICATION_FAILED {
+		t.Errorf("expected the notification to be failed, got %+v", database.transitions)
+	}
+
+	_, err = n.SendNotification(context.Background(), &pb.SendNotificationReq{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without an event, got %v", err)
+	}
+}
+
+func TestScheduleNotification(t *testing.T) {
+	event := &pb.NotificationEvent{UserId: "user", AccountId: "account", EventType: "ADD_ACCOUNT"}
+
+	n, broker, database := newPublishService(nil)
+	reply, err := n.SendNotification(context.Background(), &pb.SendNotificationReq{Event: event, DelaySeconds: 60})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if reply.ScheduledNotificationId == "" || database.scheduled[reply.ScheduledNotificationId] != contract.SCHEDULED_PENDING {
+		t.Fatalf("expected a pending scheduled notification, got %q %+v", reply.ScheduledNotificationId, database.scheduled)
+	}
+	if len(broker.published) != 1 || broker.published[0].ETA == nil {
+		t.Errorf("expected the task to be published with an ETA")
+	}
+
+	n, _, database = newPublishService(errors.New("connection refused"))
+	_, err = n.SendNotification(context.Background(), &pb.SendNotificationReq{Event: event, SendAt: time.Now().Add(time.Hour).Format(time.RFC3339)})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable when publishing fails, got %v", err)
+	}
+	if len(database.scheduled) != 1 {
+		t.Fatalf("expected one scheduled notification, got %+v", database.scheduled)
+	}
+	for id, state := range database.scheduled {
+		if state != contract.SCHEDULED_FAILED {
+			t.Errorf("expected scheduled notification %v to be failed, got %v", id, state)
+		}
+	}
+
+	_, err = n.SendNotification(context.Background(), &pb.SendNotificationReq{Event: event, SendAt: "tomorrow"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an invalid send_at, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/worker"
+	"gorm.io/datatypes"
+)
+
+func TestResendEnvelope(t *testing.T) {
+	parent := worker.NewEnvelope("user-1", "acc-1", "trade_failed", nil)
+	emailLog := model.Logs{
+		Id:               7,
+		UserConfig:       "42",
+		AccountId:        "acc-1",
+		EventType:        "trade_failed",
+		NotificationType: contract.EMAIL,
+		Status:           contract.STATUS_FAILED,
+		ReqMeta:          datatypes.JSON(`{"Subject":"Trade failed","Message":"<p>EURUSD failed</p>","Email":"old@example.com"}`),
+		Payload:          datatypes.JSON(`{"symbol":"EURUSD"}`),
+	}
+
+	envelope, err := worker.GetResendEnvelope(parent, emailLog, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resend := envelope.Resend
+	if resend.LogId != 7 || resend.Destination != "old@example.com" || resend.Message != "<p>EURUSD failed</p>" {
+		t.Errorf("unexpected resend %+v", resend)
+	}
+	if envelope.NotificationId != parent.NotificationId || envelope.Event.UserConfig != "42" || envelope.Destinations[0] != contract.EMAIL {
+		t.Errorf("unexpected resend envelope %+v", envelope)
+	}
+
+	envelope, err = worker.GetResendEnvelope(parent, emailLog, contract.RESEND_TEMPLATE_CURRENT, "new@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Resend.Message != "" || envelope.Resend.Destination != "new@example.com" || string(envelope.Event.Data) != `{"symbol":"EURUSD"}` {
+		t.Errorf("expected the current template to render the logged event got %+v", envelope)
+	}
+
+	compacted := emailLog
+	compacted.ReqMeta = datatypes.JSON(`{"Email":"old@example.com"}`)
+	compacted.Payload = nil
+	if _, err := worker.GetResendEnvelope(parent, compacted, contract.RESEND_TEMPLATE_ORIGINAL, ""); !errors.Is(err, worker.ErrResendNoMessage) {
+		t.Errorf("expected %v got %v", worker.ErrResendNoMessage, err)
+	}
+	if _, err := worker.GetResendEnvelope(parent, compacted, contract.RESEND_TEMPLATE_CURRENT, ""); !errors.Is(err, worker.ErrResendNoPayload) {
+		t.Errorf("expected %v got %v", worker.ErrResendNoPayload, err)
+	}
+	if _, err := worker.GetResendEnvelope(parent, emailLog, "latest", ""); err == nil {
+		t.Error("expected an error for an unknown template")
+	}
+
+	telegramLog := model.Logs{
+		Id:               8,
+		NotificationType: contract.TELEGRAM,
+		ReqMeta:          datatypes.JSON(`{"BotConfigId":3,"ChannelId":"-100123","Message":"EURUSD failed"}`),
+	}
+	envelope, err = worker.GetResendEnvelope(parent, telegramLog, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bots := worker.GetResendBot([]model.BotNotificationMeta{{BotConfigId: 2, ChannelId: "1"}, {BotConfigId: 3, ChannelId: "2"}, {BotConfigId: 3, ChannelId: "3"}}, envelope.Resend)
+	if len(bots) != 1 || bots[0].BotConfigId != 3 || bots[0].ChannelId != "-100123" {
+		t.Errorf("expected a single send through bot 3 to the logged channel got %+v", bots)
+	}
+	if bots := worker.GetResendBot([]model.BotNotificationMeta{{BotConfigId: 2}}, &pb.TaskResend{BotConfigId: 3}); len(bots) != 0 {
+		t.Errorf("expected no channel for a removed bot got %+v", bots)
+	}
+}
+
+func TestLastDeliveries(t *testing.T) {
+	logs := []model.Logs{
+		{Id: 1, NotificationType: contract.TELEGRAM},
+		{Id: 2, NotificationType: contract.EMAIL},
+		{Id: 3, NotificationType: contract.TELEGRAM},
+	}
+
+	last := worker.GetLastDeliveries(logs, "")
+	if len(last) != 2 || last[0].Id != 3 || last[1].Id != 2 {
+		t.Errorf("unexpected last deliveries %+v", last)
+	}
+	if last := worker.GetLastDeliveries(logs, contract.EMAIL); len(last) != 1 || last[0].Id != 2 {
+		t.Errorf("unexpected last email delivery %+v", last)
+	}
+}
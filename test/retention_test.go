@@ -0,0 +1,136 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devshahriar/notification-manager/archive"
+	"github.com/devshahriar/notification-manager/model"
+	"gorm.io/datatypes"
+)
+
+func getArchivedLogs() []model.Logs {
+	return []model.Logs{
+		{Id: 7, CreatedAt: time.Date(2023, 5, 1, 23, 0, 0, 0, time.UTC), UserConfig: "1", Status: "SUCCESS", ReqMeta: datatypes.JSON(`{"Email":"a@b.c"}`)},
+		{Id: 8, CreatedAt: time.Date(2023, 5, 2, 1, 0, 0, 0, time.UTC), UserConfig: "1", Status: "FAILED"},
+	}
+}
+
+func putArchives(t *testing.T, store archive.Store, logs []model.Logs) {
+	for _, day := range archive.SplitByDay(logs) {
+		data, _ := archive.EncodeLogs(day)
+		if err := store.Put(context.Background(), archive.GetArchiveKey(day), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestArchiveEncoding(t *testing.T) {
+	logs := getArchivedLogs()
+
+	days := archive.SplitByDay(logs)
+	if len(days) != 2 || len(days[0]) != 1 || days[1][0].Id != 8 {
+		t.Fatalf("expected a batch per day got %+v", days)
+	}
+	if key := archive.GetArchiveKey(days[1]); key != "logs/2023/05/02/00000000000000000008-00000000000000000008.jsonl.gz" {
+		t.Errorf("unexpected archive key %v", key)
+	}
+
+	data, err := archive.EncodeLogs(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := archive.DecodeLogs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0].Id != 7 || !decoded[1].CreatedAt.Equal(logs[1].CreatedAt) || string(decoded[0].ReqMeta) != `{"Email":"a@b.c"}` {
+		t.Errorf("unexpected decoded logs %+v", decoded)
+	}
+
+	prefixes := archive.GetDayPrefixes(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC), time.Date(2023, 5, 3, 0, 0, 0, 0, time.UTC))
+	if strings.Join(prefixes, ",") != "logs/2023/05/01/,logs/2023/05/02/" {
+		t.Errorf("unexpected day prefixes %v", prefixes)
+	}
+}
+
+func TestDirArchiveRestoreRange(t *testing.T) {
+	ctx := context.Background()
+	store := &archive.DirStore{Dir: t.TempDir()}
+
+	putArchives(t, store, getArchivedLogs())
+
+	restored, err := archive.ReadRange(ctx, store, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != 1 || restored[0].Id != 7 {
+		t.Errorf("expected only the log of the first day got %+v", restored)
+	}
+
+	restored, err = archive.ReadRange(ctx, store, time.Date(2023, 5, 2, 0, 30, 0, 0, time.UTC), time.Date(2023, 5, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(restored) != 1 || restored[0].Id != 8 {
+		t.Errorf("expected only the log of the second day got %+v err:%v", restored, err)
+	}
+
+	if keys, err := store.List(ctx, "logs/2023/06/01/"); err != nil || len(keys) != 0 {
+		t.Errorf("expected no archives of a missing day got %v err:%v", keys, err)
+	}
+}
+
+func TestS3ArchiveStore(t *testing.T) {
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPut:
+			if r.ContentLength <= 0 {
+				w.WriteHeader(http.StatusLengthRequired)
+				return
+			}
+			objects[strings.TrimPrefix(r.URL.Path, "/archive/")], _ = io.ReadAll(r.Body)
+		case r.URL.Query().Get("list-type") == "2":
+			fmt.Fprint(w, "<ListBucketResult>")
+			for key := range objects {
+				if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+					fmt.Fprintf(w, "<Contents><Key>%v</Key></Contents>", key)
+				}
+			}
+			fmt.Fprint(w, "<IsTruncated>false</IsTruncated></ListBucketResult>")
+		default:
+			data, ok := objects[strings.TrimPrefix(r.URL.Path, "/archive/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	store := archive.NewS3Store(srv.URL, "archive", "", "key", "secret")
+
+	putArchives(t, store, getArchivedLogs())
+
+	restored, err := archive.ReadRange(ctx, store, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 5, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != 2 {
+		t.Errorf("expected both logs got %+v", restored)
+	}
+
+	if _, err := store.Get(ctx, "logs/missing"); err == nil {
+		t.Error("expected an error for a missing object")
+	}
+}
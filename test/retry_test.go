@@ -0,0 +1,110 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/secrets"
+	"github.com/devshahriar/notification-manager/worker"
+	"github.com/mailgun/mailgun-go/v4"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyDeliveryErrors(t *testing.T) {
+	token := "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw"
+
+	cases := []struct {
+		err   error
+		class string
+	}{
+		{&mailgun.UnexpectedResponseError{Actual: 503}, contract.ERROR_TRANSIENT},
+		{&mailgun.UnexpectedResponseError{Actual: 429}, contract.ERROR_TRANSIENT},
+		{&mailgun.UnexpectedResponseError{Actual: 400}, contract.ERROR_PERMANENT},
+		{&discordgo.RESTError{Response: &http.Response{StatusCode: 502}}, contract.ERROR_TRANSIENT},
+		{&discordgo.RESTError{Response: &http.Response{StatusCode: 403}}, contract.ERROR_PERMANENT},
+		{discordgo.ErrUnauthorized, contract.ERROR_PERMANENT},
+		{tgbotapi.Error{Message: "Too Many Requests: retry after 5", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 5}}, contract.ERROR_TRANSIENT},
+		{tgbotapi.Error{Message: "Bad Request: chat not found"}, contract.ERROR_PERMANENT},
+		{errors.New("Unauthorized"), contract.ERROR_PERMANENT},
+		{fmt.Errorf("post: %w", timeoutError{}), contract.ERROR_TRANSIENT},
+		{context.DeadlineExceeded, contract.ERROR_TRANSIENT},
+		{secrets.Redact(fmt.Errorf("Post https://api.telegram.org/bot%v/sendMessage: %w", token, timeoutError{}), token), contract.ERROR_TRANSIENT},
+		{errors.New("connection reset by peer"), contract.ERROR_TRANSIENT},
+	}
+	for _, v := range cases {
+		if class := worker.ClassifyError(v.err); class != v.class {
+			t.Errorf("expected %v for %v got %v", v.class, v.err, class)
+		}
+	}
+}
+
+func TestRetryPolicies(t *testing.T) {
+	policies, err := worker.ParseRetryPolicies(contract.DEFAULT_DELIVERY_RETRIES + ",Discord=5:200ms:2s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := policies[contract.DISCORD]; p.MaxAttempts != 5 || p.BaseDelay != 200*time.Millisecond || p.MaxDelay != 2*time.Second {
+		t.Errorf("unexpected discord policy %+v", p)
+	}
+
+	for _, spec := range []string{"email=3:1s", "email=0:1s:2s", "email=3:1x:2s", "email=3:2s:1s"} {
+		if _, err := worker.ParseRetryPolicies(spec); err == nil {
+			t.Errorf("expected an error for %v", spec)
+		}
+	}
+
+	policy := worker.RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	for attempt, max := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 5: 10 * time.Second, 60: 10 * time.Second} {
+		for i := 0; i < 20; i++ {
+			if delay := policy.Backoff(attempt); delay < max/2 || delay > max {
+				t.Fatalf("attempt %v backoff %v outside [%v, %v]", attempt, delay, max/2, max)
+			}
+		}
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	w := &worker.Worker{RetryPolicies: map[string]worker.RetryPolicy{
+		contract.TELEGRAM: {MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond},
+	}}
+	ctx := context.Background()
+
+	calls := 0
+	attempts, err := w.Deliver(ctx, contract.TELEGRAM, "channel 1", nil, func() error {
+		calls++
+		if calls < 3 {
+			return &mailgun.UnexpectedResponseError{Actual: 503}
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("expected success on the third attempt got attempts:%v err:%v", attempts, err)
+	}
+
+	attempts, err = w.Deliver(ctx, contract.TELEGRAM, "channel 1", nil, func() error {
+		return tgbotapi.Error{Message: "Bad Request: chat not found"}
+	})
+	var deliveryErr *worker.DeliveryError
+	if attempts != 1 || !errors.As(err, &deliveryErr) || deliveryErr.Class != contract.ERROR_PERMANENT {
+		t.Errorf("expected a single permanent attempt got attempts:%v err:%v", attempts, err)
+	}
+
+	attempts, err = w.Deliver(ctx, contract.TELEGRAM, "channel 1", nil, func() error {
+		return timeoutError{}
+	})
+	if attempts != 3 || worker.ClassifyError(err) != contract.ERROR_TRANSIENT {
+		t.Errorf("expected the policy to run out after 3 attempts got attempts:%v err:%v", attempts, err)
+	}
+}
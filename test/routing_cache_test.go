@@ -0,0 +1,162 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/cache"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/worker"
+)
+
+func CountingLoader(loads *int, value, tag string) func() (string, string, error) {
+	return func() (string, string, error) {
+		*loads++
+		return value, tag, nil
+	}
+}
+
+func TestRoutingCacheGetOrLoad(t *testing.T) {
+	c := cache.New(time.Minute)
+	loads := 0
+
+	for i := 0; i < 3; i++ {
+		value, err := cache.GetOrLoad(c, "plan:1", CountingLoader(&loads, "plan", "1"))
+		if err != nil || value != "plan" {
+			t.Fatalf("unexpected value %v err %v", value, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("expected a single load got %v", loads)
+	}
+
+	// Values without a tag are not cached
+	cache.GetOrLoad(c, "account:unknown", CountingLoader(&loads, "", ""))
+	cache.GetOrLoad(c, "account:unknown", CountingLoader(&loads, "", ""))
+	if loads != 3 {
+		t.Errorf("expected untagged values to be loaded every time got %v loads", loads)
+	}
+
+	// A nil cache always loads
+	cache.GetOrLoad(nil, "plan:1", CountingLoader(&loads, "plan", "1"))
+	if loads != 4 {
+		t.Errorf("expected nil cache to load got %v loads", loads)
+	}
+}
+
+func TestRoutingCacheInvalidate(t *testing.T) {
+	c := cache.New(time.Minute)
+	loads := 0
+
+	cache.GetOrLoad(c, "plan:1", CountingLoader(&loads, "plan", "1"))
+	cache.GetOrLoad(c, "bot:1", CountingLoader(&loads, "bot", "1"))
+	cache.GetOrLoad(c, "plan:2", CountingLoader(&loads, "plan", "2"))
+
+	c.Invalidate("1")
+	if c.Len() != 1 {
+		t.Errorf("expected only the entry of user config 2 to be left got %v", c.Len())
+	}
+
+	cache.GetOrLoad(c, "plan:1", CountingLoader(&loads, "plan", "1"))
+	cache.GetOrLoad(c, "plan:2", CountingLoader(&loads, "plan", "2"))
+	if loads != 4 {
+		t.Errorf("expected only the invalidated entry to be reloaded got %v loads", loads)
+	}
+
+	c.Flush()
+	if c.Len() != 0 {
+		t.Errorf("expected flush to drop every entry got %v", c.Len())
+	}
+}
+
+func TestRoutingCacheRejectsStaleLoad(t *testing.T) {
+	c := cache.New(time.Minute)
+
+	// Invalidated while loading
+	cache.GetOrLoad(c, "plan:1", func() (string, string, error) {
+		c.Invalidate("1")
+		return "stale", "1", nil
+	})
+	if _, ok := c.Get("plan:1"); ok {
+		t.Error("expected value loaded before the invalidation to be dropped")
+	}
+
+	// Other users are not affected
+	cache.GetOrLoad(c, "plan:2", func() (string, string, error) {
+		c.Invalidate("1")
+		return "fresh", "2", nil
+	})
+	if _, ok := c.Get("plan:2"); !ok {
+		t.Error("expected value of another user config to be cached")
+	}
+
+	// Flushed while loading
+	cache.GetOrLoad(c, "plan:3", func() (string, string, error) {
+		c.Flush()
+		return "stale", "3", nil
+	})
+	if _, ok := c.Get("plan:3"); ok {
+		t.Error("expected value loaded before the flush to be dropped")
+	}
+
+	// Invalidation records dropped by the sweep still reject older loads
+	ttl := 10 * time.Millisecond
+	c = cache.New(ttl)
+	seq := c.Seq()
+	c.Invalidate("1")
+	time.Sleep(2 * ttl)
+	c.Sweep()
+	if c.Set("plan:1", "1", "stale", seq) {
+		t.Error("expected load older than a swept invalidation to be rejected")
+	}
+	if !c.Set("plan:1", "1", "fresh", c.Seq()) {
+		t.Error("expected new load to be cached")
+	}
+}
+
+func TestRoutingCacheExpiry(t *testing.T) {
+	ttl := 10 * time.Millisecond
+	c := cache.New(ttl)
+	loads := 0
+
+	cache.GetOrLoad(c, "plan:1", CountingLoader(&loads, "plan", "1"))
+	time.Sleep(2 * ttl)
+	cache.GetOrLoad(c, "plan:1", CountingLoader(&loads, "plan", "1"))
+	if loads != 2 {
+		t.Errorf("expected expired entry to be reloaded got %v loads", loads)
+	}
+
+	cache.GetOrLoad(c, "plan:2", CountingLoader(&loads, "plan", "2"))
+	time.Sleep(2 * ttl)
+	c.Sweep()
+	if c.Len() != 0 {
+		t.Errorf("expected sweep to drop expired entries got %v", c.Len())
+	}
+}
+
+func TestIsRoutable(t *testing.T) {
+	plan := model.RoutingPlan{
+		Blocked:     map[uint64]bool{2: true},
+		Integration: &nm.IntegrationStatusReply{EmailEnabled: true, TelegramEnabled: true},
+	}
+
+	cases := []struct {
+		config   model.NotificationConfig
+		expected bool
+	}{
+		{model.NotificationConfig{ID: 1, NotificationType: contract.EMAIL}, true},
+		{model.NotificationConfig{ID: 2, NotificationType: contract.TELEGRAM}, false},
+		{model.NotificationConfig{ID: 3, NotificationType: contract.DISCORD}, false},
+	}
+	for _, v := range cases {
+		if got := worker.IsRoutable(plan, v.config); got != v.expected {
+			t.Errorf("notificationConfig:%v expected %v got %v", v.config.ID, v.expected, got)
+		}
+	}
+
+	if worker.IsRoutable(model.RoutingPlan{}, cases[0].config) {
+		t.Error("expected plan without integration status to route nothing")
+	}
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devshahriar/notification-manager/server"
+)
+
+func TestParseSendAt(t *testing.T) {
+	now := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		sendAt  string
+		delay   int32
+		want    *time.Time
+		wantErr bool
+	}{
+		{name: "send now", want: nil},
+		{name: "future time", sendAt: "2023-01-01T12:00:00Z", want: timePtr(now.Add(2 * time.Hour))},
+		{name: "future time with offset", sendAt: "2023-01-01T13:00:00+02:00", want: timePtr(now.Add(time.Hour))},
+		{name: "past time is sent now", sendAt: "2023-01-01T09:00:00Z", want: nil},
+		{name: "delay", delay: 90, want: timePtr(now.Add(90 * time.Second))},
+		{name: "zero delay is sent now", delay: 0, want: nil},
+		{name: "invalid time", sendAt: "tomorrow", wantErr: true},
+		{name: "negative delay", delay: -5, wantErr: true},
+		{name: "both set", sendAt: "2023-01-01T12:00:00Z", delay: 90, wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, err := server.ParseSendAt(c.sendAt, c.delay, now)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%v: expected error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", c.name, err)
+			continue
+		}
+		if (got == nil) != (c.want == nil) || (got != nil && !got.Equal(*c.want)) {
+			t.Errorf("%v: expected %v got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,97 @@
+package test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/devshahriar/notification-manager/secrets"
+)
+
+func getSecretKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestSecretsEnvelopeEncryption(t *testing.T) {
+	ctx := context.Background()
+	token := "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw"
+
+	old, err := secrets.NewCipherFromConfig("k1:"+getSecretKey('a'), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := old.Encrypt(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !secrets.IsEncrypted(encrypted) || strings.Contains(encrypted, token) {
+		t.Fatalf("expected an encrypted token got %v", encrypted)
+	}
+	if again, _ := old.Encrypt(ctx, token); again == encrypted {
+		t.Error("expected a new data key for every value")
+	}
+	if decrypted, err := old.Decrypt(ctx, encrypted); err != nil || decrypted != token {
+		t.Errorf("unexpected decrypted token %v err:%v", decrypted, err)
+	}
+	if old.NeedsRotation(encrypted) || !old.NeedsRotation(token) {
+		t.Error("expected only the plaintext token to need rotation")
+	}
+
+	// k2 becomes primary. k1 stays so existing tokens can be rotated
+	rotated, err := secrets.NewCipherFromConfig("k1:"+getSecretKey('a')+",k2:"+getSecretKey('b'), "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rotated.NeedsRotation(encrypted) {
+		t.Error("expected a token of the old key to need rotation")
+	}
+	if decrypted, err := rotated.Decrypt(ctx, encrypted); err != nil || decrypted != token {
+		t.Errorf("expected the old key to still decrypt got %v err:%v", decrypted, err)
+	}
+
+	withoutOld, _ := secrets.NewCipherFromConfig("k2:"+getSecretKey('b'), "")
+	if _, err := withoutOld.Decrypt(ctx, encrypted); err == nil {
+		t.Error("expected an error without the key of the token")
+	}
+
+	disabled, _ := secrets.NewCipherFromConfig("", "")
+	if plain, _ := disabled.Encrypt(ctx, token); plain != token {
+		t.Errorf("expected plaintext without keys got %v", plain)
+	}
+	if _, err := disabled.Decrypt(ctx, encrypted); err == nil {
+		t.Error("expected an error decrypting without keys")
+	}
+
+	if _, err := secrets.NewCipherFromConfig("k1:short", ""); err == nil {
+		t.Error("expected an error for a key that isn't 32 bytes")
+	}
+	if _, err := secrets.NewCipherFromConfig("k1:"+getSecretKey('a'), "k3"); err == nil {
+		t.Error("expected an error for a missing primary key")
+	}
+}
+
+func TestSecretsRedaction(t *testing.T) {
+	token := "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw"
+
+	if masked := secrets.Mask(token); masked != "****Dsaw" || !secrets.IsMasked(masked) {
+		t.Errorf("unexpected masked token %v", masked)
+	}
+	if masked := secrets.Mask("short"); masked != secrets.MASK {
+		t.Errorf("expected short secrets to be fully masked got %v", masked)
+	}
+
+	err := secrets.Redact(errors.New(`Post "https://api.telegram.org/bot`+token+`/sendMessage": timeout`), token)
+	if strings.Contains(err.Error(), token) {
+		t.Errorf("expected the token to be redacted from %v", err)
+	}
+
+	redacted := string(secrets.RedactJSON([]byte(`{"BotToken":"` + token + `","Message":"hi","Nested":{"ApiKey":"key-0123456789"}}`)))
+	if strings.Contains(redacted, token) || strings.Contains(redacted, "key-0123456789") || !strings.Contains(redacted, `"Message":"hi"`) {
+		t.Errorf("unexpected redacted json %v", redacted)
+	}
+	if data := `{"ChannelId":"42"}`; string(secrets.RedactJSON([]byte(data))) != data {
+		t.Error("expected json without secrets to be unchanged")
+	}
+}
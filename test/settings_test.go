@@ -0,0 +1,131 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	machineryConf "github.com/RichardKnop/machinery/v2/config"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/worker"
+	"go.uber.org/zap"
+)
+
+// settingsDB serves the worker meta an admin changed with SetWorkerSettings
+type settingsDB struct {
+	db.DB
+	mu   sync.Mutex
+	meta contract.WorkerMeta
+}
+
+func (d *settingsDB) set(settings contract.WorkerSettings) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.meta.WorkerSettings = settings
+}
+
+func (d *settingsDB) GetWorkerMeta() ([]contract.WorkerMeta, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return []contract.WorkerMeta{
+		{Name: "nt-master", WorkerType: contract.MASTER, NotificationType: contract.MASTER},
+		d.meta,
+	}, nil
+}
+
+func TestWorkerSettings(t *testing.T) {
+	published := contract.WorkerSettings{Concurrency: 10, PrefetchCount: 150}
+	database := &settingsDB{meta: contract.WorkerMeta{
+		Name: "nt-email", WorkerType: contract.EMAIL, NotificationType: contract.EMAIL,
+		Queue: "email_queue", BindingKey: "email", WorkerSettings: published,
+	}}
+	w := &worker.Worker{
+		Name:       "nt-email",
+		WorkerType: contract.EMAIL,
+		WorkerConfig: &machineryConf.Config{
+			DefaultQueue: "email_queue",
+			AMQP:         &machineryConf.AMQPConfig{BindingKey: "email"},
+		},
+		Settings:        published,
+		Db:              database,
+		Logger:          zap.NewNop().Sugar(),
+		ShutdownTimeout: time.Second,
+	}
+	if w.ProviderTimeout() != contract.DELIVERY_TIMEOUT_SECONDS*time.Second {
+		t.Errorf("expected the default provider timeout got %v", w.ProviderTimeout())
+	}
+
+	consuming := make(chan struct{})
+	consumer := &queueConsumer{queue: []func(ctx context.Context) error{func(ctx context.Context) error {
+		close(consuming)
+		return nil
+	}}}
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Consume(context.Background(), consumer)
+	}()
+	<-consuming
+
+	//Timeouts and rate limits apply to the next calls without consuming again
+	database.set(contract.WorkerSettings{Concurrency: 10, PrefetchCount: 150, ProviderTimeoutSeconds: 5, RateLimit: 20})
+	if err := w.RefreshSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if w.ProviderTimeout() != 5*time.Second {
+		t.Errorf("expected the provider timeout to change got %v", w.ProviderTimeout())
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("expected the worker to keep consuming got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		if _, err := w.Deliver(context.Background(), contract.EMAIL, "jane@example.com", nil, func() error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("expected 30 calls at 20 per second to take half a second got %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := w.Deliver(ctx, contract.EMAIL, "jane@example.com", nil, func() error { return nil }); err == nil {
+		t.Error("expected a delivery waiting for the rate limit to stop with its context")
+	}
+
+	//A new concurrency drains the consumer so the worker consumes again with it
+	database.set(contract.WorkerSettings{Concurrency: 2, PrefetchCount: 4, ProviderTimeoutSeconds: 5})
+	if err := w.RefreshSettings(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, worker.ErrSettingsChanged) {
+			t.Errorf("expected the consumer to stop for the new settings got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the consumer to stop for the new settings")
+	}
+	if settings := w.GetSettings(); settings.Concurrency != 2 || settings.PrefetchCount != 4 || settings.RateLimit != 0 {
+		t.Errorf("expected the settings of the worker meta got %+v", settings)
+	}
+
+	start = time.Now()
+	for i := 0; i < 100; i++ {
+		_, _ = w.Deliver(context.Background(), contract.EMAIL, "jane@example.com", nil, func() error { return nil })
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("expected calls to be unlimited once the rate limit is removed got %v", elapsed)
+	}
+
+	workers := worker.GetWorkerHealth([]contract.WorkerMeta{database.meta}, nil, time.Now())
+	if s := workers[0].Settings; s.Concurrency != 2 || s.PrefetchCount != 4 || s.ProviderTimeoutSeconds != 5 {
+		t.Errorf("expected the workers to be listed with their settings got %+v", s)
+	}
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/devshahriar/notification-manager/worker"
+	"go.uber.org/zap"
+)
+
+// queueConsumer runs its queued tasks one by one like a machinery worker with a concurrency of 1
+type queueConsumer struct {
+	queue []func(ctx context.Context) error
+
+	mu       sync.Mutex
+	started  int
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+	errs     chan<- error
+}
+
+func (c *queueConsumer) LaunchAsync(errorsChan chan<- error) {
+	c.stop = make(chan struct{})
+	c.errs = errorsChan
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for _, task := range c.queue {
+			select {
+			case <-c.stop:
+				return
+			default:
+			}
+			c.mu.Lock()
+			c.started++
+			c.mu.Unlock()
+			_ = task(context.Background())
+		}
+	}()
+}
+
+// Quit stops consuming and waits for the task in flight like the AMQP broker of machinery
+func (c *queueConsumer) Quit() {
+	c.stopOnce.Do(func() { close(c.stop) })
+	c.wg.Wait()
+	c.errs <- nil
+}
+
+func (c *queueConsumer) Started() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.started
+}
+
+func TestShutdownDrainsSend(t *testing.T) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+
+	sending := make(chan struct{})
+	var mu sync.Mutex
+	steps := []string{}
+	step := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, s)
+	}
+
+	send := worker.WithInFlight("task_shutdown_test", func(ctx context.Context) error {
+		close(sending)
+		time.Sleep(100 * time.Millisecond)
+		step("sent")
+		step("logged")
+		return nil
+	}).(func(ctx context.Context) error)
+	next := func(ctx context.Context) error {
+		step("next")
+		return nil
+	}
+
+	consumer := &queueConsumer{queue: []func(ctx context.Context) error{send, next}}
+	w := &worker.Worker{Logger: zap.NewNop().Sugar(), ShutdownTimeout: time.Second}
+
+	go func() {
+		<-sending
+		_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	}()
+	if err := w.Consume(ctx, consumer); err != nil {
+		t.Fatalf("expected a clean shutdown got %v", err)
+	}
+
+	//The send finished and was logged, the next task stayed in the queue to be requeued
+	mu.Lock()
+	defer mu.Unlock()
+	if len(steps) != 2 || steps[0] != "sent" || steps[1] != "logged" || consumer.Started() != 1 {
+		t.Errorf("expected the send in flight to finish and the queue to stop got %v", steps)
+	}
+	if worker.InFlight() != 0 {
+		t.Errorf("expected no task in flight got %v", worker.InFlight())
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+
+	sending := make(chan struct{})
+	release := make(chan struct{})
+	send := worker.WithInFlight("task_shutdown_test", func(ctx context.Context) error {
+		close(sending)
+		<-release
+		return nil
+	}).(func(ctx context.Context) error)
+
+	consumer := &queueConsumer{queue: []func(ctx context.Context) error{send}}
+	w := &worker.Worker{Logger: zap.NewNop().Sugar(), ShutdownTimeout: 50 * time.Millisecond}
+
+	go func() {
+		<-sending
+		_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	}()
+	start := time.Now()
+	err := w.Consume(ctx, consumer)
+	if !errors.Is(err, worker.ErrShutdownTimeout) || time.Since(start) > time.Second {
+		t.Fatalf("expected the worker to give up on the stuck send at the deadline got %v", err)
+	}
+	if worker.InFlight() != 1 {
+		t.Errorf("expected the stuck send to be left to the broker got %v in flight", worker.InFlight())
+	}
+	close(release)
+	consumer.wg.Wait()
+}
@@ -21,5 +21,5 @@ func TestSendTelegramNotification(t *testing.T) {
 		nt.NotificationDataKeys_COPIER_ERROR.String():  "copy error",
 	}
 	dataBytes, _ := json.Marshal(&data)
-	task_send_telegram.SendTelegramNotification("3", "", "TRADE_COPY_FAILURE", dataBytes)
+	task_send_telegram.SendTelegramNotification(ctx, "3", "", "TRADE_COPY_FAILURE", dataBytes)
 }
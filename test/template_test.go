@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/devshahriar/notification-manager/contract"
 	"github.com/devshahriar/notification-manager/template"
 )
 
@@ -13,6 +14,6 @@ func TestTemplateEngine(t *testing.T) {
 		"ACCOUNT_NUMBER": "123432",
 		"ACCOUNT_NAME":   "shahriar",
 	}
-	body := template.IngestDataIntoMsgBody("shahirar", template.EVENT_ACCOUNT_ADDED, temp, data)
+	body := template.IngestDataIntoMsgBody("shahirar", template.EVENT_ACCOUNT_ADDED, temp, data, contract.EMAIL)
 	fmt.Println(body)
 }
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/devshahriar/notification-manager/tracing"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+)
+
+func TestTraceparent(t *testing.T) {
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := tracing.ParseTraceparent(traceparent)
+	if !ok {
+		t.Fatalf("expected %v to parse", traceparent)
+	}
+	if got := tracing.FormatTraceparent(sc); got != traceparent {
+		t.Errorf("expected %v got %v", traceparent, got)
+	}
+
+	for _, v := range []string{"", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "00-zzz-00f067aa0ba902b7-01"} {
+		if _, ok := tracing.ParseTraceparent(v); ok {
+			t.Errorf("expected %q to be rejected", v)
+		}
+	}
+}
+
+func TestStartSpanFromCarrier(t *testing.T) {
+	carrier := map[string]string{tracing.TRACEPARENT: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"}
+
+	ctx, span := tracing.StartSpan(context.Background(), "RouteNotification", carrier)
+	defer span.End()
+	if got := tracing.TraceId(ctx); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the remote trace id got %v", got)
+	}
+
+	child, childSpan := tracing.StartSpan(ctx, "SendToSlave", nil)
+	defer childSpan.End()
+	injected, ok := tracing.Extract(tracing.Inject(child))
+	if !ok {
+		t.Fatal("expected the injected trace context to parse")
+	}
+	if injected.TraceID != span.SpanContext().TraceID || injected.SpanID != childSpan.SpanContext().SpanID {
+		t.Errorf("expected the trace context of the child span got %v", tracing.Inject(child))
+	}
+
+	if tracing.TraceId(context.Background()) != "" || tracing.Inject(context.Background()) != nil {
+		t.Error("expected no trace context without a span")
+	}
+}
+
+func TestZipkinExporter(t *testing.T) {
+	received := make(chan []tracing.ZipkinSpan, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var spans []tracing.ZipkinSpan
+		_ = json.NewDecoder(r.Body).Decode(&spans)
+		received <- spans
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer collector.Close()
+
+	exporter := tracing.NewZipkinExporter("nt-master", collector.URL, zap.NewNop().Sugar())
+	start := time.Now()
+	exporter.ExportSpan(&trace.SpanData{
+		SpanContext:  trace.SpanContext{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}},
+		ParentSpanID: trace.SpanID{3},
+		Name:         "mailgun.Send",
+		SpanKind:     trace.SpanKindClient,
+		StartTime:    start,
+		EndTime:      start.Add(250 * time.Millisecond),
+		Status:       trace.Status{Code: trace.StatusCodeUnknown, Message: "timeout"},
+	})
+	exporter.Flush(context.Background())
+
+	spans := <-received
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span got %v", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "mailgun.Send" || span.LocalEndpoint.ServiceName != "nt-master" || span.Kind != "CLIENT" {
+		t.Errorf("unexpected span %+v", span)
+	}
+	if span.ParentId != (trace.SpanID{3}).String() || span.Duration != 250000 || span.Tags["error"] != "timeout" {
+		t.Errorf("unexpected span %+v", span)
+	}
+}
@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+)
+
+// TRACEPARENT is the W3C trace context key used in task envelopes, task headers and grpc metadata
+const TRACEPARENT = "traceparent"
+
+// Init samples sampleRate of the traces and exports them to the zipkin compatible collector until ctx is done.
+// Without a collector spans are not recorded but trace ids are still propagated and logged
+func Init(ctx context.Context, serviceName, collectorUrl string, sampleRate float64, logger *zap.SugaredLogger) {
+	if collectorUrl == "" {
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.NeverSample()})
+		return
+	}
+
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(sampleRate)})
+	exporter := NewZipkinExporter(serviceName, collectorUrl, logger)
+	trace.RegisterExporter(exporter)
+	go exporter.Run(ctx)
+
+	logger.Infof("Exporting %v%% of the traces to %v", sampleRate*100, collectorUrl)
+}
+
+// StartSpan starts a span under the span of ctx, or under the remote span of carrier when ctx has none
+func StartSpan(ctx context.Context, name string, carrier map[string]string) (context.Context, *trace.Span) {
+	if trace.FromContext(ctx) == nil {
+		if parent, ok := Extract(carrier); ok {
+			return trace.StartSpanWithRemoteParent(ctx, name, parent)
+		}
+	}
+	return trace.StartSpan(ctx, name)
+}
+
+// Inject returns the trace context of the span of ctx to send with a task
+func Inject(ctx context.Context) map[string]string {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return nil
+	}
+	return map[string]string{TRACEPARENT: FormatTraceparent(span.SpanContext())}
+}
+
+func Extract(carrier map[string]string) (trace.SpanContext, bool) {
+	return ParseTraceparent(carrier[TRACEPARENT])
+}
+
+func FormatTraceparent(sc trace.SpanContext) string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID.String(), sc.SpanID.String(), uint32(sc.TraceOptions))
+}
+
+func ParseTraceparent(traceparent string) (trace.SpanContext, bool) {
+	var sc trace.SpanContext
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+
+	traceId, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return sc, false
+	}
+	spanId, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return sc, false
+	}
+	options, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+
+	copy(sc.TraceID[:], traceId)
+	copy(sc.SpanID[:], spanId)
+	sc.TraceOptions = trace.TraceOptions(options[0])
+	if sc.TraceID == (trace.TraceID{}) || sc.SpanID == (trace.SpanID{}) {
+		return sc, false
+	}
+	return sc, true
+}
+
+// TraceId returns the trace id of the span of ctx. Empty when ctx has no span
+func TraceId(ctx context.Context) string {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return ""
+	}
+	return span.SpanContext().TraceID.String()
+}
+
+// Logger adds the trace and span ids of ctx to every line of logger
+func Logger(ctx context.Context, logger *zap.SugaredLogger) *zap.SugaredLogger {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return logger
+	}
+	sc := span.SpanContext()
+	return logger.With("traceId", sc.TraceID.String(), "spanId", sc.SpanID.String())
+}
+
+// EndSpan records err on the span before ending it
+func EndSpan(span *trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+	span.End()
+}
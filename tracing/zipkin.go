@@ -0,0 +1,155 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	zipkinBatchSize     = 500
+	zipkinMaxBuffered   = 10000
+	zipkinFlushInterval = 2 * time.Second
+)
+
+type ZipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+type ZipkinSpan struct {
+	TraceId       string            `json:"traceId"`
+	Id            string            `json:"id"`
+	ParentId      string            `json:"parentId,omitempty"`
+	Name          string            `json:"name"`
+	Kind          string            `json:"kind,omitempty"`
+	Timestamp     int64             `json:"timestamp"`
+	Duration      int64             `json:"duration"`
+	LocalEndpoint ZipkinEndpoint    `json:"localEndpoint"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+// ZipkinExporter batches the spans and posts them to a zipkin v2 json endpoint,
+// e.g. http://localhost:9411/api/v2/spans of zipkin, jaeger or the opentelemetry collector
+type ZipkinExporter struct {
+	serviceName string
+	url         string
+	client      *http.Client
+	logger      *zap.SugaredLogger
+
+	mu    sync.Mutex
+	spans []ZipkinSpan
+}
+
+func NewZipkinExporter(serviceName, url string, logger *zap.SugaredLogger) *ZipkinExporter {
+	return &ZipkinExporter{
+		serviceName: serviceName,
+		url:         url,
+		client:      &http.Client{Timeout: 5 * time.Second},
+		logger:      logger,
+	}
+}
+
+func (e *ZipkinExporter) ExportSpan(sd *trace.SpanData) {
+	span := ToZipkinSpan(e.serviceName, sd)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	//Spans are dropped rather than growing without bound while the collector is down
+	if len(e.spans) >= zipkinMaxBuffered {
+		return
+	}
+	e.spans = append(e.spans, span)
+}
+
+// Run flushes the buffered spans periodically and once more when ctx is done
+func (e *ZipkinExporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(zipkinFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.Flush(context.Background())
+			return
+		case <-ticker.C:
+			e.Flush(ctx)
+		}
+	}
+}
+
+func (e *ZipkinExporter) Flush(ctx context.Context) {
+	for {
+		e.mu.Lock()
+		n := len(e.spans)
+		if n > zipkinBatchSize {
+			n = zipkinBatchSize
+		}
+		batch := e.spans[:n]
+		e.spans = e.spans[n:]
+		e.mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.post(ctx, batch); err != nil {
+			e.logger.Errorw("Error while exporting spans", "spans", len(batch), "error", err)
+			return
+		}
+	}
+}
+
+func (e *ZipkinExporter) post(ctx context.Context, spans []ZipkinSpan) error {
+	body, err := json.Marshal(spans)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %v", resp.Status)
+	}
+	return nil
+}
+
+func ToZipkinSpan(serviceName string, sd *trace.SpanData) ZipkinSpan {
+	span := ZipkinSpan{
+		TraceId:       sd.TraceID.String(),
+		Id:            sd.SpanID.String(),
+		Name:          sd.Name,
+		Timestamp:     sd.StartTime.UnixNano() / int64(time.Microsecond),
+		Duration:      sd.EndTime.Sub(sd.StartTime).Microseconds(),
+		LocalEndpoint: ZipkinEndpoint{ServiceName: serviceName},
+		Tags:          map[string]string{},
+	}
+	if sd.ParentSpanID != (trace.SpanID{}) {
+		span.ParentId = sd.ParentSpanID.String()
+	}
+	switch sd.SpanKind {
+	case trace.SpanKindServer:
+		span.Kind = "SERVER"
+	case trace.SpanKindClient:
+		span.Kind = "CLIENT"
+	}
+	for k, v := range sd.Attributes {
+		span.Tags[k] = fmt.Sprintf("%v", v)
+	}
+	if sd.Status.Code != trace.StatusCodeOK {
+		span.Tags["error"] = sd.Status.Message
+	}
+	return span
+}
@@ -0,0 +1,336 @@
+package worker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "notification_manager_circuit_breaker_state",
+		Help: "State of the circuit breaker of a provider. 0 closed, 1 half open, 2 open",
+	}, []string{"breaker"})
+	breakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_manager_circuit_breaker_transitions_total",
+		Help: "State changes of the circuit breaker of a provider",
+	}, []string{"breaker", "state"})
+)
+
+var breakerStateValues = map[string]float64{
+	contract.BREAKER_CLOSED:    0,
+	contract.BREAKER_HALF_OPEN: 1,
+	contract.BREAKER_OPEN:      2,
+}
+
+// BreakerSettings opens a breaker when ErrorRate of at least MinRequests calls in Window failed.
+// It stays open for OpenFor, then lets a single probe through
+type BreakerSettings struct {
+	ErrorRate   float64
+	MinRequests int
+	Window      time.Duration
+	OpenFor     time.Duration
+}
+
+// ParseBreakerSettings parses error percent:min requests:window:open duration ex: 50:20:60s:30s
+func ParseBreakerSettings(spec string) (BreakerSettings, error) {
+	values := strings.Split(strings.TrimSpace(spec), ":")
+	if len(values) != 4 {
+		return BreakerSettings{}, fmt.Errorf("circuit breaker %q is not error percent:min requests:window:open duration", spec)
+	}
+
+	percent, err := strconv.Atoi(values[0])
+	if err != nil || percent < 1 || percent > 100 {
+		return BreakerSettings{}, fmt.Errorf("circuit breaker %q needs an error percent between 1 and 100", spec)
+	}
+	minRequests, err := strconv.Atoi(values[1])
+	if err != nil || minRequests < 1 {
+		return BreakerSettings{}, fmt.Errorf("circuit breaker %q needs at least 1 request", spec)
+	}
+	window, err := time.ParseDuration(values[2])
+	if err != nil || window <= 0 {
+		return BreakerSettings{}, fmt.Errorf("circuit breaker %q needs a positive window", spec)
+	}
+	openFor, err := time.ParseDuration(values[3])
+	if err != nil || openFor <= 0 {
+		return BreakerSettings{}, fmt.Errorf("circuit breaker %q needs a positive open duration", spec)
+	}
+
+	return BreakerSettings{ErrorRate: float64(percent) / 100, MinRequests: minRequests, Window: window, OpenFor: openFor}, nil
+}
+
+// BreakerName is the breaker of a provider, or of a bot of the provider when botConfigId is set
+func BreakerName(provider string, botConfigId uint64) string {
+	if botConfigId == 0 {
+		return provider
+	}
+	return fmt.Sprintf("%v:bot-%d", provider, botConfigId)
+}
+
+// BotBreakers are the breakers a call of a bot goes through. The breaker of the provider opens on an outage
+// of the provider, as each bot alone rarely makes enough calls to reach the min requests of its breaker
+func BotBreakers(provider string, botConfigId uint64) []string {
+	return []string{BreakerName(provider, 0), BreakerName(provider, botConfigId)}
+}
+
+// BreakerOpenError is returned by Deliver instead of calling a provider whose breaker is open
+type BreakerOpenError struct {
+	Name  string
+	Until time.Time
+}
+
+func (e *BreakerOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker %v is open until %v", e.Name, e.Until.UTC().Format(time.RFC3339))
+}
+
+// BreakerStatus is the state of a breaker as reported by the health endpoint
+type BreakerStatus struct {
+	Name      string  `json:"name"`
+	State     string  `json:"state"`
+	Requests  int     `json:"requests"`
+	Failures  int     `json:"failures"`
+	ErrorRate float64 `json:"errorRate"`
+	OpenUntil string  `json:"openUntil,omitempty"`
+}
+
+// Breaker counts the calls to a provider in fixed windows. Only transient errors count as failures,
+// a rejected recipient says nothing about the health of the provider
+type Breaker struct {
+	Name     string
+	settings BreakerSettings
+	exported bool
+
+	mu          sync.Mutex
+	state       string
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probeAt     time.Time
+}
+
+// NewBreaker creates the breaker name. Only the breakers of the providers are exported in the metrics,
+// a series per bot would grow with every bot. Bot breakers are reported by the health endpoint
+func NewBreaker(name string, settings BreakerSettings) *Breaker {
+	b := &Breaker{Name: name, settings: settings, state: contract.BREAKER_CLOSED, exported: !strings.Contains(name, ":")}
+	if b.exported {
+		breakerState.WithLabelValues(name).Set(breakerStateValues[contract.BREAKER_CLOSED])
+	}
+	return b
+}
+
+// Allow reports whether a call can go to the provider. A nil breaker allows every call
+func (b *Breaker) Allow(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.ready(now) {
+		return false
+	}
+	switch b.state {
+	case contract.BREAKER_OPEN:
+		b.setState(contract.BREAKER_HALF_OPEN)
+		b.probeAt = now
+	case contract.BREAKER_HALF_OPEN:
+		b.probeAt = now
+	}
+	return true
+}
+
+// Ready reports whether Allow would let a call through, without taking the probe of an open breaker
+func (b *Breaker) Ready(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ready(now)
+}
+
+// Release gives back the probe taken by Allow(now) for a call that was never made,
+// so the next call can probe without waiting for OpenFor
+func (b *Breaker) Release(now time.Time) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == contract.BREAKER_HALF_OPEN && b.probeAt.Equal(now) {
+		b.probeAt = time.Time{}
+	}
+}
+
+func (b *Breaker) ready(now time.Time) bool {
+	switch b.state {
+	case contract.BREAKER_OPEN:
+		return !now.Before(b.openedAt.Add(b.settings.OpenFor))
+	case contract.BREAKER_HALF_OPEN:
+		//A probe that never reported back doesn't hold the breaker half open forever
+		return !now.Before(b.probeAt.Add(b.settings.OpenFor))
+	}
+	return true
+}
+
+// AllowAll lets a call through when every breaker allows it. All of them are checked before a probe is taken
+// and the probes already taken are released when a breaker refuses anyway. It returns the index of the
+// breaker that refused, -1 when the call is allowed
+func AllowAll(breakers []*Breaker, now time.Time) int {
+	for i, breaker := range breakers {
+		if !breaker.Ready(now) {
+			return i
+		}
+	}
+	for i, breaker := range breakers {
+		if !breaker.Allow(now) {
+			ReleaseAll(breakers[:i], now)
+			return i
+		}
+	}
+	return -1
+}
+
+// ReleaseAll releases the probes taken by AllowAll(breakers, now)
+func ReleaseAll(breakers []*Breaker, now time.Time) {
+	for _, breaker := range breakers {
+		breaker.Release(now)
+	}
+}
+
+// Record counts the result of a call allowed by Allow
+func (b *Breaker) Record(failed bool, now time.Time) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case contract.BREAKER_HALF_OPEN:
+		if failed {
+			b.open(now)
+			return
+		}
+		b.reset(now)
+		b.setState(contract.BREAKER_CLOSED)
+		return
+	case contract.BREAKER_OPEN:
+		return
+	}
+
+	if now.Sub(b.windowStart) > b.settings.Window {
+		b.reset(now)
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if b.requests >= b.settings.MinRequests && float64(b.failures)/float64(b.requests) >= b.settings.ErrorRate {
+		b.open(now)
+	}
+}
+
+// OpenUntil is when an open breaker lets the next probe through
+func (b *Breaker) OpenUntil() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == contract.BREAKER_HALF_OPEN {
+		return b.probeAt.Add(b.settings.OpenFor)
+	}
+	return b.openedAt.Add(b.settings.OpenFor)
+}
+
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{Name: b.Name, State: b.state, Requests: b.requests, Failures: b.failures}
+	if b.requests > 0 {
+		status.ErrorRate = float64(b.failures) / float64(b.requests)
+	}
+	if b.state == contract.BREAKER_OPEN {
+		status.OpenUntil = b.openedAt.Add(b.settings.OpenFor).UTC().Format(time.RFC3339)
+	}
+	return status
+}
+
+func (b *Breaker) open(now time.Time) {
+	b.openedAt = now
+	b.setState(contract.BREAKER_OPEN)
+}
+
+func (b *Breaker) reset(now time.Time) {
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+}
+
+func (b *Breaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.exported {
+		breakerState.WithLabelValues(b.Name).Set(breakerStateValues[state])
+		breakerTransitions.WithLabelValues(b.Name, state).Inc()
+	}
+}
+
+// Breakers holds the breakers of the providers a slave calls, created on first use
+type Breakers struct {
+	Settings BreakerSettings
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+func NewBreakers(settings BreakerSettings) *Breakers {
+	return &Breakers{Settings: settings, breakers: map[string]*Breaker{}}
+}
+
+// Get returns the breaker of name. It is nil when the breakers are disabled
+func (b *Breakers) Get(name string) *Breaker {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	breaker, ok := b.breakers[name]
+	if !ok {
+		breaker = NewBreaker(name, b.Settings)
+		b.breakers[name] = breaker
+	}
+	return breaker
+}
+
+// Status returns the state of every breaker sorted by name
+func (b *Breakers) Status() []BreakerStatus {
+	statuses := []BreakerStatus{}
+	if b == nil {
+		return statuses
+	}
+
+	b.mu.Lock()
+	breakers := make([]*Breaker, 0, len(b.breakers))
+	for _, v := range b.breakers {
+		breakers = append(breakers, v)
+	}
+	b.mu.Unlock()
+
+	for _, v := range breakers {
+		statuses = append(statuses, v.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
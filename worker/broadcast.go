@@ -0,0 +1,136 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+)
+
+// BroadcastBatch sends the next batch of a broadcast to the slaves and queues the batch after it.
+// Batch tasks of a paused, cancelled or resumed broadcast stop here
+func (n *NotificationRouter) BroadcastBatch(ctx context.Context, broadcastId string, generation int) error {
+
+	broadcast, err := n.Db.GetBroadcast(ctx, broadcastId)
+	if err != nil {
+		return err
+	}
+	if broadcast.Status != contract.BROADCAST_RUNNING || broadcast.Generation != generation {
+		n.Logger.Infof("Stopping broadcast:%v status:%v", broadcastId, broadcast.Status)
+		return nil
+	}
+
+	//Without a slave every user of the batch would be dead-lettered so the broadcast is paused as a whole
+	notificationTypes := strings.Split(broadcast.NotificationTypes, ",")
+	if ntType := UnavailableSlave(notificationTypes); ntType != "" {
+		n.Logger.Errorw("Pausing broadcast. No available slave worker", "broadcastId", broadcastId, "notificationType", ntType)
+		_, err := n.Db.SetBroadcastStatus(ctx, broadcastId, []string{contract.BROADCAST_RUNNING}, contract.BROADCAST_PAUSED)
+		return err
+	}
+
+	userConfigs, err := n.Db.GetBroadcastBatch(ctx, broadcast)
+	if err != nil {
+		return err
+	}
+
+	completed := len(userConfigs) < broadcast.BatchSize
+	lastUserConfig := broadcast.LastUserConfig
+	if len(userConfigs) > 0 {
+		lastUserConfig = userConfigs[len(userConfigs)-1].ID
+	}
+
+	//Claim the batch before sending so a pause in the meantime doesn't send it twice on resume
+	claimed, err := n.Db.AdvanceBroadcast(ctx, broadcast, lastUserConfig, len(userConfigs), completed)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		n.Logger.Infof("Broadcast:%v changed while sending batch. Stopping", broadcastId)
+		return nil
+	}
+
+	dataBytes, _ := json.Marshal(map[string]string{"BROADCAST_NAME": broadcast.Name})
+	headers := tasks.Headers{contract.HEADER_BROADCAST_ID: broadcast.UuId}
+
+	for _, userConfig := range userConfigs {
+		for _, ntType := range notificationTypes {
+			if !IsChannelEnabled(userConfig, ntType) {
+				continue
+			}
+			n.SendToSlave(ctx, ntType, contract.BROADCAST, fmt.Sprintf("%d", userConfig.ID), "", dataBytes, headers)
+		}
+	}
+
+	if completed {
+		n.Logger.Infof("Broadcast:%v completed", broadcastId)
+		return nil
+	}
+
+	eta := time.Now().UTC().Add(time.Duration(broadcast.BatchIntervalSeconds) * time.Second)
+	_, err = n.MachineryServer.SendTask(GetBroadcastBatchTask(n.WorkerConfig.AMQP.BindingKey, broadcastId, generation, &eta))
+	return err
+}
+
+func GetBroadcastBatchTask(bindingKey, broadcastId string, generation int, eta *time.Time) *tasks.Signature {
+	return &tasks.Signature{
+		Name:       contract.TASK_BROADCAST_BATCH,
+		RoutingKey: bindingKey,
+		ETA:        eta,
+		Args: []tasks.Arg{
+			{
+				Name:  "broadcastId",
+				Type:  "string",
+				Value: broadcastId,
+			},
+			{
+				Name:  "generation",
+				Type:  "int",
+				Value: generation,
+			},
+		},
+		RetryCount:   contract.TASK_RETRY_COUNT,
+		RetryTimeout: contract.TASK_RETRY_TIMEOUT,
+	}
+}
+
+// UnavailableSlave returns the first of the notification types without an available slave. Empty when all are available
+func UnavailableSlave(notificationTypes []string) string {
+	for _, v := range notificationTypes {
+		if !IsSlaveAvailable(v) {
+			return v
+		}
+	}
+	return ""
+}
+
+// GetBroadcastId returns the broadcast the task delivers. Empty id means it isn't a broadcast
+func GetBroadcastId(ctx context.Context) string {
+	signature := tasks.SignatureFromContext(ctx)
+	if signature == nil {
+		return ""
+	}
+	broadcastId, _ := signature.Headers[contract.HEADER_BROADCAST_ID].(string)
+	return broadcastId
+}
+
+func IsChannelEnabled(userConfig model.UserConfig, notificationType string) bool {
+	switch strings.ToUpper(notificationType) {
+	case nm.NotificationType_EMAIL.String():
+		return userConfig.EmailEnabled
+	case nm.NotificationType_DISCORD.String():
+		return userConfig.DiscordEnabled
+	case nm.NotificationType_SLACK.String():
+		return userConfig.SlackEnabled
+	case nm.NotificationType_TELEGRAM.String():
+		return userConfig.TelegramEnabled
+	case nm.NotificationType_WHATSAPP.String():
+		return userConfig.WhatsAppEnabled
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	brokersiface "github.com/RichardKnop/machinery/v2/brokers/iface"
+)
+
+// ErrConsumerLost is returned by Consume when the connection of the consumer to the broker is lost
+var ErrConsumerLost = errors.New("consumer connection to the broker lost")
+
+// ErrNotConsuming is reported by ConsumerCheck while the worker doesn't consume
+var ErrNotConsuming = errors.New("worker is not consuming")
+
+// consumerBroker reports the state of the consumer to the worker. Machinery reconnects a lost consumer
+// inside StartConsuming and sleeps longer between each attempt there, so a lost connection is returned
+// to Consume instead and Run consumes again on a new connection
+type consumerBroker struct {
+	brokersiface.Broker
+	worker *Worker
+}
+
+// ConsumerBroker reports the state of the consumer of broker to ConsumerCheck
+func (w *Worker) ConsumerBroker(broker brokersiface.Broker) brokersiface.Broker {
+	return &consumerBroker{Broker: broker, worker: w}
+}
+
+func (b *consumerBroker) StartConsuming(consumerTag string, concurrency int, taskProcessor brokersiface.TaskProcessor) (bool, error) {
+	b.worker.setConsumerErr(nil)
+	retry, err := b.Broker.StartConsuming(consumerTag, concurrency, taskProcessor)
+	if err == nil {
+		return retry, nil
+	}
+
+	err = fmt.Errorf("%w: %v", ErrConsumerLost, err)
+	b.worker.setConsumerErr(err)
+	return false, err
+}
+
+// ConsumerCheck reports whether the worker consumes its queue. It fails while Consume isn't running
+// and once the connection of the consumer is lost, until the worker consumes again
+func (w *Worker) ConsumerCheck(ctx context.Context) error {
+	w.consumerMu.Lock()
+	defer w.consumerMu.Unlock()
+
+	if w.consumerErr != nil {
+		return w.consumerErr
+	}
+	if !w.consuming {
+		return ErrNotConsuming
+	}
+	return nil
+}
+
+func (w *Worker) setConsuming(consuming bool) {
+	w.consumerMu.Lock()
+	defer w.consumerMu.Unlock()
+	w.consuming = consuming
+}
+
+func (w *Worker) setConsumerErr(err error) {
+	w.consumerMu.Lock()
+	defer w.consumerMu.Unlock()
+	w.consumerErr = err
+}
@@ -0,0 +1,428 @@
+package worker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devShahriar/H"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/tracing"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+)
+
+var (
+	deadLetterMu      sync.Mutex
+	deadLetterWorkers = map[string]*Worker{}
+)
+
+// Meta describes the queue of the worker the way it is ingested in worker meta
+func (w *Worker) Meta() contract.WorkerMeta {
+	return contract.WorkerMeta{
+		Name:             w.Name,
+		WorkerType:       w.WorkerType,
+		Exchange:         w.WorkerConfig.AMQP.Exchange,
+		Queue:            w.WorkerConfig.DefaultQueue,
+		ExchangeType:     w.WorkerConfig.AMQP.ExchangeType,
+		BindingKey:       w.WorkerConfig.AMQP.BindingKey,
+		NotificationType: w.WorkerType,
+	}
+}
+
+// GetDeadLetterMeta describes the dead-letter exchange and queue of the worker of meta
+func GetDeadLetterMeta(meta contract.WorkerMeta) contract.WorkerMeta {
+	meta.Exchange += contract.DEAD_LETTER_EXCHANGE_SUFFIX
+	meta.Queue += contract.DEAD_LETTER_QUEUE_SUFFIX
+	meta.BindingKey += contract.DEAD_LETTER_QUEUE_SUFFIX
+	return meta
+}
+
+// GetDeadLetterWorker returns the worker publishing to the dead-letter queue of the worker of meta.
+// Nothing consumes the queue so its messages expire after DEAD_LETTER_TTL_DAYS
+func GetDeadLetterWorker(meta contract.WorkerMeta) *Worker {
+	deadLetterMu.Lock()
+	defer deadLetterMu.Unlock()
+
+	dlqMeta := GetDeadLetterMeta(meta)
+	if existing := deadLetterWorkers[meta.Name]; existing != nil && existing.WorkerConfig.AMQP.BindingKey == dlqMeta.BindingKey {
+		return existing
+	}
+
+	workerInstance := newWorkerFromMeta(dlqMeta)
+	workerInstance.WorkerConfig.AMQP.QueueDeclareArgs = map[string]interface{}{
+		"x-message-ttl": int64(contract.DEAD_LETTER_TTL_DAYS * 24 * time.Hour / time.Millisecond),
+	}
+	workerInstance.InitMachineryWorker()
+	deadLetterWorkers[meta.Name] = workerInstance
+	return workerInstance
+}
+
+// NewDeadLetter creates the stored dead letter of a task of the worker workerName that failed with taskErr.
+// The notification it belongs to is read from its envelope or from its positional arguments
+func NewDeadLetter(workerName string, signature *tasks.Signature, taskErr error) (*model.DeadLetters, error) {
+	signatureBytes, err := json.Marshal(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	letter := &model.DeadLetters{
+		UuId:       uuid.New().String(),
+		WorkerName: workerName,
+		TaskName:   signature.Name,
+		Signature:  datatypes.JSON(signatureBytes),
+		Status:     contract.DEAD_LETTER_DEAD,
+	}
+	if taskErr != nil {
+		letter.LastError = taskErr.Error()
+	}
+
+	for _, arg := range signature.Args {
+		switch arg.Name {
+		case "envelope":
+			envelope, err := DecodeEnvelope(GetBytesArg(arg))
+			if err != nil {
+				continue
+			}
+			letter.NotificationId = envelope.NotificationId
+			letter.UserConfig = envelope.Event.UserConfig
+			letter.AccountId = envelope.Event.AccountId
+			letter.EventType = envelope.Event.EventType
+			if len(envelope.Destinations) == 1 {
+				letter.NotificationType = envelope.Destinations[0]
+			}
+		case "userConfig":
+			letter.UserConfig, _ = arg.Value.(string)
+		case "accountId":
+			letter.AccountId, _ = arg.Value.(string)
+		case "eventType":
+			letter.EventType, _ = arg.Value.(string)
+		}
+	}
+	if letter.NotificationId == "" {
+		letter.NotificationId, _ = signature.Headers[contract.HEADER_NOTIFICATION_ID].(string)
+	}
+	return letter, nil
+}
+
+// GetBytesArg returns the value of a []byte argument. Signatures decoded from a queue hold it base64 encoded
+func GetBytesArg(arg tasks.Arg) []byte {
+	switch v := arg.Value.(type) {
+	case []byte:
+		return v
+	case string:
+		b, _ := base64.StdEncoding.DecodeString(v)
+		return b
+	}
+	return nil
+}
+
+// GetDeadLetterTask is the copy of the failed task published to the dead-letter queue
+func GetDeadLetterTask(signature *tasks.Signature, letter *model.DeadLetters, routingKey string) *tasks.Signature {
+	deadLetter := *signature
+	deadLetter.UUID = ""
+	deadLetter.RoutingKey = routingKey
+	deadLetter.ETA = nil
+	deadLetter.RetryCount = 0
+	deadLetter.Headers = MergeHeaders(signature.Headers, tasks.Headers{
+		contract.HEADER_DEAD_LETTER_ID: letter.UuId,
+		contract.HEADER_LAST_ERROR:     letter.LastError,
+	})
+	return &deadLetter
+}
+
+// GetReplayTask restores the task of the dead letter for the worker of meta with its retries reset
+func GetReplayTask(letter model.DeadLetters, meta contract.WorkerMeta) (*tasks.Signature, error) {
+	signature := &tasks.Signature{}
+	if err := json.Unmarshal(letter.Signature, signature); err != nil {
+		return nil, fmt.Errorf("invalid signature of dead letter %v: %v", letter.UuId, err)
+	}
+	signature.UUID = ""
+	signature.RoutingKey = meta.BindingKey
+	signature.ETA = nil
+	signature.RetryCount = contract.TASK_RETRY_COUNT
+	signature.RetryTimeout = contract.TASK_RETRY_TIMEOUT
+	signature.Headers = MergeHeaders(signature.Headers, tasks.Headers{contract.HEADER_DEAD_LETTER_ID: letter.UuId})
+	return signature, nil
+}
+
+// DeadLetter publishes a task that failed after its last retry to the dead-letter queue of the worker
+// of meta and stores it with its last error so it can be inspected and replayed
+func (w *Worker) DeadLetter(ctx context.Context, meta contract.WorkerMeta, signature *tasks.Signature, taskErr error) {
+	logger := tracing.Logger(ctx, w.Logger)
+
+	letter, err := NewDeadLetter(meta.Name, signature, taskErr)
+	if err != nil {
+		logger.Errorw("Error while creating dead letter", "task", signature.Name, "error", err)
+		return
+	}
+
+	if meta.Name != "" {
+		dlq := GetDeadLetterWorker(meta)
+		if _, err := dlq.MachineryServer.SendTask(GetDeadLetterTask(signature, letter, dlq.WorkerConfig.AMQP.BindingKey)); err != nil {
+			logger.Errorw("Error while publishing dead letter", "deadLetterId", letter.UuId, "worker", meta.Name, "error", err)
+		}
+	}
+
+	if err := w.Db.CreateDeadLetter(ctx, letter); err != nil {
+		logger.Errorw("Error while storing dead letter", "deadLetterId", letter.UuId, "task", signature.Name, "error", err)
+		return
+	}
+	w.RecordTransition(ctx, letter.NotificationType, contract.NOTIFICATION_DEAD_LETTERED, letter.LastError)
+	logger.Infow("Dead-lettered task", "deadLetterId", letter.UuId, "task", signature.Name, "worker", meta.Name, "error", letter.LastError)
+}
+
+// DeadLetterDelivery dead-letters the delivery of an event through notificationType when the master gives up on it.
+// The dead letter replays the delivery on the slave of notificationType
+func (n *NotificationRouter) DeadLetterDelivery(ctx context.Context, notificationType, eventType, userConfig, accId string, dataBytes []byte, headers tasks.Headers, taskErr error) {
+	envelope := GetDeliveryEnvelope(EnvelopeFromContext(ctx), notificationType, eventType, userConfig, accId, dataBytes)
+	envelope.TraceContext = tracing.Inject(ctx)
+
+	meta := contract.WorkerMeta{NotificationType: notificationType}
+	if slave := GetSlaveFromPool(notificationType); slave != nil {
+		meta = slave.Meta()
+	}
+
+	signature, err := GetEnvelopeTask(GetEnvelopeTaskName(notificationType), meta.BindingKey, envelope)
+	if err != nil {
+		n.Logger.Errorw("Error while encoding dead letter envelope", "notificationType", notificationType, "error", err)
+		return
+	}
+	signature.Headers = headers
+	signature.RetryCount = 0
+
+	n.DeadLetter(WithEnvelope(ctx, envelope), meta, signature, taskErr)
+}
+
+// WithDeadLetter wraps a task so it is dead-lettered when it fails on its last retry.
+// Tasks are registered by reflection so the wrapper keeps the type of fn. Tasks without a context are returned as they are
+func (w *Worker) WithDeadLetter(fn interface{}) interface{} {
+	fnValue := reflect.ValueOf(fn)
+	fnType := fnValue.Type()
+	contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	if fnType.Kind() != reflect.Func || fnType.NumIn() == 0 || fnType.In(0) != contextType ||
+		fnType.NumOut() == 0 || fnType.Out(fnType.NumOut()-1) != errorType {
+		return fn
+	}
+
+	return reflect.MakeFunc(fnType, func(args []reflect.Value) []reflect.Value {
+		results := fnValue.Call(args)
+		err, _ := results[len(results)-1].Interface().(error)
+		if err == nil {
+			return results
+		}
+
+		//Machinery requeues retriable errors whatever the retry count
+		ctx, _ := args[0].Interface().(context.Context)
+		if _, retriable := err.(tasks.Retriable); retriable || ctx == nil || !IsLastAttempt(ctx) {
+			return results
+		}
+		if signature := tasks.SignatureFromContext(ctx); signature != nil {
+			w.DeadLetter(ctx, w.Meta(), signature, err)
+		}
+		return results
+	}).Interface()
+}
+
+// DeadLetterReplayer publishes dead letters back to the worker they were meant for
+type DeadLetterReplayer struct {
+	Db     db.DB
+	Logger *zap.SugaredLogger
+
+	metas []contract.WorkerMeta
+}
+
+func NewDeadLetterReplayer(database db.DB, logger *zap.SugaredLogger) (*DeadLetterReplayer, error) {
+	metas, err := database.GetWorkerMeta()
+	if err != nil {
+		return nil, err
+	}
+	return &DeadLetterReplayer{Db: database, Logger: logger, metas: metas}, nil
+}
+
+// GetReplayWorkerMeta returns the worker the dead letter is replayed on. Dead letters of a worker that
+// is no longer registered, or that was unknown when they were created, go to the last registered slave of their notification type
+func GetReplayWorkerMeta(metas []contract.WorkerMeta, letter model.DeadLetters) (contract.WorkerMeta, bool) {
+	for _, v := range metas {
+		if letter.WorkerName != "" && v.Name == letter.WorkerName {
+			return v, true
+		}
+	}
+	if letter.NotificationType == "" {
+		return contract.WorkerMeta{}, false
+	}
+	return GetSlaveMeta(metas, letter.NotificationType)
+}
+
+// Replay publishes the dead letter again. With onlyDead a dead letter that was already replayed is skipped
+// and false is returned
+func (r *DeadLetterReplayer) Replay(ctx context.Context, letter model.DeadLetters, onlyDead bool) (bool, error) {
+	meta, ok := GetReplayWorkerMeta(r.metas, letter)
+	if !ok {
+		return false, fmt.Errorf("no worker registered for %v", H.If(letter.WorkerName != "", letter.WorkerName, letter.NotificationType))
+	}
+	signature, err := GetReplayTask(letter, meta)
+	if err != nil {
+		return false, err
+	}
+
+	claimed, err := r.Db.MarkDeadLetterReplayed(ctx, letter.UuId, onlyDead)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	if _, err := GetPublisher(meta).MachineryServer.SendTask(signature); err != nil {
+		if resetErr := r.Db.ResetDeadLetter(ctx, letter.UuId); resetErr != nil {
+			r.Logger.Errorw("Error while resetting dead letter", "deadLetterId", letter.UuId, "error", resetErr)
+		}
+		return false, err
+	}
+
+	if letter.NotificationId != "" {
+		transition := model.NotificationTransitions{
+			NotificationId:   letter.NotificationId,
+			NotificationType: letter.NotificationType,
+			State:            contract.NOTIFICATION_ROUTED,
+			Reason:           "replayed from dead letter " + letter.UuId,
+			UserConfig:       letter.UserConfig,
+			AccountId:        letter.AccountId,
+			EventType:        letter.EventType,
+		}
+		if err := r.Db.RecordNotificationTransitions(ctx, []model.NotificationTransitions{transition}); err != nil {
+			r.Logger.Errorw("Error while recording notification transition", "notificationId", letter.NotificationId, "error", err)
+		}
+	}
+	r.Logger.Infow("Replayed dead letter", "deadLetterId", letter.UuId, "task", letter.TaskName, "worker", meta.Name)
+	return true, nil
+}
+
+// ReplayDeadLetters replays the dead letters of the ids or up to limit DEAD letters of the filter,
+// rate per second. Failures are reported per dead letter
+func (r *DeadLetterReplayer) ReplayDeadLetters(ctx context.Context, ids []string, filter contract.DeadLetterFilter, limit, rate int) (*pb.ReplayDeadLettersReply, error) {
+	reply := &pb.ReplayDeadLettersReply{}
+
+	var interval time.Duration
+	if rate > 0 {
+		interval = time.Second / time.Duration(rate)
+	}
+	replay := func(letter model.DeadLetters, onlyDead bool) error {
+		replayed, err := r.Replay(ctx, letter, onlyDead)
+		if err != nil {
+			reply.Failed = append(reply.Failed, &pb.DeadLetterFailure{DeadLetterId: letter.UuId, Error: err.Error()})
+		}
+		if replayed {
+			reply.Replayed++
+		}
+		if interval > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+		return nil
+	}
+
+	if len(ids) > 0 {
+		for _, id := range ids {
+			letter, err := r.Db.GetDeadLetter(ctx, id)
+			if err != nil {
+				reply.Failed = append(reply.Failed, &pb.DeadLetterFailure{DeadLetterId: id, Error: err.Error()})
+				continue
+			}
+			if err := replay(letter, false); err != nil {
+				return reply, err
+			}
+		}
+		return reply, nil
+	}
+
+	if limit <= 0 {
+		limit = contract.DEFAULT_DEAD_LETTER_REPLAYS
+	}
+	filter.Status = contract.DEAD_LETTER_DEAD
+
+	var afterId uint64
+	seen := 0
+	for seen < limit {
+		page, err := r.Db.ListDeadLetters(ctx, filter, afterId, contract.MAX_LOG_PAGE_SIZE)
+		if err != nil {
+			return reply, err
+		}
+		for _, v := range page {
+			if seen >= limit {
+				break
+			}
+			seen++
+			letter, err := r.Db.GetDeadLetter(ctx, v.UuId)
+			if err != nil {
+				reply.Failed = append(reply.Failed, &pb.DeadLetterFailure{DeadLetterId: v.UuId, Error: err.Error()})
+				continue
+			}
+			if err := replay(letter, true); err != nil {
+				return reply, err
+			}
+		}
+		if len(page) < contract.MAX_LOG_PAGE_SIZE {
+			break
+		}
+		afterId = page[len(page)-1].Id
+	}
+	return reply, nil
+}
+
+// ParseDeadLetterFilter converts the filter of a request. Times are RFC3339
+func ParseDeadLetterFilter(req *pb.DeadLetterFilter) (contract.DeadLetterFilter, error) {
+	filter := contract.DeadLetterFilter{
+		WorkerName:       req.GetWorkerName(),
+		NotificationType: req.GetNotificationType(),
+		Status:           req.GetStatus(),
+	}
+	for _, v := range []struct {
+		value string
+		field **time.Time
+	}{{req.GetFrom(), &filter.From}, {req.GetTo(), &filter.To}} {
+		if v.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v.value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid time %v. Expected RFC3339", v.value)
+		}
+		t = t.UTC()
+		*v.field = &t
+	}
+	return filter, nil
+}
+
+func ToDeadLetter(letter model.DeadLetters) *pb.DeadLetter {
+	deadLetter := &pb.DeadLetter{
+		DeadLetterId:     letter.UuId,
+		CreatedAt:        letter.CreatedAt.UTC().Format(time.RFC3339),
+		WorkerName:       letter.WorkerName,
+		TaskName:         letter.TaskName,
+		NotificationId:   letter.NotificationId,
+		NotificationType: letter.NotificationType,
+		UserConfig:       letter.UserConfig,
+		AccountId:        letter.AccountId,
+		EventType:        letter.EventType,
+		LastError:        letter.LastError,
+		Status:           letter.Status,
+		ReplayCount:      int32(letter.ReplayCount),
+		Signature:        string(letter.Signature),
+	}
+	if letter.ReplayedAt != nil {
+		deadLetter.ReplayedAt = letter.ReplayedAt.UTC().Format(time.RFC3339)
+	}
+	return deadLetter
+}
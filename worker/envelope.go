@@ -0,0 +1,162 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/tracing"
+	"github.com/google/uuid"
+	"go.opencensus.io/trace"
+	"google.golang.org/protobuf/proto"
+)
+
+type envelopeKey struct{}
+
+// ErrNewerEnvelope is returned for envelopes of a version newer than TASK_ENVELOPE_VERSION
+var ErrNewerEnvelope = errors.New("task envelope is newer than this worker")
+
+// GetEnvelopeTaskName is the envelope task of the slave of notificationType.
+// The slaves keep the positional task of GetTaskName during rolling upgrades
+func GetEnvelopeTaskName(notificationType string) string {
+	return fmt.Sprintf("task_deliver_%s", notificationType)
+}
+
+// NewEnvelope creates the envelope of a new notification
+func NewEnvelope(userId, accId, eventType string, dataBytes []byte) *pb.TaskEnvelope {
+	notificationId := uuid.New().String()
+	return &pb.TaskEnvelope{
+		Version:        contract.TASK_ENVELOPE_VERSION,
+		NotificationId: notificationId,
+		CreatedAt:      time.Now().UTC().Format(time.RFC3339),
+		IdempotencyKey: notificationId,
+		Event: &pb.TaskEvent{
+			UserId:    userId,
+			AccountId: accId,
+			EventType: eventType,
+			Data:      dataBytes,
+		},
+	}
+}
+
+// GetDeliveryEnvelope derives the envelope of a slave task from the envelope the master is routing.
+// Each destination gets its own idempotency key
+func GetDeliveryEnvelope(parent *pb.TaskEnvelope, notificationType, eventType, userConfig, accId string, dataBytes []byte) *pb.TaskEnvelope {
+	envelope := &pb.TaskEnvelope{
+		Version:        contract.TASK_ENVELOPE_VERSION,
+		NotificationId: parent.GetNotificationId(),
+		TraceContext:   parent.GetTraceContext(),
+		Priority:       parent.GetPriority(),
+		CreatedAt:      parent.GetCreatedAt(),
+		IdempotencyKey: fmt.Sprintf("%v:%v", parent.GetIdempotencyKey(), notificationType),
+		Event: &pb.TaskEvent{
+			UserId:     parent.GetEvent().GetUserId(),
+			UserConfig: userConfig,
+			AccountId:  accId,
+			EventType:  eventType,
+			Data:       dataBytes,
+		},
+		Destinations: []string{notificationType},
+	}
+	if envelope.NotificationId == "" {
+		envelope.NotificationId = uuid.New().String()
+		envelope.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+		envelope.IdempotencyKey = fmt.Sprintf("%v:%v", envelope.NotificationId, notificationType)
+	}
+	return envelope
+}
+
+func DecodeEnvelope(envelopeBytes []byte) (*pb.TaskEnvelope, error) {
+	envelope := &pb.TaskEnvelope{}
+	if err := proto.Unmarshal(envelopeBytes, envelope); err != nil {
+		return nil, fmt.Errorf("invalid task envelope: %v", err)
+	}
+	if envelope.Version > contract.TASK_ENVELOPE_VERSION {
+		return nil, fmt.Errorf("%w: version %v, newest accepted %v", ErrNewerEnvelope, envelope.Version, contract.TASK_ENVELOPE_VERSION)
+	}
+	if envelope.Event == nil {
+		return nil, fmt.Errorf("task envelope %v has no event", envelope.NotificationId)
+	}
+	return envelope, nil
+}
+
+func GetEnvelopeTask(taskName, bindingKey string, envelope *pb.TaskEnvelope) (*tasks.Signature, error) {
+	envelopeBytes, err := proto.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tasks.Signature{
+		Name:       taskName,
+		RoutingKey: bindingKey,
+		Priority:   uint8(envelope.Priority),
+		Args: []tasks.Arg{
+			{
+				Name:  "envelope",
+				Type:  "[]byte",
+				Value: envelopeBytes,
+			},
+		},
+		RetryCount:   contract.TASK_RETRY_COUNT,
+		RetryTimeout: contract.TASK_RETRY_TIMEOUT,
+	}, nil
+}
+
+func WithEnvelope(ctx context.Context, envelope *pb.TaskEnvelope) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, envelope)
+}
+
+// EnvelopeFromContext returns the envelope of the task being processed.
+// It is nil for tasks received in the positional format
+func EnvelopeFromContext(ctx context.Context) *pb.TaskEnvelope {
+	envelope, _ := ctx.Value(envelopeKey{}).(*pb.TaskEnvelope)
+	return envelope
+}
+
+// EnvelopeError is the task error of an envelope DecodeEnvelope rejected. A newer envelope is requeued for the
+// upgraded workers during a rolling upgrade. A corrupt one never succeeds so it fails and is dead-lettered
+func EnvelopeError(err error) error {
+	if errors.Is(err, ErrNewerEnvelope) {
+		return tasks.NewErrRetryTaskLater(err.Error(), contract.TASK_ENVELOPE_RETRY_SECONDS*time.Second)
+	}
+	return err
+}
+
+// EnvelopeTask adapts a positional slave task to the envelope format
+func EnvelopeTask(fn func(ctx context.Context, userConfig, accId, eventType string, data []byte) error) func(ctx context.Context, envelopeBytes []byte) error {
+	return func(ctx context.Context, envelopeBytes []byte) error {
+		envelope, err := DecodeEnvelope(envelopeBytes)
+		if err != nil {
+			return EnvelopeError(err)
+		}
+		ctx = WithEnvelope(ctx, envelope)
+		ObserveQueueLag(ctx, envelope)
+		name := "deliver"
+		if signature := tasks.SignatureFromContext(ctx); signature != nil {
+			name = signature.Name
+		}
+		ctx, span := tracing.StartSpan(ctx, name, envelope.TraceContext)
+		span.AddAttributes(trace.StringAttribute("notification.id", envelope.NotificationId), trace.StringAttribute("idempotency.key", envelope.IdempotencyKey))
+
+		event := envelope.Event
+		err = fn(ctx, event.UserConfig, event.AccountId, event.EventType, event.Data)
+		tracing.EndSpan(span, err)
+		return err
+	}
+}
+
+// RouteEnvelope routes a notification sent in the envelope format
+func (n *NotificationRouter) RouteEnvelope(ctx context.Context, envelopeBytes []byte) error {
+	envelope, err := DecodeEnvelope(envelopeBytes)
+	if err != nil {
+		n.Logger.Errorw("Invalid task envelope", "error", err)
+		return EnvelopeError(err)
+	}
+	ObserveQueueLag(ctx, envelope)
+	event := envelope.Event
+	return n.RouteNotification(WithEnvelope(ctx, envelope), event.UserId, event.AccountId, event.EventType, event.Data)
+}
@@ -0,0 +1,205 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/google/uuid"
+)
+
+const TASK_ESCALATE = "task_escalate"
+
+// StartEscalation creates an escalation when the notification config has an escalation policy
+// and schedules its first step. It returns the data with the acknowledgement link added
+// so the first notification can already be acknowledged
+func (n *NotificationRouter) StartEscalation(ntConfig model.NotificationConfig, userConfig, accId, eventType string, dataBytes []byte) []byte {
+
+	steps, err := n.Db.GetEscalationSteps(ctx, ntConfig.ID)
+	if err != nil || len(steps) == 0 {
+		return dataBytes
+	}
+
+	userConfigId, err := strconv.ParseUint(userConfig, 10, 64)
+	if err != nil {
+		n.Logger.Errorw("Invalid userConfig for escalation", "userConfig", userConfig, "error", err)
+		return dataBytes
+	}
+
+	escalation := &model.Escalations{
+		UuId:                 uuid.New().String(),
+		NotificationConfigId: ntConfig.ID,
+		UserConfig:           userConfigId,
+		AccountId:            accId,
+		EventType:            eventType,
+		Status:               contract.ESCALATION_PENDING,
+	}
+	if err := n.Db.CreateEscalation(ctx, escalation); err != nil {
+		return dataBytes
+	}
+
+	dataBytes = AddAckUrl(dataBytes, GetAckUrl(contract.GetWorkerArgs().PublicUrl, escalation.UuId))
+
+	err = n.ScheduleEscalation(escalation.UuId, dataBytes, GetEscalationETA(time.Now(), steps[0].DelayMinutes))
+	if err != nil {
+		n.Logger.Errorw("Error while scheduling escalation", "escalationId", escalation.UuId, "error", err)
+	}
+	return dataBytes
+}
+
+// ScheduleEscalation sends the escalation task back to the master to run at eta
+func (n *NotificationRouter) ScheduleEscalation(escalationId string, dataBytes []byte, eta time.Time) error {
+
+	taskSignature := &tasks.Signature{
+		Name:       TASK_ESCALATE,
+		RoutingKey: n.WorkerConfig.AMQP.BindingKey,
+		ETA:        &eta,
+		Args: []tasks.Arg{
+			{
+				Name:  "escalationId",
+				Type:  "string",
+				Value: escalationId,
+			},
+			{
+				Name:  "dataBytes",
+				Type:  "[]byte",
+				Value: dataBytes,
+			},
+		},
+		RetryCount:   contract.TASK_RETRY_COUNT,
+		RetryTimeout: contract.TASK_RETRY_TIMEOUT,
+	}
+
+	_, err := n.MachineryServer.SendTask(taskSignature)
+	return err
+}
+
+// Escalate sends the current step of a pending escalation and schedules the next one.
+// Acknowledged or resolved escalations are dropped here
+func (n *NotificationRouter) Escalate(ctx context.Context, escalationId string, dataBytes []byte) error {
+
+	escalation, err := n.Db.GetEscalation(ctx, escalationId)
+	if err != nil {
+		return err
+	}
+
+	if escalation.Status != contract.ESCALATION_PENDING {
+		n.Logger.Infof("Escalation %v is %v. Stopping escalation", escalationId, escalation.Status)
+		return nil
+	}
+
+	steps, err := n.Db.GetEscalationSteps(ctx, escalation.NotificationConfigId)
+	if err != nil {
+		return err
+	}
+
+	if escalation.Step >= len(steps) {
+		return n.Db.ExhaustEscalation(ctx, escalationId)
+	}
+
+	step := steps[escalation.Step]
+	userConfig := fmt.Sprintf("%d", escalation.UserConfig)
+
+	if n.ShouldEscalate(ctx, escalation, step.NotificationType) {
+		n.Logger.Infof("Escalating %v to %v step:%v", escalationId, step.NotificationType, escalation.Step)
+		n.SendToSlave(ctx, step.NotificationType, escalation.EventType, userConfig, escalation.AccountId, dataBytes, nil)
+	}
+
+	next := escalation.Step + 1
+	ok, err := n.Db.UpdateEscalationStep(ctx, escalationId, next)
+	if err != nil || !ok {
+		return err
+	}
+
+	if next >= len(steps) {
+		return n.Db.ExhaustEscalation(ctx, escalationId)
+	}
+	return n.ScheduleEscalation(escalationId, dataBytes, GetEscalationETA(time.Now(), steps[next].DelayMinutes))
+}
+
+// ShouldEscalate checks a step against the notification config of its notification type for the event.
+// A step to a notification type without a config for the event is checked against the escalated config
+func (n *NotificationRouter) ShouldEscalate(ctx context.Context, escalation *model.Escalations, notificationType string) bool {
+
+	userConfig := fmt.Sprintf("%d", escalation.UserConfig)
+	userId, err := n.Db.GetUserIdByConfigId(ctx, userConfig)
+	if err != nil {
+		return false
+	}
+
+	configs, err := n.Db.GetEventNotificationConfigs(ctx, userConfig, escalation.EventType)
+	if err != nil {
+		return false
+	}
+	notificationConfigId := escalation.NotificationConfigId
+	for _, v := range configs {
+		if strings.EqualFold(v.NotificationType, notificationType) {
+			if !v.Enabled {
+				return false
+			}
+			notificationConfigId = v.ID
+			break
+		}
+	}
+
+	accountConfId := ""
+	if escalation.AccountId != "" {
+		configIds, err := n.Db.GetUserConfig(ctx, escalation.AccountId)
+		if err != nil {
+			return false
+		}
+		accountConfId = configIds.AccountConfId
+	}
+
+	disabled, err := n.Db.IsAccountNotificationDisabled(ctx, accountConfId, fmt.Sprintf("%d", notificationConfigId))
+	if err != nil || disabled {
+		return false
+	}
+
+	status, err := n.Db.GetIntegrationStatus(ctx, &nm.IntegrationStatusReq{UserId: userId})
+	if err != nil {
+		return false
+	}
+	return IsUserConfigEnabled(status, notificationType)
+}
+
+// ResolveEscalations stops the escalations of the events that eventType resolves
+func (n *NotificationRouter) ResolveEscalations(userConfig, accId, eventType string) {
+	events, ok := contract.ResolvingEvents[eventType]
+	if !ok || accId == "" {
+		return
+	}
+
+	err := n.Db.ResolveEscalations(ctx, userConfig, accId, events)
+	if err != nil {
+		n.Logger.Errorw("Error while resolving escalations", "accountId", accId, "eventType", eventType, "error", err)
+	}
+}
+
+func GetEscalationETA(now time.Time, delayMinutes int) time.Time {
+	return now.UTC().Add(time.Duration(delayMinutes) * time.Minute)
+}
+
+func GetAckUrl(publicUrl, escalationId string) string {
+	return fmt.Sprintf("%s/escalations/%s/ack", strings.TrimRight(publicUrl, "/"), escalationId)
+}
+
+// AddAckUrl adds the acknowledgement link to the notification data
+func AddAckUrl(dataBytes []byte, ackUrl string) []byte {
+	data := map[string]string{}
+	_ = json.Unmarshal(dataBytes, &data)
+	data[contract.ACK_URL] = ackUrl
+
+	newDataBytes, err := json.Marshal(data)
+	if err != nil {
+		return dataBytes
+	}
+	return newDataBytes
+}
@@ -0,0 +1,237 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/filter"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+)
+
+// RouteInput is everything the master reads from the db to route an event
+type RouteInput struct {
+	Configs     []model.NotificationConfig
+	Blocked     map[uint64]bool // Notification configs the account disabled
+	Integration *nm.IntegrationStatusReply
+	Chain       []model.FallbackRules
+	Workers     map[string]bool // Notification types with an available slave
+	Bots        map[uint64][]model.BotRouteCandidate
+	Data        map[string]string
+}
+
+// ExplainRoute reads what the master would read for the event and explains the routing without sending it
+func ExplainRoute(ctx context.Context, database db.DB, req *pb.ExplainRouteReq) (*pb.ExplainRouteReply, error) {
+
+	userId := req.UserId
+	configIds := contract.ConfigIds{}
+	if req.AccountId != "" {
+		ids, err := database.GetUserConfig(ctx, req.AccountId)
+		if err != nil || ids.UserConfigId == "" {
+			return nil, fmt.Errorf("account doesn't exist accountId: %v", req.AccountId)
+		}
+		if userId != "" && ids.UserId != userId {
+			return nil, fmt.Errorf("account %v doesn't belong to user %v", req.AccountId, userId)
+		}
+		configIds = ids
+		userId = ids.UserId
+	} else {
+		id, err := database.GetUserConfigId(ctx, userId)
+		if err != nil {
+			return nil, fmt.Errorf("user doesn't exist userId: %v", userId)
+		}
+		configIds.UserConfigId = fmt.Sprintf("%d", *id)
+	}
+
+	integration, err := database.GetIntegrationStatus(ctx, &nm.IntegrationStatusReq{UserId: userId})
+	if err != nil {
+		return nil, err
+	}
+
+	configs, err := database.GetEventNotificationConfigs(ctx, configIds.UserConfigId, req.EventType)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := database.GetFallbackChain(ctx, configIds.UserConfigId, req.EventType)
+	if err != nil {
+		return nil, err
+	}
+
+	workerMeta, err := database.GetWorkerMeta()
+	if err != nil {
+		return nil, err
+	}
+	heartbeats, err := database.GetWorkerHeartbeats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	workers := map[string]bool{}
+	for i, v := range GetWorkerHealth(workerMeta, heartbeats, time.Now()) {
+		if workerMeta[i].WorkerType != contract.MASTER {
+			workers[v.NotificationType] = v.Status != contract.WORKER_UNAVAILABLE
+		}
+	}
+
+	input := RouteInput{
+		Configs:     configs,
+		Blocked:     map[uint64]bool{},
+		Integration: integration,
+		Chain:       chain,
+		Workers:     workers,
+		Bots:        map[uint64][]model.BotRouteCandidate{},
+		Data:        req.Data,
+	}
+	for _, v := range configs {
+		if configIds.AccountConfId != "" {
+			blocked, err := database.IsAccountNotificationDisabled(ctx, configIds.AccountConfId, fmt.Sprintf("%d", v.ID))
+			if err != nil {
+				return nil, err
+			}
+			input.Blocked[v.ID] = blocked
+		}
+		if IsBotNotificationType(v.NotificationType) {
+			bots, err := database.GetBotRouteCandidates(ctx, v.ID)
+			if err != nil {
+				return nil, err
+			}
+			input.Bots[v.ID] = bots
+		}
+	}
+
+	return &pb.ExplainRouteReply{
+		UserId:     userId,
+		AccountId:  req.AccountId,
+		EventType:  req.EventType,
+		Candidates: GetRouteCandidates(input),
+	}, nil
+}
+
+// GetRouteCandidates applies the checks of DispatchNotification in the same order
+// and returns whether each notification config and bot chat would be sent
+func GetRouteCandidates(input RouteInput) []*pb.RouteCandidate {
+
+	reasons := make([]string, len(input.Configs))
+	details := make([]string, len(input.Configs))
+	routable := map[string]bool{}
+
+	for i, v := range input.Configs {
+		switch {
+		case !v.Enabled:
+			reasons[i] = contract.ROUTE_CONFIG_DISABLED
+		case input.Blocked[v.ID]:
+			reasons[i] = contract.ROUTE_ACCOUNT_BLOCKED
+		case input.Integration == nil || !IsUserConfigEnabled(input.Integration, v.NotificationType):
+			reasons[i] = contract.ROUTE_INTEGRATION_DISABLED
+			details[i] = fmt.Sprintf("%v integration is disabled for the user", v.NotificationType)
+		case !MatchFilterExplained(v.FilterExpression, input.Data):
+			reasons[i] = contract.ROUTE_FILTERED
+			details[i] = fmt.Sprintf("event doesn't match %v", v.FilterExpression)
+		case IsBotNotificationType(v.NotificationType) && AllBotsFiltered(input.Bots[v.ID], input.Data):
+			reasons[i] = contract.ROUTE_FILTERED
+			details[i] = "event doesn't match the filter of any bot"
+		case routable[v.NotificationType]:
+			reasons[i] = contract.ROUTE_DUPLICATE
+			details[i] = fmt.Sprintf("another %v config of the event is routed", v.NotificationType)
+		default:
+			routable[v.NotificationType] = true
+		}
+	}
+
+	primary := GetPrimaryNotificationType(input.Chain, routable)
+
+	candidates := []*pb.RouteCandidate{}
+	for i, v := range input.Configs {
+		if reasons[i] == "" {
+			switch {
+			case !input.Workers[v.NotificationType]:
+				reasons[i] = contract.ROUTE_NO_WORKER
+				details[i] = fmt.Sprintf("no available slave worker for %v", v.NotificationType)
+			case IsInFallbackChain(input.Chain, v.NotificationType) && v.NotificationType != primary:
+				reasons[i] = contract.ROUTE_FALLBACK_STANDBY
+				details[i] = fmt.Sprintf("sent only if the fallback chain reaches it. Primary is %v", primary)
+			default:
+				reasons[i] = contract.ROUTE_SEND
+			}
+		}
+
+		if !IsBotNotificationType(v.NotificationType) {
+			candidates = append(candidates, &pb.RouteCandidate{
+				NotificationType:     v.NotificationType,
+				NotificationConfigId: v.ID,
+				Send:                 reasons[i] == contract.ROUTE_SEND,
+				Reason:               reasons[i],
+				Detail:               details[i],
+			})
+			continue
+		}
+
+		bots := input.Bots[v.ID]
+		if len(bots) == 0 {
+			candidates = append(candidates, &pb.RouteCandidate{
+				NotificationType:     v.NotificationType,
+				NotificationConfigId: v.ID,
+				Reason:               contract.ROUTE_NO_BOT,
+				Detail:               "no bot is linked to the event",
+			})
+			continue
+		}
+
+		for _, bot := range bots {
+			reason, detail := reasons[i], details[i]
+			if reason == contract.ROUTE_SEND || reason == contract.ROUTE_FALLBACK_STANDBY {
+				reason, detail = ExplainBot(bot, input.Data, reason, detail)
+			}
+			candidates = append(candidates, &pb.RouteCandidate{
+				NotificationType:     v.NotificationType,
+				NotificationConfigId: v.ID,
+				BotConfigId:          bot.BotConfigId,
+				BotName:              bot.BotName,
+				ChannelName:          bot.ChannelName,
+				ChannelId:            bot.ChannelId,
+				Send:                 reason == contract.ROUTE_SEND,
+				Reason:               reason,
+				Detail:               detail,
+			})
+		}
+	}
+	return candidates
+}
+
+// ExplainBot applies the checks of the bot slaves to a bot chat of a routed config
+func ExplainBot(bot model.BotRouteCandidate, data map[string]string, reason, detail string) (string, string) {
+	switch {
+	case !bot.BotEnabled:
+		return contract.ROUTE_BOT_DISABLED, fmt.Sprintf("bot %v is disabled", bot.BotName)
+	case bot.ChannelId == "":
+		return contract.ROUTE_NO_CHANNEL, fmt.Sprintf("bot %v has no channel for the event", bot.BotName)
+	case !bot.ChannelEnabled:
+		return contract.ROUTE_CHANNEL_BLOCKED, fmt.Sprintf("channel %v is disabled", bot.ChannelName)
+	case !MatchFilterExplained(bot.FilterExpression, data):
+		return contract.ROUTE_FILTERED, fmt.Sprintf("event doesn't match %v", bot.FilterExpression)
+	}
+	return reason, detail
+}
+
+func AllBotsFiltered(bots []model.BotRouteCandidate, data map[string]string) bool {
+	for _, v := range bots {
+		if MatchFilterExplained(v.FilterExpression, data) {
+			return false
+		}
+	}
+	return len(bots) > 0
+}
+
+// MatchFilterExplained fails open like MatchFilter
+func MatchFilterExplained(expr string, data map[string]string) bool {
+	match, err := filter.Match(expr, data)
+	return err != nil || match
+}
+
+func IsBotNotificationType(notificationType string) bool {
+	return notificationType == contract.TELEGRAM || notificationType == contract.DISCORD
+}
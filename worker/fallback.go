@@ -0,0 +1,138 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/tracing"
+)
+
+const TASK_ROUTE_FALLBACK = "task_route_fallback"
+
+// GetChainId returns the fallback chain id the master attached to the task.
+// Empty chain id means the event has no fallback chain
+func GetChainId(ctx context.Context) string {
+	signature := tasks.SignatureFromContext(ctx)
+	if signature == nil {
+		return ""
+	}
+	chainId, _ := signature.Headers[contract.HEADER_CHAIN_ID].(string)
+	return chainId
+}
+
+// IsLastAttempt reports whether machinery will not retry the task again if it fails
+func IsLastAttempt(ctx context.Context) bool {
+	signature := tasks.SignatureFromContext(ctx)
+	return signature == nil || signature.RetryCount <= 0
+}
+
+// Fallback hands a permanently failed delivery back to the master
+// so it can be routed to the next channel of the fallback chain. It reports whether the master got it
+func (w *Worker) Fallback(ctx context.Context, userConfig, accId, eventType string, data []byte, failedNotificationType string, lastErr error) bool {
+	chainId := GetChainId(ctx)
+	if chainId == "" {
+		return false
+	}
+	return w.SendFallback(ctx, chainId, userConfig, accId, eventType, data, failedNotificationType, lastErr)
+}
+
+// GetLastError returns the error the slave attached to the fallback task
+func GetLastError(ctx context.Context) string {
+	signature := tasks.SignatureFromContext(ctx)
+	if signature == nil {
+		return ""
+	}
+	lastErr, _ := signature.Headers[contract.HEADER_LAST_ERROR].(string)
+	return lastErr
+}
+
+// SendFallback sends the route fallback task of the chain to the master
+func (w *Worker) SendFallback(ctx context.Context, chainId, userConfig, accId, eventType string, data []byte, failedNotificationType string, lastErr error) bool {
+	master := GetMasterWorker(w.Db)
+	if master == nil {
+		w.Logger.Errorw("Master worker is not registered. Fallback dropped", "chainId", chainId, "notificationType", failedNotificationType)
+		return false
+	}
+
+	taskSignature := &tasks.Signature{
+		Name:       TASK_ROUTE_FALLBACK,
+		RoutingKey: master.WorkerConfig.AMQP.BindingKey,
+		Headers:    tasks.Headers{contract.HEADER_CHAIN_ID: chainId},
+		Args: []tasks.Arg{
+			{
+				Name:  "userConfig",
+				Type:  "string",
+				Value: userConfig,
+			},
+			{
+				Name:  "accountId",
+				Type:  "string",
+				Value: accId,
+			},
+			{
+				Name:  "eventType",
+				Type:  "string",
+				Value: eventType,
+			},
+			{
+				Name:  "dataBytes",
+				Type:  "[]byte",
+				Value: data,
+			},
+			{
+				Name:  "failedNotificationType",
+				Type:  "string",
+				Value: failedNotificationType,
+			},
+		},
+		RetryCount:   contract.TASK_RETRY_COUNT,
+		RetryTimeout: contract.TASK_RETRY_TIMEOUT,
+	}
+
+	taskSignature.Headers = MergeHeaders(taskSignature.Headers, NotificationHeaders(ctx))
+	if lastErr != nil {
+		taskSignature.Headers[contract.HEADER_LAST_ERROR] = lastErr.Error()
+	}
+	if traceContext := tracing.Inject(ctx); traceContext != nil {
+		taskSignature.Headers[tracing.TRACEPARENT] = traceContext[tracing.TRACEPARENT]
+	}
+
+	_, err := master.MachineryServer.SendTask(taskSignature)
+	if err != nil {
+		w.Logger.Errorw("Error while sending fallback task to master", "chainId", chainId, "error", err)
+		return false
+	}
+	w.Logger.Infof("Delivery through %v failed. Sent to master for fallback chainId:%v", failedNotificationType, chainId)
+	return true
+}
+
+// GetPrimaryNotificationType returns the first notification type of the chain that can be routed
+func GetPrimaryNotificationType(chain []model.FallbackRules, routable map[string]bool) string {
+	for _, v := range chain {
+		if routable[v.NotificationType] {
+			return v.NotificationType
+		}
+	}
+	return ""
+}
+
+// GetNextFallbackRules returns the rules that come after the failed notification type
+func GetNextFallbackRules(chain []model.FallbackRules, failedNotificationType string) []model.FallbackRules {
+	for i, v := range chain {
+		if v.NotificationType == failedNotificationType {
+			return chain[i+1:]
+		}
+	}
+	return []model.FallbackRules{}
+}
+
+func IsInFallbackChain(chain []model.FallbackRules, notificationType string) bool {
+	for _, v := range chain {
+		if v.NotificationType == notificationType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/devshahriar/notification-manager/tracing"
+	"google.golang.org/protobuf/proto"
+)
+
+// GetTarget returns the destination a fan-out narrowed the task down to. It is nil for tasks that weren't fanned out
+func GetTarget(ctx context.Context) *pb.TaskTarget {
+	return EnvelopeFromContext(ctx).GetTarget()
+}
+
+// GetTargetEnvelope derives the envelope of a destination of the fan-out of parent
+func GetTargetEnvelope(parent *pb.TaskEnvelope, target *pb.TaskTarget) *pb.TaskEnvelope {
+	envelope := proto.Clone(parent).(*pb.TaskEnvelope)
+	envelope.Target = target
+	envelope.IdempotencyKey = fmt.Sprintf("%v:%v", parent.GetIdempotencyKey(), GetTargetDestination(target))
+	return envelope
+}
+
+// GetTargetDestination identifies the target among the destinations of a task
+func GetTargetDestination(target *pb.TaskTarget) string {
+	if target.BotConfigId != 0 {
+		return GetBotDestination(target.BotConfigId, target.Destination)
+	}
+	return target.Destination
+}
+
+// GetEmailTargets returns a target per recipient
+func GetEmailTargets(emails []string) []*pb.TaskTarget {
+	targets := []*pb.TaskTarget{}
+	for _, v := range emails {
+		targets = append(targets, &pb.TaskTarget{Destination: v})
+	}
+	return targets
+}
+
+// GetBotTargets returns a target per bot channel the master didn't filter out
+func GetBotTargets(ctx context.Context, ntMeta []model.BotNotificationMeta) []*pb.TaskTarget {
+	targets := []*pb.TaskTarget{}
+	for _, v := range ntMeta {
+		if IsBotFiltered(ctx, v.BotConfigId) {
+			continue
+		}
+		targets = append(targets, &pb.TaskTarget{Destination: v.ChannelId, BotConfigId: v.BotConfigId})
+	}
+	return targets
+}
+
+// GetTargetBots returns the bot channel of the target
+func GetTargetBots(ntMeta []model.BotNotificationMeta, target *pb.TaskTarget) []model.BotNotificationMeta {
+	for _, v := range ntMeta {
+		if v.BotConfigId == target.BotConfigId && v.ChannelId == target.Destination {
+			return []model.BotNotificationMeta{v}
+		}
+	}
+	return nil
+}
+
+// FanOut publishes a task per target to the queue of the task being processed so every destination
+// is retried, rate limited and logged on its own. It reports whether the task was fanned out.
+// Only envelope tasks with several destinations are fanned out
+func (w *Worker) FanOut(ctx context.Context, notificationType string, targets []*pb.TaskTarget) bool {
+	envelope := EnvelopeFromContext(ctx)
+	signature := tasks.SignatureFromContext(ctx)
+	if len(targets) < 2 || envelope == nil || signature == nil || envelope.Target != nil || envelope.Resend != nil {
+		return false
+	}
+	if _, parked := signature.Headers[contract.HEADER_PARKED_DESTINATIONS]; parked {
+		return false
+	}
+	logger := tracing.Logger(ctx, w.Logger)
+
+	event := envelope.Event
+	published := 0
+	for _, target := range targets {
+		target.FanOutId = envelope.IdempotencyKey
+		target.FanOutTotal = int32(len(targets))
+		child := GetTargetEnvelope(envelope, target)
+		child.TraceContext = tracing.Inject(ctx)
+		childCtx := WithEnvelope(ctx, child)
+
+		task, err := GetEnvelopeTask(signature.Name, signature.RoutingKey, child)
+		if err != nil {
+			logger.Errorw("Error while encoding fan-out envelope", "destination", GetTargetDestination(target), "error", err)
+			w.finishDelivery(childCtx, nil, notificationType, event.UserConfig, event.AccountId, event.EventType, event.Data, 0, 1, err)
+			continue
+		}
+		task.Headers = MergeHeaders(signature.Headers)
+
+		if _, err := w.MachineryServer.SendTask(task); err != nil {
+			logger.Errorw("Error while fanning out destination", "destination", GetTargetDestination(target), "error", err)
+			w.finishDelivery(childCtx, task, notificationType, event.UserConfig, event.AccountId, event.EventType, event.Data, 0, 1, err)
+			continue
+		}
+		published++
+	}
+
+	w.RecordTransition(ctx, notificationType, contract.NOTIFICATION_ROUTED, fmt.Sprintf("fanned out to %d of %d destinations", published, len(targets)))
+	return true
+}
+
+// FinishDelivery records the delivery of the task being processed. When nothing was sent it falls back
+// to the next channel of the chain, or dead-letters the task without one
+func (w *Worker) FinishDelivery(ctx context.Context, notificationType, userConfig, accId, eventType string, data []byte, sent, attempts int, lastErr error) {
+	w.finishDelivery(ctx, tasks.SignatureFromContext(ctx), notificationType, userConfig, accId, eventType, data, sent, attempts, lastErr)
+}
+
+func (w *Worker) finishDelivery(ctx context.Context, signature *tasks.Signature, notificationType, userConfig, accId, eventType string, data []byte, sent, attempts int, lastErr error) {
+	w.RecordDelivery(ctx, notificationType, sent, attempts, lastErr)
+
+	noneSent := w.FinishFanOut(ctx, notificationType, sent > 0)
+	if sent > 0 {
+		return
+	}
+
+	//Only the last destination of a fan-out falls back, once none of them was sent
+	if GetChainId(ctx) != "" && (!noneSent || w.Fallback(ctx, userConfig, accId, eventType, data, notificationType, lastErr)) {
+		return
+	}
+
+	if attempts > 0 && signature != nil {
+		if lastErr == nil {
+			lastErr = errors.New("delivery failed")
+		}
+		w.DeadLetter(ctx, w.Meta(), signature, lastErr)
+	}
+}
+
+// FinishFanOut counts the destination of the task in its fan-out. It reports whether nothing was sent:
+// to the destination, or to the whole fan-out once its last destination finished.
+// Fan-outs are only counted for events with a fallback chain, otherwise every destination is on its own
+func (w *Worker) FinishFanOut(ctx context.Context, notificationType string, sent bool) bool {
+	target := GetTarget(ctx)
+	if target == nil || target.FanOutId == "" || GetChainId(ctx) == "" {
+		return !sent
+	}
+
+	fanOut, err := w.Db.FinishFanOut(ctx, model.FanOuts{
+		FanOutId:         target.FanOutId,
+		NotificationId:   GetNotificationId(ctx),
+		NotificationType: notificationType,
+		Total:            int(target.FanOutTotal),
+	}, sent)
+	if err != nil {
+		//Falling back twice is better than not falling back
+		return !sent
+	}
+	return fanOut.Done >= fanOut.Total && fanOut.Sent == 0
+}
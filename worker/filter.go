@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+)
+
+// FilterNotification evaluates the filter expressions of the notification config and its bot event rules
+// against the event data before dispatch. It returns false when nothing should be dispatched
+// and the headers listing the bots whose filter didn't match
+func (n *NotificationRouter) FilterNotification(plan model.RoutingPlan, ntConfig model.NotificationConfig, data map[string]string) (tasks.Headers, bool) {
+
+	if !MatchFilter(plan, ntConfig.FilterExpression, data) {
+		n.Logger.Infof("Event doesn't match the filter of notificationConfig:%v", ntConfig.ID)
+		return nil, false
+	}
+
+	if ntConfig.NotificationType != contract.TELEGRAM && ntConfig.NotificationType != contract.DISCORD {
+		return nil, true
+	}
+
+	rules := plan.BotFilters[ntConfig.ID]
+	if len(rules) == 0 {
+		return nil, true
+	}
+
+	filtered := []string{}
+	for _, v := range rules {
+		if !MatchFilter(plan, v.FilterExpression, data) {
+			filtered = append(filtered, fmt.Sprintf("%d", v.BotConfigId))
+		}
+	}
+
+	if len(filtered) == 0 {
+		return nil, true
+	}
+	if len(filtered) == len(rules) {
+		n.Logger.Infof("Event doesn't match the filter of any bot for notificationConfig:%v", ntConfig.ID)
+		return nil, false
+	}
+	return tasks.Headers{contract.HEADER_FILTERED_BOTS: strings.Join(filtered, ",")}, true
+}
+
+// MatchFilter evaluates the expression parsed in the routing plan. It fails open as expressions are validated when saved
+func MatchFilter(plan model.RoutingPlan, expr string, data map[string]string) bool {
+	node := plan.Filters[expr]
+	if node == nil {
+		return true
+	}
+	return node.Eval(data)
+}
+
+// IsBotFiltered reports whether the master filtered the bot out of this task
+func IsBotFiltered(ctx context.Context, botConfigId uint64) bool {
+	signature := tasks.SignatureFromContext(ctx)
+	if signature == nil {
+		return false
+	}
+	filtered, _ := signature.Headers[contract.HEADER_FILTERED_BOTS].(string)
+	for _, v := range strings.Split(filtered, ",") {
+		if v == fmt.Sprintf("%d", botConfigId) {
+			return true
+		}
+	}
+	return false
+}
+
+func MergeHeaders(headers ...tasks.Headers) tasks.Headers {
+	merged := tasks.Headers{}
+	for _, h := range headers {
+		for k, v := range h {
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,235 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/google/uuid"
+)
+
+const TASK_RELEASE_DISCONNECT = "task_release_disconnect"
+
+type FlapDecision string
+
+const (
+	FLAP_DISPATCH FlapDecision = "DISPATCH" // Route the event now
+	FLAP_HOLD     FlapDecision = "HOLD"     // Hold the disconnect for the grace period
+	FLAP_SUPPRESS FlapDecision = "SUPPRESS" // Drop the event
+	FLAP_SUMMARY  FlapDecision = "SUMMARY"  // Send a single connection unstable notification instead
+)
+
+// SuppressFlap runs connection status events through the flap suppressor.
+// It returns true when the event must not be routed now
+func (n *NotificationRouter) SuppressFlap(ctx context.Context, userConfigId contract.ConfigIds, accId, eventType string, dataBytes []byte) bool {
+
+	if accId == "" || GetConnectionState(eventType) == "" {
+		return false
+	}
+
+	settings, err := n.Db.GetFlapSettingsByUserConfig(ctx, userConfigId.UserConfigId)
+	if err != nil || !settings.Enabled {
+		return false
+	}
+
+	pendingId := uuid.New().String()
+	var decision FlapDecision
+	var transitions int
+	err = n.Db.UpdateConnectionState(ctx, accId, func(state *model.ConnectionStates) error {
+		decision = EvaluateFlap(state, settings, eventType, time.Now(), pendingId)
+		transitions = state.Transitions
+		return nil
+	})
+	if err != nil {
+		n.Logger.Errorw("Error while updating connection state. Routing without flap suppression", "accountId", accId, "error", err)
+		return false
+	}
+
+	n.Logger.Infof("Flap suppression decision for accountId:%v eventType:%v decision:%v", accId, eventType, decision)
+
+	switch decision {
+	case FLAP_HOLD:
+		eta := time.Now().UTC().Add(time.Duration(settings.GracePeriodSeconds) * time.Second)
+		err := n.ScheduleReleaseDisconnect(ctx, pendingId, accId, eventType, dataBytes, eta)
+		if err != nil {
+			n.Logger.Errorw("Error while holding disconnect. Routing it now", "accountId", accId, "error", err)
+			n.CancelPendingDisconnect(accId, pendingId)
+			return false
+		}
+		n.RecordTransition(ctx, "", contract.NOTIFICATION_SKIPPED, "held for the flap grace period")
+		return true
+
+	case FLAP_SUPPRESS:
+		n.RecordTransition(ctx, "", contract.NOTIFICATION_SKIPPED, "suppressed as flapping")
+		return true
+
+	case FLAP_SUMMARY:
+		summary := GetUnstableSummaryData(dataBytes, transitions, settings.FlapWindowSeconds)
+		err := n.DispatchNotification(ctx, userConfigId, accId, nm.EventType_ACCOUNT_CONNECTION_ERROR.String(), summary)
+		if err != nil {
+			n.Logger.Errorw("Error while sending connection unstable notification", "accountId", accId, "error", err)
+		}
+		return true
+	}
+
+	return false
+}
+
+// ScheduleReleaseDisconnect sends the held disconnect back to the master once the grace period is over
+func (n *NotificationRouter) ScheduleReleaseDisconnect(ctx context.Context, pendingId, accId, eventType string, dataBytes []byte, eta time.Time) error {
+
+	taskSignature := &tasks.Signature{
+		Name:       TASK_RELEASE_DISCONNECT,
+		RoutingKey: n.WorkerConfig.AMQP.BindingKey,
+		ETA:        &eta,
+		Headers:    NotificationHeaders(ctx),
+		Args: []tasks.Arg{
+			{
+				Name:  "pendingId",
+				Type:  "string",
+				Value: pendingId,
+			},
+			{
+				Name:  "accountId",
+				Type:  "string",
+				Value: accId,
+			},
+			{
+				Name:  "eventType",
+				Type:  "string",
+				Value: eventType,
+			},
+			{
+				Name:  "dataBytes",
+				Type:  "[]byte",
+				Value: dataBytes,
+			},
+		},
+		RetryCount:   contract.TASK_RETRY_COUNT,
+		RetryTimeout: contract.TASK_RETRY_TIMEOUT,
+	}
+
+	_, err := n.MachineryServer.SendTask(taskSignature)
+	return err
+}
+
+// ReleaseDisconnect routes a held disconnect unless a reconnect or flapping cancelled it
+func (n *NotificationRouter) ReleaseDisconnect(ctx context.Context, pendingId, accId, eventType string, dataBytes []byte) error {
+
+	release := false
+	err := n.Db.UpdateConnectionState(ctx, accId, func(state *model.ConnectionStates) error {
+		if state.PendingDisconnectId == pendingId {
+			state.PendingDisconnectId = ""
+			release = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !release {
+		n.Logger.Infof("Held disconnect of accountId:%v was cancelled", accId)
+		n.RecordTransition(WithNotification(ctx, "", accId, eventType), "", contract.NOTIFICATION_SKIPPED, "cancelled by a reconnect")
+		return nil
+	}
+
+	userConfigId, err := n.Db.GetUserConfig(ctx, accId)
+	if err != nil {
+		return err
+	}
+
+	n.Logger.Infof("Releasing held disconnect of accountId:%v", accId)
+	ctx = WithNotification(ctx, userConfigId.UserId, accId, eventType)
+	return n.DispatchNotification(ctx, userConfigId, accId, eventType, dataBytes)
+}
+
+func (n *NotificationRouter) CancelPendingDisconnect(accId, pendingId string) {
+	err := n.Db.UpdateConnectionState(ctx, accId, func(state *model.ConnectionStates) error {
+		if state.PendingDisconnectId == pendingId {
+			state.PendingDisconnectId = ""
+		}
+		return nil
+	})
+	if err != nil {
+		n.Logger.Errorw("Error while cancelling held disconnect", "accountId", accId, "error", err)
+	}
+}
+
+// EvaluateFlap applies a connection event to the state of the account and decides what to do with the event.
+// Only changes of the connection state are counted towards the flap threshold
+func EvaluateFlap(state *model.ConnectionStates, settings model.FlapSettings, eventType string, now time.Time, pendingId string) FlapDecision {
+
+	newState := GetConnectionState(eventType)
+
+	window := time.Duration(settings.FlapWindowSeconds) * time.Second
+	if state.WindowStart.IsZero() || now.Sub(state.WindowStart) > window {
+		state.WindowStart = now
+		state.Transitions = 0
+		state.UnstableNotified = false
+	}
+
+	if state.State != "" && state.State != newState {
+		state.Transitions++
+	}
+	state.State = newState
+
+	if state.Transitions >= settings.FlapThreshold {
+		state.PendingDisconnectId = ""
+		if state.UnstableNotified {
+			return FLAP_SUPPRESS
+		}
+		state.UnstableNotified = true
+		return FLAP_SUMMARY
+	}
+
+	switch newState {
+	case contract.CONNECTION_DISCONNECTED:
+		if state.PendingDisconnectId != "" {
+			return FLAP_SUPPRESS
+		}
+		if settings.GracePeriodSeconds <= 0 {
+			return FLAP_DISPATCH
+		}
+		state.PendingDisconnectId = pendingId
+		return FLAP_HOLD
+
+	case contract.CONNECTION_CONNECTED:
+		//Reconnected within the grace period. Neither the disconnect nor the reconnect is sent
+		if state.PendingDisconnectId != "" {
+			state.PendingDisconnectId = ""
+			return FLAP_SUPPRESS
+		}
+	}
+	return FLAP_DISPATCH
+}
+
+func GetConnectionState(eventType string) string {
+	switch eventType {
+	case nm.EventType_ACCOUNT_CONNECTION_ERROR.String():
+		return contract.CONNECTION_DISCONNECTED
+	case nm.EventType_ACCOUNT_CONNECTED.String():
+		return contract.CONNECTION_CONNECTED
+	}
+	return ""
+}
+
+// GetUnstableSummaryData turns the event data into the connection unstable summary
+func GetUnstableSummaryData(dataBytes []byte, transitions, windowSeconds int) []byte {
+	data := map[string]string{}
+	_ = json.Unmarshal(dataBytes, &data)
+
+	data["CONNECTION_STATUS"] = contract.CONNECTION_UNSTABLE
+	data["CONNECTION_ERROR"] = fmt.Sprintf("Connection changed %d times in the last %d minutes. Connection events are paused until it is stable", transitions, windowSeconds/60)
+
+	summary, err := json.Marshal(data)
+	if err != nil {
+		return dataBytes
+	}
+	return summary
+}
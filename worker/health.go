@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// WorkerHealth is the health of a worker instance as served on /health
+type WorkerHealth struct {
+	Name         string            `json:"name"`
+	WorkerType   string            `json:"workerType"`
+	Status       string            `json:"status"`
+	Breakers     []BreakerStatus   `json:"breakers"`
+	Dependencies map[string]string `json:"dependencies"`
+}
+
+// Health reports the worker as degraded while a breaker is open. A degraded worker keeps
+// parking the tasks of the provider so it isn't restarted. It is unavailable while a dependency isn't serving
+func (w *Worker) Health() WorkerHealth {
+	health := WorkerHealth{
+		Name:         w.Name,
+		WorkerType:   w.WorkerType,
+		Status:       contract.WORKER_HEALTHY,
+		Breakers:     w.Breakers.Status(),
+		Dependencies: w.Dependencies.Errors(),
+	}
+	for _, v := range health.Breakers {
+		if v.State != contract.BREAKER_CLOSED {
+			health.Status = contract.WORKER_DEGRADED
+		}
+	}
+	if status, _ := w.Dependencies.Status(""); status != grpc_health_v1.HealthCheckResponse_SERVING {
+		health.Status = contract.WORKER_UNAVAILABLE
+	}
+	return health
+}
+
+// ServeHealth serves the health of the worker on /health, its readiness on /ready and its metrics on /metrics until ctx is done.
+// /health always answers 200 so the worker isn't restarted. /ready answers 503 while a dependency isn't serving
+func (w *Worker) ServeHealth(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/health", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(w.Health())
+	})
+	mux.HandleFunc("/ready", w.Dependencies.ServeReady)
+
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			w.Logger.Errorw("Error while serving worker health", "addr", addr, "error", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+}
@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/google/uuid"
+)
+
+// StartHeartbeat records the worker instance as alive every heartbeat interval until ctx is done
+func (w *Worker) StartHeartbeat(ctx context.Context) {
+	hostname, _ := os.Hostname()
+	heartbeat := &model.WorkerHeartbeats{
+		InstanceId:       fmt.Sprintf("%v-%v", hostname, uuid.New().String()[:8]),
+		WorkerName:       w.Name,
+		WorkerType:       w.WorkerType,
+		NotificationType: w.WorkerType,
+		Version:          contract.Version,
+	}
+
+	go func() {
+		ticker := time.NewTicker(contract.WORKER_HEARTBEAT_INTERVAL_SECONDS * time.Second)
+		defer ticker.Stop()
+		for {
+			heartbeat.Capacity = w.GetSettings().Concurrency
+			heartbeat.LastSeenAt = time.Now().UTC()
+			_ = w.Db.Heartbeat(ctx, heartbeat)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// GetWorkerHealth joins the registered workers with the heartbeats of their instances
+func GetWorkerHealth(workerMeta []contract.WorkerMeta, heartbeats []model.WorkerHeartbeats, now time.Time) []*pb.WorkerStatus {
+	timeout := contract.WORKER_HEARTBEAT_TIMEOUT_SECONDS * time.Second
+
+	instances := map[string][]*pb.WorkerInstance{}
+	for _, v := range heartbeats {
+		instances[v.WorkerName] = append(instances[v.WorkerName], &pb.WorkerInstance{
+			InstanceId: v.InstanceId,
+			Version:    v.Version,
+			Capacity:   int32(v.Capacity),
+			LastSeenAt: v.LastSeenAt.UTC().Format(time.RFC3339),
+			Alive:      now.Sub(v.LastSeenAt) <= timeout,
+		})
+	}
+
+	workers := []*pb.WorkerStatus{}
+	for _, v := range workerMeta {
+		status := contract.WORKER_UNKNOWN
+		if len(instances[v.Name]) > 0 {
+			status = contract.WORKER_UNAVAILABLE
+		}
+		for _, instance := range instances[v.Name] {
+			if instance.Alive {
+				status = contract.WORKER_HEALTHY
+				break
+			}
+		}
+
+		workers = append(workers, &pb.WorkerStatus{
+			Name:             v.Name,
+			WorkerType:       v.WorkerType,
+			NotificationType: v.NotificationType,
+			Queue:            v.Queue,
+			BindingKey:       v.BindingKey,
+			Status:           status,
+			Instances:        instances[v.Name],
+			Settings: &pb.WorkerSettings{
+				Name:                   v.Name,
+				Concurrency:            int32(v.Concurrency),
+				PrefetchCount:          int32(v.PrefetchCount),
+				ProviderTimeoutSeconds: int32(v.ProviderTimeoutSeconds),
+				RateLimit:              int32(v.RateLimit),
+			},
+		})
+	}
+	return workers
+}
@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+)
+
+// RouteNotifications routes a chunk of batch ingested notifications.
+// A failed event is logged and doesn't fail the chunk so the rest isn't routed twice on retry
+func (n *NotificationRouter) RouteNotifications(ctx context.Context, eventsBytes []byte) error {
+
+	var events []contract.NotificationEvent
+	if err := json.Unmarshal(eventsBytes, &events); err != nil {
+		n.Logger.Errorw("Error while decoding notification chunk", "error", err)
+		return err
+	}
+
+	n.Logger.Infof("Routing chunk of %d notifications", len(events))
+
+	for _, v := range events {
+		dataBytes, err := json.Marshal(v.Data)
+		if err != nil {
+			n.Logger.Errorw("Error while encoding notification data", "accountId", v.AccountId, "error", err)
+			continue
+		}
+		eventCtx := ctx
+		if v.NotificationId != "" {
+			envelope := NewEnvelope(v.UserId, v.AccountId, v.EventType, dataBytes)
+			envelope.NotificationId = v.NotificationId
+			envelope.IdempotencyKey = v.NotificationId
+			eventCtx = WithEnvelope(ctx, envelope)
+		}
+		err = n.RouteNotification(eventCtx, v.UserId, v.AccountId, v.EventType, dataBytes)
+		if err != nil {
+			n.Logger.Errorw("Error while routing notification of chunk", "accountId", v.AccountId, "eventType", v.EventType, "error", err)
+		}
+	}
+	return nil
+}
+
+func GetRouteNotificationsTask(bindingKey string, eventsBytes []byte) *tasks.Signature {
+	return &tasks.Signature{
+		Name:       contract.TASK_ROUTE_NOTIFICATIONS,
+		RoutingKey: bindingKey,
+		Args: []tasks.Arg{
+			{
+				Name:  "eventsBytes",
+				Type:  "[]byte",
+				Value: eventsBytes,
+			},
+		},
+		RetryCount:   contract.TASK_RETRY_COUNT,
+		RetryTimeout: contract.TASK_RETRY_TIMEOUT,
+	}
+}
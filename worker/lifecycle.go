@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+)
+
+// GetNotificationId returns the id of the notification the task belongs to
+func GetNotificationId(ctx context.Context) string {
+	return EnvelopeFromContext(ctx).GetNotificationId()
+}
+
+// WithNotification attaches the notification id of the task headers to ctx. Fallbacks and held
+// disconnects are sent without envelope and carry the id of the notification they belong to in a header
+func WithNotification(ctx context.Context, userId, accId, eventType string) context.Context {
+	if EnvelopeFromContext(ctx) != nil {
+		return ctx
+	}
+	signature := tasks.SignatureFromContext(ctx)
+	if signature == nil {
+		return ctx
+	}
+	notificationId, _ := signature.Headers[contract.HEADER_NOTIFICATION_ID].(string)
+	if notificationId == "" {
+		return ctx
+	}
+	return WithEnvelope(ctx, &pb.TaskEnvelope{
+		Version:        contract.TASK_ENVELOPE_VERSION,
+		NotificationId: notificationId,
+		IdempotencyKey: notificationId,
+		Event:          &pb.TaskEvent{UserId: userId, AccountId: accId, EventType: eventType},
+	})
+}
+
+// NotificationHeaders returns the task headers carrying the notification id of ctx
+func NotificationHeaders(ctx context.Context) tasks.Headers {
+	headers := tasks.Headers{}
+	if notificationId := GetNotificationId(ctx); notificationId != "" {
+		headers[contract.HEADER_NOTIFICATION_ID] = notificationId
+	}
+	return headers
+}
+
+// RecordTransition records a lifecycle state of the notification of ctx.
+// Tasks without an envelope, like broadcasts and escalations, are not tracked
+func (w *Worker) RecordTransition(ctx context.Context, notificationType, state, reason string) {
+	countSkipped(state, reason)
+
+	envelope := EnvelopeFromContext(ctx)
+	if envelope == nil {
+		return
+	}
+	transition := NewTransition(envelope, notificationType, state, reason)
+	if err := w.Db.RecordNotificationTransitions(ctx, []model.NotificationTransitions{transition}); err != nil {
+		w.Logger.Errorw("Error while recording notification transition", "notificationId", envelope.NotificationId, "state", state, "error", err)
+	}
+}
+
+// RecordDelivery records the outcome of a slave task that tried attempts recipients
+func (w *Worker) RecordDelivery(ctx context.Context, notificationType string, sent, attempts int, err error) {
+	switch {
+	case sent > 0:
+		w.RecordTransition(ctx, notificationType, contract.NOTIFICATION_SENT, fmt.Sprintf("sent to %d of %d recipients", sent, attempts))
+	case attempts == 0:
+		w.RecordTransition(ctx, notificationType, contract.NOTIFICATION_SKIPPED, "no recipient")
+	case err != nil:
+		w.RecordTransition(ctx, notificationType, contract.NOTIFICATION_FAILED, err.Error())
+	default:
+		w.RecordTransition(ctx, notificationType, contract.NOTIFICATION_FAILED, "delivery failed")
+	}
+}
+
+func NewTransition(envelope *pb.TaskEnvelope, notificationType, state, reason string) model.NotificationTransitions {
+	event := envelope.GetEvent()
+	return model.NotificationTransitions{
+		NotificationId:   envelope.GetNotificationId(),
+		NotificationType: notificationType,
+		State:            state,
+		Reason:           reason,
+		UserId:           event.GetUserId(),
+		UserConfig:       event.GetUserConfig(),
+		AccountId:        event.GetAccountId(),
+		EventType:        event.GetEventType(),
+	}
+}
+
+// GetNotificationStatus builds the fan-out tree of a notification from its transitions and delivery logs
+func GetNotificationStatus(notificationId string, transitions []model.NotificationTransitions, logs []model.Logs) *pb.NotificationStatus {
+	status := &pb.NotificationStatus{NotificationId: notificationId}
+	destinations := map[string]*pb.DestinationStatus{}
+
+	destination := func(notificationType string) *pb.DestinationStatus {
+		d, ok := destinations[notificationType]
+		if !ok {
+			d = &pb.DestinationStatus{NotificationType: notificationType}
+			destinations[notificationType] = d
+			status.Destinations = append(status.Destinations, d)
+		}
+		return d
+	}
+
+	for _, v := range transitions {
+		transition := &pb.NotificationTransition{State: v.State, Reason: v.Reason, CreatedAt: v.CreatedAt.Format(time.RFC3339)}
+
+		if status.UserId == "" {
+			status.UserId = v.UserId
+		}
+		if status.AccountId == "" {
+			status.AccountId = v.AccountId
+		}
+		if status.EventType == "" {
+			status.EventType = v.EventType
+		}
+
+		if v.NotificationType == "" {
+			status.Transitions = append(status.Transitions, transition)
+			status.State, status.Reason = v.State, v.Reason
+			continue
+		}
+
+		d := destination(v.NotificationType)
+		d.Transitions = append(d.Transitions, transition)
+		//Destinations of a fan-out finish in any order. A channel that sent to one of them stays SENT
+		if d.State != contract.NOTIFICATION_SENT {
+			d.State, d.Reason = v.State, v.Reason
+		}
+	}
+
+	for _, v := range logs {
+		if v.NotificationType == "" {
+			continue
+		}
+		d := destination(v.NotificationType)
+		d.Attempts = append(d.Attempts, &pb.DeliveryAttempt{
+			Status:       v.Status,
+			CreatedAt:    v.CreatedAt.Format(time.RFC3339),
+			ChainId:      v.ChainId,
+			FallbackFrom: v.FallbackFrom,
+		})
+	}
+
+	//Destinations routed before the lifecycle was tracked only have delivery logs
+	for _, d := range status.Destinations {
+		if d.State == "" && len(d.Attempts) > 0 {
+			d.State = contract.NOTIFICATION_FAILED
+			if d.Attempts[len(d.Attempts)-1].Status == contract.STATUS_SUCCESS {
+				d.State = contract.NOTIFICATION_SENT
+			}
+		}
+	}
+
+	if state := GetFanOutState(status.Destinations); state != "" {
+		status.State = state
+	}
+	return status
+}
+
+// GetFanOutState is ROUTED or RETRYING while a destination is in flight, then SENT when any destination was sent,
+// DEAD_LETTERED when any waits for a replay, FAILED when any failed and SKIPPED otherwise. It is empty without destinations
+func GetFanOutState(destinations []*pb.DestinationStatus) string {
+	if len(destinations) == 0 {
+		return ""
+	}
+	states := map[string]bool{}
+	for _, v := range destinations {
+		states[v.State] = true
+	}
+	for _, state := range []string{contract.NOTIFICATION_ROUTED, contract.NOTIFICATION_RETRYING, contract.NOTIFICATION_SENT, contract.NOTIFICATION_DEAD_LETTERED, contract.NOTIFICATION_FAILED} {
+		if states[state] {
+			return state
+		}
+	}
+	return contract.NOTIFICATION_SKIPPED
+}
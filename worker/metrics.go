@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/pb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	eventsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_manager_events_received_total",
+		Help: "Events received by the master",
+	}, []string{"event_type"})
+
+	eventsRouted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_manager_events_routed_total",
+		Help: "Events the master published to the slave of a channel",
+	}, []string{"notification_type"})
+
+	eventsSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_manager_events_skipped_total",
+		Help: "Events or destinations that were not sent, by reason",
+	}, []string{"reason"})
+
+	deliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_manager_deliveries_total",
+		Help: "Deliveries to a destination once its retries are over, by status and error class",
+	}, []string{"notification_type", "status", "error_class"})
+
+	providerLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "notification_manager_provider_latency_seconds",
+		Help:    "Latency of the calls to the provider of a channel",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"notification_type", "status"})
+
+	templateRenderErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_manager_template_render_errors_total",
+		Help: "Message templates that failed to render",
+	}, []string{"event_type"})
+
+	queueLag = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "notification_manager_queue_lag_seconds",
+		Help:    "Age of the notification when a worker picked up its envelope task. Delayed tasks are aged from their eta",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 15, 60, 300, 900},
+	}, []string{"task"})
+
+	tasksInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "notification_manager_tasks_in_flight",
+		Help: "Tasks being processed by the worker",
+	}, []string{"task"})
+)
+
+// ObserveDelivery counts the delivery to a destination. err is the error Deliver returned
+func ObserveDelivery(notificationType string, err error) {
+	if err == nil {
+		deliveries.WithLabelValues(notificationType, contract.STATUS_SUCCESS, "").Inc()
+		return
+	}
+
+	class := ClassifyError(err)
+	var openErr *BreakerOpenError
+	if errors.As(err, &openErr) {
+		class = contract.ERROR_BREAKER_OPEN
+	}
+	deliveries.WithLabelValues(notificationType, contract.STATUS_FAILED, class).Inc()
+}
+
+// ObserveQueueLag measures how long the envelope task of ctx waited in the queue.
+// The wait starts when the envelope was created, or at the eta of delayed tasks like retries, parks and schedules
+func ObserveQueueLag(ctx context.Context, envelope *pb.TaskEnvelope) {
+	createdAt, err := time.Parse(time.RFC3339, envelope.GetCreatedAt())
+	if err != nil {
+		return
+	}
+
+	task := "unknown"
+	if signature := tasks.SignatureFromContext(ctx); signature != nil {
+		task = signature.Name
+		if signature.ETA != nil && signature.ETA.After(createdAt) {
+			createdAt = *signature.ETA
+		}
+	}
+
+	lag := time.Since(createdAt)
+	if lag < 0 {
+		lag = 0
+	}
+	queueLag.WithLabelValues(task).Observe(lag.Seconds())
+}
+
+var inFlight int64
+
+// InFlight is the number of tasks of the process running
+func InFlight() int64 {
+	return atomic.LoadInt64(&inFlight)
+}
+
+// WithInFlight wraps a task so it is counted in the in-flight tasks of name while it runs.
+// Tasks are registered by reflection so the wrapper keeps the type of fn
+func WithInFlight(name string, fn interface{}) interface{} {
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func {
+		return fn
+	}
+
+	gauge := tasksInFlight.WithLabelValues(name)
+	return reflect.MakeFunc(fnValue.Type(), func(args []reflect.Value) []reflect.Value {
+		gauge.Inc()
+		atomic.AddInt64(&inFlight, 1)
+		defer func() {
+			gauge.Dec()
+			atomic.AddInt64(&inFlight, -1)
+		}()
+		return fnValue.Call(args)
+	}).Interface()
+}
+
+// countSkipped counts the notifications skipped for reason
+func countSkipped(state, reason string) {
+	if state == contract.NOTIFICATION_SKIPPED {
+		eventsSkipped.WithLabelValues(reason).Inc()
+	}
+}
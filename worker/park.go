@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var parkedTasks = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "notification_manager_parked_tasks_total",
+	Help: "Tasks parked in a delay queue because the circuit breaker of their provider was open",
+}, []string{"notification_type"})
+
+// Parking collects the destinations of a task whose breaker was open so they are sent
+// by a copy of the task published once the breaker lets a probe through
+type Parking struct {
+	w                *Worker
+	notificationType string
+	signature        *tasks.Signature
+	destinations     []string
+	breakers         []string
+	until            time.Time
+}
+
+// NewParking returns the parking of the task being processed. It is nil when the task
+// can't be parked anymore, so the deliveries to an open breaker fail
+func (w *Worker) NewParking(ctx context.Context, notificationType string) *Parking {
+	signature := tasks.SignatureFromContext(ctx)
+	if signature == nil || w.Breakers == nil || GetParkCount(signature) >= contract.MAX_TASK_PARKS {
+		return nil
+	}
+	return &Parking{w: w, notificationType: notificationType, signature: signature}
+}
+
+// Park parks destination when err is a *BreakerOpenError
+func (p *Parking) Park(destination string, err error) bool {
+	var openErr *BreakerOpenError
+	if p == nil || !errors.As(err, &openErr) {
+		return false
+	}
+
+	p.destinations = append(p.destinations, destination)
+	if !hasString(p.breakers, openErr.Name) {
+		p.breakers = append(p.breakers, openErr.Name)
+	}
+	if openErr.Until.After(p.until) {
+		p.until = openErr.Until
+	}
+	return true
+}
+
+// Len is the number of parked destinations
+func (p *Parking) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.destinations)
+}
+
+// Publish sends the parked task to the delay queue. It reports whether the destinations were parked
+func (p *Parking) Publish(ctx context.Context) bool {
+	if p.Len() == 0 {
+		return false
+	}
+	logger := tracing.Logger(ctx, p.w.Logger)
+
+	parked := GetParkedTask(p.signature, p.destinations, p.until)
+	if _, err := p.w.MachineryServer.SendTask(parked); err != nil {
+		logger.Errorw("Error while parking task", "task", p.signature.Name, "breakers", p.breakers, "error", err)
+		return false
+	}
+
+	parkedTasks.WithLabelValues(p.notificationType).Inc()
+	p.w.RecordTransition(ctx, p.notificationType, contract.NOTIFICATION_RETRYING,
+		fmt.Sprintf("circuit breaker %v open. Parked %d destinations until %v", strings.Join(p.breakers, ","), len(p.destinations), p.until.UTC().Format(time.RFC3339)))
+	logger.Infow("Parked task", "task", p.signature.Name, "breakers", p.breakers, "destinations", len(p.destinations), "until", p.until)
+	return true
+}
+
+// GetParkedTask is the copy of the task sending only destinations at eta.
+// The AMQP broker holds tasks with an eta in a delay queue
+func GetParkedTask(signature *tasks.Signature, destinations []string, eta time.Time) *tasks.Signature {
+	parked := *signature
+	parked.UUID = ""
+	parked.ETA = &eta
+	parked.Headers = MergeHeaders(signature.Headers, tasks.Headers{
+		contract.HEADER_PARKED_DESTINATIONS: strings.Join(destinations, ","),
+		contract.HEADER_PARK_COUNT:          strconv.Itoa(GetParkCount(signature) + 1),
+	})
+	return &parked
+}
+
+// GetParkCount is the number of times the task was parked
+func GetParkCount(signature *tasks.Signature) int {
+	count, _ := signature.Headers[contract.HEADER_PARK_COUNT].(string)
+	n, _ := strconv.Atoi(count)
+	return n
+}
+
+// IsDestinationSent reports whether the task was parked without destination, which was handled before
+func IsDestinationSent(ctx context.Context, destination string) bool {
+	signature := tasks.SignatureFromContext(ctx)
+	if signature == nil {
+		return false
+	}
+	parked, ok := signature.Headers[contract.HEADER_PARKED_DESTINATIONS].(string)
+	if !ok {
+		return false
+	}
+	return !hasString(strings.Split(parked, ","), destination)
+}
+
+// GetBotDestination identifies a bot channel among the destinations of a task
+func GetBotDestination(botConfigId uint64, channelId string) string {
+	return fmt.Sprintf("%d:%s", botConfigId, channelId)
+}
+
+func hasString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,71 +1,199 @@
 package worker
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	machineryConf "github.com/RichardKnop/machinery/v2/config"
 	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/pb"
 	"github.com/sirupsen/logrus"
 )
 
+// WorkerPool holds the slave of every notification type. The master refreshes it
+// so slaves registered after it started are picked up
 var WorkerPool map[string]*Worker
 
+// SlaveStatus is the health of the slave of every notification type
+var SlaveStatus map[string]string
+var poolMu sync.RWMutex
+
+// MasterWorker is used by slaves to hand failed deliveries back to the master
+var MasterWorker *Worker
+var masterMu sync.Mutex
+
+var publishers = map[string]*Worker{}
+var publishersMu sync.Mutex
+
 func (w *Worker) InitWorkerPool() {
-	//TODO: GET list or meta of slave workers from DB
-	//workerMeta := []contract.WorkerMeta{}
+	poolMu.Lock()
 	WorkerPool = make(map[string]*Worker)
-	// workerMeta = append(workerMeta, contract.WorkerMeta{
-	// 	Name:             "nt-email",
-	// 	NotificationType: "email",
-	// 	WorkerType:       "slave",
-	// 	Event:            "send_email",
-	// 	Exchange:         "nt-email",
-	// 	Queue:            "nt-email",
-	// 	ExchangeType:     "direct",
-	// 	BindingKey:       "nt-email"})
-	workerMeta, err := w.Db.GetWorkerMeta()
-	if err != nil {
+	SlaveStatus = make(map[string]string)
+	poolMu.Unlock()
+
+	if err := w.RefreshWorkerPool(ctx); err != nil {
 		w.Logger.Info("Failed to load slave workers meta")
 		panic("Failed to load slave workers meta")
 	}
-	for i := 0; i < len(workerMeta); i++ {
+}
 
-		workerInstance := &Worker{
-			Name:       workerMeta[i].Name,
-			WorkerType: workerMeta[i].WorkerType,
-			WorkerConfig: &machineryConf.Config{
-				Broker:       contract.GetWorkerArgs().WorkerConfig.Broker,
-				DefaultQueue: workerMeta[i].Queue,
-				AMQP: &machineryConf.AMQPConfig{
-					Exchange:      workerMeta[i].Exchange,
-					ExchangeType:  workerMeta[i].ExchangeType,
-					BindingKey:    workerMeta[i].BindingKey,
-					PrefetchCount: 150,
-				},
-				ResultBackend: contract.GetWorkerArgs().WorkerConfig.ResultBackend,
-				Redis: &machineryConf.RedisConfig{
-
-					MaxIdle:                3,
-					IdleTimeout:            240,
-					ReadTimeout:            15,
-					WriteTimeout:           15,
-					ConnectTimeout:         15,
-					NormalTasksPollPeriod:  1000,
-					DelayedTasksPollPeriod: 500,
-				},
-			},
+// RefreshWorkerPool registers slaves that joined since the last refresh and updates their health
+func (w *Worker) RefreshWorkerPool(ctx context.Context) error {
+	workerMeta, err := w.Db.GetWorkerMeta()
+	if err != nil {
+		return err
+	}
+	heartbeats, err := w.Db.GetWorkerHeartbeats(ctx)
+	if err != nil {
+		return err
+	}
+
+	//The last registered slave of a notification type is used
+	health := map[string]*pb.WorkerStatus{}
+	slaves := map[string]contract.WorkerMeta{}
+	for i, v := range GetWorkerHealth(workerMeta, heartbeats, time.Now()) {
+		if workerMeta[i].WorkerType == contract.MASTER {
+			continue
 		}
+		health[workerMeta[i].NotificationType] = v
+		slaves[workerMeta[i].NotificationType] = workerMeta[i]
+	}
+
+	poolMu.Lock()
+	defer poolMu.Unlock()
 
-		workerInstance.InitMachineryWorker()
-		logrus.Infof("[ * ] Registering slave worker: %v for notifcationType:%v", workerInstance.Name, workerMeta[i].NotificationType)
-		WorkerPool[workerMeta[i].NotificationType] = workerInstance
+	for notificationType, meta := range slaves {
+		existing := WorkerPool[notificationType]
+		if existing == nil || existing.Name != meta.Name || existing.WorkerConfig.AMQP.BindingKey != meta.BindingKey {
+			workerInstance := NewWorkerFromMeta(meta)
+			logrus.Infof("[ * ] Registering slave worker: %v for notifcationType:%v", workerInstance.Name, notificationType)
+			WorkerPool[notificationType] = workerInstance
+		}
+
+		status := health[notificationType].Status
+		if SlaveStatus[notificationType] != status {
+			logrus.Infof("[ * ] Slave worker: %v for notificationType:%v is %v", meta.Name, notificationType, status)
+			SlaveStatus[notificationType] = status
+		}
+	}
+	return nil
+}
+
+// StartWorkerPoolRefresh refreshes the worker pool and removes the heartbeats of stopped instances until ctx is done
+func (w *Worker) StartWorkerPoolRefresh(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(contract.WORKER_POOL_REFRESH_SECONDS * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := w.RefreshWorkerPool(ctx); err != nil {
+				w.Logger.Errorw("Error while refreshing worker pool", "error", err)
+			}
+			_ = w.Db.DeleteStaleHeartbeats(ctx, time.Now().Add(-contract.WORKER_HEARTBEAT_RETENTION_HOURS*time.Hour))
+		}
+	}()
+}
+
+// NewWorkerFromMeta creates a worker which is only used to publish tasks to the queue described by meta
+func NewWorkerFromMeta(meta contract.WorkerMeta) *Worker {
+	workerInstance := newWorkerFromMeta(meta)
+	workerInstance.InitMachineryWorker()
+	return workerInstance
+}
+
+// GetPublisher returns a cached worker publishing to the queue of meta for processes without a worker pool
+func GetPublisher(meta contract.WorkerMeta) *Worker {
+	publishersMu.Lock()
+	defer publishersMu.Unlock()
+
+	if existing := publishers[meta.Name]; existing != nil && existing.WorkerConfig.AMQP.BindingKey == meta.BindingKey {
+		return existing
+	}
+	publishers[meta.Name] = NewWorkerFromMeta(meta)
+	return publishers[meta.Name]
+}
+
+// GetSlaveMeta returns the meta of the last registered slave of the notification type
+func GetSlaveMeta(metas []contract.WorkerMeta, notificationType string) (contract.WorkerMeta, bool) {
+	var found *contract.WorkerMeta
+	for i, v := range metas {
+		if v.NotificationType == notificationType && v.WorkerType != contract.MASTER {
+			found = &metas[i]
+		}
+	}
+	if found == nil {
+		return contract.WorkerMeta{}, false
+	}
+	return *found, true
+}
+
+// newWorkerFromMeta describes the worker of meta with the settings it published. The machinery config
+// of the queue is built by NewMachineryServer like for the worker itself
+func newWorkerFromMeta(meta contract.WorkerMeta) *Worker {
+	return &Worker{
+		Name:       meta.Name,
+		WorkerType: meta.WorkerType,
+		Settings:   meta.WorkerSettings,
+		WorkerConfig: &machineryConf.Config{
+			Broker:       contract.GetWorkerArgs().WorkerConfig.Broker,
+			DefaultQueue: meta.Queue,
+			AMQP: &machineryConf.AMQPConfig{
+				Exchange:     meta.Exchange,
+				ExchangeType: meta.ExchangeType,
+				BindingKey:   meta.BindingKey,
+			},
+			ResultBackend: contract.GetWorkerArgs().WorkerConfig.ResultBackend,
+		},
 	}
 }
 
 func GetSlaveFromPool(NotificationType string) *Worker {
+	poolMu.RLock()
+	defer poolMu.RUnlock()
 	return WorkerPool[NotificationType]
 }
 
+// IsSlaveAvailable reports whether the slave of the notification type is registered
+// and didn't stop sending heartbeats
+func IsSlaveAvailable(notificationType string) bool {
+	poolMu.RLock()
+	defer poolMu.RUnlock()
+	return WorkerPool[notificationType] != nil && SlaveStatus[notificationType] != contract.WORKER_UNAVAILABLE
+}
+
+// GetMasterWorker lazily discovers the master from worker meta.
+// Master might register after the slave started so it is retried until found
+func GetMasterWorker(database db.DB) *Worker {
+	masterMu.Lock()
+	defer masterMu.Unlock()
+
+	if MasterWorker != nil {
+		return MasterWorker
+	}
+
+	workerMeta, err := database.GetWorkerMeta()
+	if err != nil {
+		logrus.Info("Failed to load master worker meta")
+		return nil
+	}
+	for i := 0; i < len(workerMeta); i++ {
+		if workerMeta[i].WorkerType == contract.MASTER {
+			MasterWorker = NewWorkerFromMeta(workerMeta[i])
+			logrus.Infof("[ * ] Discovered master worker: %v", MasterWorker.Name)
+			return MasterWorker
+		}
+	}
+	return nil
+}
+
 func GetTaskName(notificationType string) string {
 	return fmt.Sprintf("task_send_%s", notificationType)
 }
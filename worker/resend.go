@@ -0,0 +1,115 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/pb"
+	"gorm.io/datatypes"
+)
+
+// Logs lose their message and event data when they are compacted. Logs written before resends were supported have no event data
+var (
+	ErrResendNoMessage = errors.New("the log has no message to resend. Resend it with the current template")
+	ErrResendNoPayload = errors.New("the log has no event data to render. Resend it with the original template")
+)
+
+// loggedDelivery is the req meta the slaves log for a delivery
+type loggedDelivery struct {
+	Message     string
+	Email       string
+	ChannelId   string
+	BotConfigId uint64
+}
+
+// GetResend returns the resend the task is narrowed down to. It is nil for regular deliveries
+func GetResend(ctx context.Context) *pb.TaskResend {
+	return EnvelopeFromContext(ctx).GetResend()
+}
+
+// GetResendEnvelope derives the envelope resending the delivery of log from the envelope of the resend.
+// The original template resends the logged message and the current one renders the logged event again.
+// An empty destination resends to the original recipient
+func GetResendEnvelope(parent *pb.TaskEnvelope, log model.Logs, template, destination string) (*pb.TaskEnvelope, error) {
+	var delivery loggedDelivery
+	if err := json.Unmarshal(log.ReqMeta, &delivery); err != nil {
+		return nil, fmt.Errorf("invalid req meta of log %v: %v", log.Id, err)
+	}
+
+	resend := &pb.TaskResend{LogId: log.Id, Destination: destination, BotConfigId: delivery.BotConfigId}
+	if resend.Destination == "" {
+		resend.Destination = delivery.Email
+		if log.NotificationType != contract.EMAIL {
+			resend.Destination = delivery.ChannelId
+		}
+	}
+	if resend.Destination == "" {
+		return nil, fmt.Errorf("log %v has no recipient to resend to", log.Id)
+	}
+	if log.NotificationType != contract.EMAIL && resend.BotConfigId == 0 {
+		return nil, fmt.Errorf("log %v has no bot to resend with", log.Id)
+	}
+
+	data := []byte(log.Payload)
+	switch template {
+	case "", contract.RESEND_TEMPLATE_ORIGINAL:
+		if delivery.Message == "" {
+			return nil, ErrResendNoMessage
+		}
+		resend.Message = delivery.Message
+		if len(data) == 0 {
+			data = []byte("{}")
+		}
+	case contract.RESEND_TEMPLATE_CURRENT:
+		if len(data) == 0 {
+			return nil, ErrResendNoPayload
+		}
+	default:
+		return nil, fmt.Errorf("template must be %v or %v", contract.RESEND_TEMPLATE_ORIGINAL, contract.RESEND_TEMPLATE_CURRENT)
+	}
+
+	envelope := GetDeliveryEnvelope(parent, log.NotificationType, log.EventType, log.UserConfig, log.AccountId, data)
+	envelope.Resend = resend
+	return envelope, nil
+}
+
+// GetLastDeliveries returns the last delivery log of each notification type, or only of notificationType
+func GetLastDeliveries(logs []model.Logs, notificationType string) []model.Logs {
+	last := map[string]int{}
+	deliveries := []model.Logs{}
+	for _, v := range logs {
+		if notificationType != "" && v.NotificationType != notificationType {
+			continue
+		}
+		if i, ok := last[v.NotificationType]; ok {
+			deliveries[i] = v
+			continue
+		}
+		last[v.NotificationType] = len(deliveries)
+		deliveries = append(deliveries, v)
+	}
+	return deliveries
+}
+
+// GetResendBot returns the bot channel of the bot the resend goes through
+func GetResendBot(ntMeta []model.BotNotificationMeta, resend *pb.TaskResend) []model.BotNotificationMeta {
+	for _, v := range ntMeta {
+		if v.BotConfigId == resend.BotConfigId {
+			v.ChannelId = resend.Destination
+			return []model.BotNotificationMeta{v}
+		}
+	}
+	return nil
+}
+
+// GetLogPayload is the event data logged with a delivery so it can be resent with the current template
+func GetLogPayload(data []byte) datatypes.JSON {
+	if !json.Valid(data) {
+		return nil
+	}
+	return datatypes.JSON(data)
+}
@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/devshahriar/notification-manager/archive"
+	"github.com/devshahriar/notification-manager/contract"
+)
+
+// RetentionPolicy of the delivery logs. Zero durations disable purging or compaction.
+// Expired logs are archived to Store before they are deleted when it is set
+type RetentionPolicy struct {
+	Retention  time.Duration
+	Compaction time.Duration
+	Store      archive.Store
+}
+
+// StartLogRetention purges and compacts the logs every retention interval until ctx is done.
+// Running it on several masters is safe. Batches are keyed by their id range and restores skip duplicates
+func (w *Worker) StartLogRetention(ctx context.Context, policy RetentionPolicy) {
+	if policy.Retention <= 0 && policy.Compaction <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(contract.LOG_RETENTION_INTERVAL_MINUTES * time.Minute)
+		defer ticker.Stop()
+
+		compactedId := uint64(0)
+		for {
+			if policy.Retention > 0 {
+				purged, err := w.PurgeLogs(ctx, policy, time.Now())
+				if err != nil {
+					w.Logger.Errorw("Error while purging expired logs", "purged", purged, "error", err)
+				}
+			}
+			if policy.Compaction > 0 {
+				id, err := w.CompactLogs(ctx, policy, compactedId, time.Now())
+				if err != nil {
+					w.Logger.Errorw("Error while compacting logs", "lastId", id, "error", err)
+				}
+				compactedId = id
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// PurgeLogs archives and deletes the logs older than the retention in batches.
+// A batch is only deleted once its archive is stored
+func (w *Worker) PurgeLogs(ctx context.Context, policy RetentionPolicy, now time.Time) (int64, error) {
+	cutoff := now.Add(-policy.Retention)
+	purged := int64(0)
+	for {
+		logs, err := w.Db.GetExpiredLogs(ctx, cutoff, contract.LOG_PURGE_BATCH_SIZE)
+		if err != nil || len(logs) == 0 {
+			return purged, err
+		}
+
+		if policy.Store != nil {
+			for _, day := range archive.SplitByDay(logs) {
+				data, err := archive.EncodeLogs(day)
+				if err != nil {
+					return purged, err
+				}
+				if err := policy.Store.Put(ctx, archive.GetArchiveKey(day), data); err != nil {
+					return purged, err
+				}
+			}
+		}
+
+		ids := make([]uint64, 0, len(logs))
+		for _, v := range logs {
+			ids = append(ids, v.Id)
+		}
+		deleted, err := w.Db.DeleteLogs(ctx, ids)
+		purged += deleted
+		if err != nil {
+			return purged, err
+		}
+
+		if !pause(ctx) {
+			return purged, ctx.Err()
+		}
+	}
+}
+
+// CompactLogs drops the rendered messages of the logs older than the compaction after afterId.
+// It returns the last compacted id to continue from on the next run
+func (w *Worker) CompactLogs(ctx context.Context, policy RetentionPolicy, afterId uint64, now time.Time) (uint64, error) {
+	cutoff := now.Add(-policy.Compaction)
+	for {
+		lastId, _, err := w.Db.CompactLogs(ctx, cutoff, afterId, contract.LOG_PURGE_BATCH_SIZE)
+		if err != nil || lastId == afterId {
+			return lastId, err
+		}
+		afterId = lastId
+
+		if !pause(ctx) {
+			return afterId, ctx.Err()
+		}
+	}
+}
+
+func pause(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(contract.LOG_PURGE_BATCH_PAUSE_MS * time.Millisecond):
+		return true
+	}
+}
@@ -0,0 +1,227 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/devShahriar/H"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/mailgun/mailgun-go/v4"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+// RetryPolicy limits the delivery attempts to a destination of a channel.
+// Attempt n waits a random delay between half and all of BaseDelay*2^(n-1), capped at MaxDelay
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: contract.DEFAULT_DELIVERY_ATTEMPTS,
+	BaseDelay:   contract.DEFAULT_DELIVERY_BASE_DELAY_MS * time.Millisecond,
+	MaxDelay:    contract.DEFAULT_DELIVERY_MAX_DELAY_SECONDS * time.Second,
+}
+
+// ParseRetryPolicies parses channel=attempts:base delay:max delay policies separated by commas
+// ex: email=3:1s:30s,telegram=5:500ms:10s
+func ParseRetryPolicies(spec string) (map[string]RetryPolicy, error) {
+	policies := map[string]RetryPolicy{}
+	for _, v := range strings.Split(spec, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		parts := strings.SplitN(v, "=", 2)
+		values := []string{}
+		if len(parts) == 2 {
+			values = strings.Split(parts[1], ":")
+		}
+		if len(values) != 3 {
+			return nil, fmt.Errorf("retry policy %q is not channel=attempts:base delay:max delay", v)
+		}
+
+		attempts, err := strconv.Atoi(values[0])
+		if err != nil || attempts < 1 {
+			return nil, fmt.Errorf("retry policy %q needs at least 1 attempt", v)
+		}
+		base, err := time.ParseDuration(values[1])
+		if err != nil {
+			return nil, fmt.Errorf("retry policy %q: %w", v, err)
+		}
+		max, err := time.ParseDuration(values[2])
+		if err != nil {
+			return nil, fmt.Errorf("retry policy %q: %w", v, err)
+		}
+		if max < base {
+			return nil, fmt.Errorf("retry policy %q has a max delay below the base delay", v)
+		}
+
+		policies[strings.ToLower(parts[0])] = RetryPolicy{MaxAttempts: attempts, BaseDelay: base, MaxDelay: max}
+	}
+	return policies, nil
+}
+
+// GetRetryPolicy returns the policy of the channel or DefaultRetryPolicy
+func (w *Worker) GetRetryPolicy(notificationType string) RetryPolicy {
+	if policy, ok := w.RetryPolicies[notificationType]; ok {
+		return policy
+	}
+	return DefaultRetryPolicy
+}
+
+// Backoff returns the delay before the attempt after the given one
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 31 && p.BaseDelay<<(attempt-1) < p.MaxDelay {
+		delay = p.BaseDelay << (attempt - 1)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// DeliveryError is a provider error classified as transient or permanent
+type DeliveryError struct {
+	Class string
+	Err   error
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Class + ": " + e.Err.Error()
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError tells if retrying err can succeed. Timeouts, network errors, 5xx and 429 are transient.
+// Rejected credentials, recipients and requests are permanent. Unknown errors are transient
+func ClassifyError(err error) string {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr.Class
+	}
+
+	var mgErr *mailgun.UnexpectedResponseError
+	if errors.As(err, &mgErr) {
+		return classifyStatus(mgErr.Actual)
+	}
+
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		return classifyStatus(restErr.Response.StatusCode)
+	}
+	var rateLimitErr *discordgo.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return contract.ERROR_TRANSIENT
+	}
+	if errors.Is(err, discordgo.ErrUnauthorized) {
+		return contract.ERROR_PERMANENT
+	}
+
+	var tgErr tgbotapi.Error
+	if errors.As(err, &tgErr) && tgErr.RetryAfter > 0 {
+		return contract.ERROR_TRANSIENT
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return contract.ERROR_TRANSIENT
+	}
+
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return contract.ERROR_PERMANENT
+	}
+
+	// Telegram and the discord gateway only describe the failure
+	msg := strings.ToLower(err.Error())
+	for _, v := range permanentMessages {
+		if strings.Contains(msg, v) {
+			return contract.ERROR_PERMANENT
+		}
+	}
+	return contract.ERROR_TRANSIENT
+}
+
+var permanentMessages = []string{
+	"bad request",
+	"unauthorized",
+	"forbidden",
+	"not found",
+	"authentication failed",
+	"invalid token",
+}
+
+func classifyStatus(status int) string {
+	if status == 408 || status == 429 || status >= 500 {
+		return contract.ERROR_TRANSIENT
+	}
+	if status >= 400 {
+		return contract.ERROR_PERMANENT
+	}
+	return contract.ERROR_TRANSIENT
+}
+
+// Deliver calls send until it succeeds, fails permanently or the retry policy of the channel runs out.
+// Every retry is recorded in the lifecycle of the notification. The returned error is a *DeliveryError.
+// Calls go through the circuit breakers breakerNames and stop with a *BreakerOpenError once one is open.
+// Each call is recorded by all of them
+func (w *Worker) Deliver(ctx context.Context, notificationType, destination string, breakerNames []string, send func() error) (attempts int, err error) {
+	defer func() { ObserveDelivery(notificationType, err) }()
+	policy := w.GetRetryPolicy(notificationType)
+	breakers := make([]*Breaker, len(breakerNames))
+	for i, name := range breakerNames {
+		breakers[i] = w.Breakers.Get(name)
+	}
+
+	for attempt := 1; ; attempt++ {
+		allowedAt := time.Now()
+		if i := AllowAll(breakers, allowedAt); i >= 0 {
+			return attempt - 1, &DeliveryError{Class: contract.ERROR_TRANSIENT, Err: &BreakerOpenError{Name: breakerNames[i], Until: breakers[i].OpenUntil()}}
+		}
+
+		//Waiting for the rate limit is not provider latency
+		if err := w.WaitRateLimit(ctx); err != nil {
+			ReleaseAll(breakers, allowedAt)
+			return attempt - 1, &DeliveryError{Class: contract.ERROR_TRANSIENT, Err: err}
+		}
+
+		start := time.Now()
+		err := send()
+		providerLatency.WithLabelValues(notificationType, H.If(err != nil, contract.STATUS_FAILED, contract.STATUS_SUCCESS)).Observe(time.Since(start).Seconds())
+		for _, breaker := range breakers {
+			breaker.Record(err != nil && ClassifyError(err) == contract.ERROR_TRANSIENT, time.Now())
+		}
+		if err == nil {
+			return attempt, nil
+		}
+
+		class := ClassifyError(err)
+		deliveryErr := &DeliveryError{Class: class, Err: err}
+		if class == contract.ERROR_PERMANENT || attempt >= policy.MaxAttempts {
+			return attempt, deliveryErr
+		}
+
+		delay := policy.Backoff(attempt)
+		w.RecordTransition(ctx, notificationType, contract.NOTIFICATION_RETRYING,
+			fmt.Sprintf("attempt %d of %d to %v failed: %v. Retrying in %v", attempt, policy.MaxAttempts, destination, deliveryErr, delay.Round(time.Millisecond)))
+
+		select {
+		case <-ctx.Done():
+			return attempt, deliveryErr
+		case <-time.After(delay):
+		}
+	}
+}
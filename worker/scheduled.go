@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/contract"
+)
+
+// GetScheduledId returns the id of the scheduled notification the task delivers.
+// Empty id means the notification wasn't scheduled
+func GetScheduledId(ctx context.Context) string {
+	signature := tasks.SignatureFromContext(ctx)
+	if signature == nil {
+		return ""
+	}
+	scheduledId, _ := signature.Headers[contract.HEADER_SCHEDULED_ID].(string)
+	return scheduledId
+}
+
+// ClaimScheduledNotification marks a scheduled notification as sent.
+// It returns false when the notification was cancelled or already sent
+func (n *NotificationRouter) ClaimScheduledNotification(ctx context.Context) bool {
+	scheduledId := GetScheduledId(ctx)
+	if scheduledId == "" {
+		return true
+	}
+
+	claimed, err := n.Db.MarkScheduledNotificationSent(ctx, scheduledId)
+	if err != nil {
+		n.Logger.Errorw("Error while claiming scheduled notification. Sending it anyway", "scheduledId", scheduledId, "error", err)
+		return true
+	}
+	if !claimed {
+		n.Logger.Infof("Scheduled notification:%v was cancelled or already sent", scheduledId)
+	}
+	return claimed
+}
@@ -0,0 +1,116 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/devshahriar/notification-manager/contract"
+	"golang.org/x/time/rate"
+)
+
+// GetSettings returns the current settings of the worker
+func (w *Worker) GetSettings() contract.WorkerSettings {
+	w.settingsMu.RLock()
+	defer w.settingsMu.RUnlock()
+	return w.Settings
+}
+
+// ApplySettings changes the settings of the running worker. The provider timeout and rate limit apply
+// to the next calls to the provider, a new concurrency or prefetch count restarts consuming
+func (w *Worker) ApplySettings(settings contract.WorkerSettings) {
+	w.settingsMu.Lock()
+	defer w.settingsMu.Unlock()
+
+	restart := settings.Concurrency != w.Settings.Concurrency || settings.PrefetchCount != w.Settings.PrefetchCount
+	w.Settings = settings
+	if restart && w.reconsume != nil {
+		select {
+		case w.reconsume <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ProviderTimeout is the time a call to the provider may take
+func (w *Worker) ProviderTimeout() time.Duration {
+	if seconds := w.GetSettings().ProviderTimeoutSeconds; seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return contract.DELIVERY_TIMEOUT_SECONDS * time.Second
+}
+
+// prefetchCount is the number of tasks the broker delivers ahead of processing
+func (w *Worker) prefetchCount() int {
+	if prefetch := w.GetSettings().PrefetchCount; prefetch > 0 {
+		return prefetch
+	}
+	return contract.DEFAULT_PREFETCH_COUNT
+}
+
+// WaitRateLimit blocks until the rate limit of the worker allows another call to the provider or ctx is done
+func (w *Worker) WaitRateLimit(ctx context.Context) error {
+	limiter := w.rateLimiter()
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
+
+// rateLimiter returns the limiter of the current rate limit, nil when calls are unlimited.
+// The limiter is kept across changes of the limit so the calls already made still count
+func (w *Worker) rateLimiter() *rate.Limiter {
+	w.settingsMu.Lock()
+	defer w.settingsMu.Unlock()
+
+	limit := w.Settings.RateLimit
+	if limit <= 0 {
+		return nil
+	}
+	if w.limiter == nil {
+		w.limiter = rate.NewLimiter(rate.Limit(limit), limit)
+	} else if w.limiter.Burst() != limit {
+		w.limiter.SetLimit(rate.Limit(limit))
+		w.limiter.SetBurst(limit)
+	}
+	return w.limiter
+}
+
+// RefreshSettings applies the settings stored in the worker meta of the worker
+func (w *Worker) RefreshSettings() error {
+	workerMeta, err := w.Db.GetWorkerMeta()
+	if err != nil {
+		return err
+	}
+
+	meta := w.Meta()
+	for _, v := range workerMeta {
+		if v.Name != meta.Name || v.Queue != meta.Queue || v.BindingKey != meta.BindingKey {
+			continue
+		}
+		if current := w.GetSettings(); v.WorkerSettings != current {
+			w.Logger.Infow("Worker settings changed", "from", current, "to", v.WorkerSettings)
+			w.ApplySettings(v.WorkerSettings)
+		}
+		return nil
+	}
+	return nil
+}
+
+// StartSettingsRefresh applies the settings changed with SetWorkerSettings until ctx is done
+func (w *Worker) StartSettingsRefresh(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(contract.WORKER_SETTINGS_REFRESH_SECONDS * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := w.RefreshSettings(); err != nil {
+				w.Logger.Errorw("Error while refreshing worker settings", "error", err)
+			}
+		}
+	}()
+}
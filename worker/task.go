@@ -1,5 +1,7 @@
 package worker
 
+import "github.com/devshahriar/notification-manager/contract"
+
 var TaskFactory map[string]map[string]interface{}
 
 // WORKER NAMES
@@ -14,22 +16,31 @@ func (w *Worker) InitTaskFactory() {
 
 	email := &TaskSendEmail{Worker: w}
 	emailTask := map[string]interface{}{
-		"task_send_email": email.SendEmail,
+		"task_send_email":                   email.SendEmail,
+		GetEnvelopeTaskName(contract.EMAIL): EnvelopeTask(email.SendEmail),
 	}
 
 	telegram := &TaskSendTelegramNotification{Worker: w}
 	telegramTask := map[string]interface{}{
-		"task_send_telegram": telegram.SendTelegramNotification,
+		"task_send_telegram":                   telegram.SendTelegramNotification,
+		GetEnvelopeTaskName(contract.TELEGRAM): EnvelopeTask(telegram.SendTelegramNotification),
 	}
 
 	discord := &TaskSendDiscordNotification{Worker: w}
 	discordTask := map[string]interface{}{
-		"task_send_discord": discord.SendDiscordNotification,
+		"task_send_discord":                   discord.SendDiscordNotification,
+		GetEnvelopeTaskName(contract.DISCORD): EnvelopeTask(discord.SendDiscordNotification),
 	}
 
 	ntRouter := NotificationRouter{Worker: w}
 	RouteNotificationTask := map[string]interface{}{
-		"task_route_notification": ntRouter.RouteNotification,
+		"task_route_notification":         ntRouter.RouteNotification,
+		TASK_ROUTE_FALLBACK:               ntRouter.RouteFallback,
+		TASK_ESCALATE:                     ntRouter.Escalate,
+		TASK_RELEASE_DISCONNECT:           ntRouter.ReleaseDisconnect,
+		contract.TASK_BROADCAST_BATCH:     ntRouter.BroadcastBatch,
+		contract.TASK_ROUTE_NOTIFICATIONS: ntRouter.RouteNotifications,
+		contract.TASK_ROUTE_ENVELOPE:      ntRouter.RouteEnvelope,
 	}
 
 	TaskFactory = map[string]map[string]interface{}{
@@ -38,6 +49,12 @@ func (w *Worker) InitTaskFactory() {
 		TELEGRAM_WORKER: telegramTask,
 		DISCORD_WORKER:  discordTask,
 	}
+
+	for _, workerTasks := range TaskFactory {
+		for name, fn := range workerTasks {
+			workerTasks[name] = WithInFlight(name, w.WithDeadLetter(fn))
+		}
+	}
 }
 
 func GetTaskByWorkerName(workerName string) map[string]interface{} {
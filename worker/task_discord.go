@@ -1,16 +1,20 @@
 package worker
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/devShahriar/H"
 	"github.com/devshahriar/notification-manager/contract"
 	"github.com/devshahriar/notification-manager/model"
-	"github.com/devshahriar/notification-manager/template"
+	"github.com/devshahriar/notification-manager/secrets"
+	"github.com/devshahriar/notification-manager/tracing"
+	"go.opencensus.io/trace"
 	"gorm.io/datatypes"
 )
 
@@ -18,41 +22,88 @@ type TaskSendDiscordNotification struct {
 	*Worker
 }
 
-func (t *TaskSendDiscordNotification) SendDiscordNotification(userConfig, accId, eventType string, data []byte) error {
+func (t *TaskSendDiscordNotification) SendDiscordNotification(ctx context.Context, userConfig, accId, eventType string, data []byte) error {
 
-	ntMeta, err := t.Db.GetBotNotificationMeta(ctx, userConfig, eventType, contract.DISCORD)
+	logger := tracing.Logger(ctx, t.Logger)
+
+	var ntMeta []model.BotNotificationMeta
+	var err error
+
+	broadcastId := GetBroadcastId(ctx)
+	if broadcastId != "" {
+		ntMeta, err = t.Db.GetBroadcastBotMeta(ctx, userConfig, broadcastId, contract.DISCORD)
+	} else {
+		ntMeta, err = t.Db.GetBotNotificationMeta(ctx, userConfig, eventType, contract.DISCORD)
+	}
 
 	if err != nil {
-		t.Logger.Errorw("Error: while retrieving BotNotification Meta for userConfig", userConfig, "eventType", eventType)
+		logger.Errorw("Error: while retrieving BotNotification Meta for userConfig", userConfig, "eventType", eventType)
+		if IsLastAttempt(ctx) {
+			t.RecordDelivery(ctx, contract.DISCORD, 0, 1, err)
+		}
 		return err
 	}
 
+	resend := GetResend(ctx)
+	if resend != nil {
+		ntMeta = GetResendBot(ntMeta, resend)
+	}
+	if target := GetTarget(ctx); target != nil {
+		ntMeta = GetTargetBots(ntMeta, target)
+	} else if t.FanOut(ctx, contract.DISCORD, GetBotTargets(ctx, ntMeta)) {
+		return nil
+	}
+
+	sent := 0
+	attempts := 0
+	var lastErr error
+	parking := t.NewParking(ctx, contract.DISCORD)
 	for _, v := range ntMeta {
 
+		destination := GetBotDestination(v.BotConfigId, v.ChannelId)
+		if IsBotFiltered(ctx, v.BotConfigId) || IsDestinationSent(ctx, destination) {
+			continue
+		}
+		attempts++
+
 		var dataObj map[string]string
 		err := json.Unmarshal(data, &dataObj)
 
 		if err != nil {
-			t.Logger.Errorw("Error while parsing notification data", err)
+			logger.Errorw("Error while parsing notification data", err)
+			lastErr = err
 			continue
 		}
 
-		message := template.IngestDataIntoMsgBody(v.FirstName, v.EventType, v.MessageTemplate, dataObj)
+		message := RenderMessage(ctx, contract.DISCORD, v.FirstName, v.EventType, v.MessageTemplate, dataObj)
+		if resend.GetMessage() != "" {
+			message = resend.Message
+		}
 
-		sendErr := t.Send(v.BotToken, v.ChannelId, message)
-		if sendErr != nil {
-			t.Logger.Errorw("Error while sending discord notification", sendErr)
+		_, span := trace.StartSpan(ctx, "discord.Send", trace.WithSpanKind(trace.SpanKindClient))
+		_, sendErr := t.Deliver(ctx, contract.DISCORD, "channel "+v.ChannelId, BotBreakers(contract.DISCORD, v.BotConfigId), func() error {
+			return secrets.Redact(t.Send(v.BotToken, v.ChannelId, message), v.BotToken)
+		})
+		tracing.EndSpan(span, sendErr)
+		if parking.Park(destination, sendErr) {
+			lastErr = sendErr
 			continue
 		}
+		if sendErr != nil {
+			lastErr = sendErr
+			logger.Errorw("Error while sending discord notification", sendErr)
+		} else {
+			sent++
+		}
 
 		reqMeta := struct {
-			BotToken  string
-			ChannelId string
-			Message   string
+			BotConfigId uint64
+			ChannelId   string
+			Message     string
 		}{
-			BotToken:  v.BotToken,
-			ChannelId: v.ChannelId,
-			Message:   message,
+			BotConfigId: v.BotConfigId,
+			ChannelId:   v.ChannelId,
+			Message:     message,
 		}
 
 		reqMetaBytes, _ := json.Marshal(reqMeta)
@@ -63,15 +114,31 @@ func (t *TaskSendDiscordNotification) SendDiscordNotification(userConfig, accId,
 			EventType:        eventType,
 			NotificationType: contract.DISCORD,
 			ReqMeta:          datatypes.JSON(reqMetaBytes),
-			Status:           H.If(sendErr != nil, "FAILED", "SUCCESS"),
+			Status:           H.If(sendErr != nil, contract.STATUS_FAILED, contract.STATUS_SUCCESS),
+			ChainId:          GetChainId(ctx),
+			TraceId:          tracing.TraceId(ctx),
+			NotificationId:   GetNotificationId(ctx),
+			BroadcastId:      broadcastId,
+			Payload:          GetLogPayload(data),
+			ResendOf:         resend.GetLogId(),
 		})
 
 		if dumpLogErr != nil {
-			t.Logger.Errorw(dumpLogErr.Error())
+			logger.Errorw(dumpLogErr.Error())
 		}
 
 		log.Println("Message sent successfully!")
 	}
+
+	//The parked task sends the destinations behind an open breaker and falls back if they fail
+	if parking.Publish(ctx) {
+		if attempts -= parking.Len(); attempts > 0 {
+			t.RecordDelivery(ctx, contract.DISCORD, sent, attempts, lastErr)
+		}
+		return nil
+	}
+
+	t.FinishDelivery(ctx, contract.DISCORD, userConfig, accId, eventType, data, sent, attempts, lastErr)
 	return nil
 }
 
@@ -80,14 +147,15 @@ func (t *TaskSendDiscordNotification) Send(botToken, channelId, message string)
 	// Create a new Discord session
 	dg, err := discordgo.New("Bot " + botToken)
 	if err != nil {
-		fmt.Println("Error creating Discord session:", err)
+		fmt.Println("Error creating Discord session:", secrets.Redact(err, botToken))
 		return err
 	}
+	dg.Client = &http.Client{Timeout: t.ProviderTimeout()}
 
 	// Open a websocket connection to Discord
 	err = dg.Open()
 	if err != nil {
-		fmt.Println("Error opening Discord connection:", err)
+		fmt.Println("Error opening Discord connection:", secrets.Redact(err, botToken))
 		return err
 	}
 
@@ -96,7 +164,7 @@ func (t *TaskSendDiscordNotification) Send(botToken, channelId, message string)
 	message = strings.ReplaceAll(message, "\\n", "\n")
 	_, err = dg.ChannelMessageSend(channelId, message)
 	if err != nil {
-		fmt.Println("Error sending message:", err)
+		fmt.Println("Error sending message:", secrets.Redact(err, botToken))
 		return err
 	}
 
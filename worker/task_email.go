@@ -3,7 +3,6 @@ package worker
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"strings"
 
 	"github.com/Traders-Connect/esb-contract/golang/notification_manager"
@@ -11,8 +10,10 @@ import (
 	"github.com/devshahriar/notification-manager/contract"
 	"github.com/devshahriar/notification-manager/model"
 	"github.com/devshahriar/notification-manager/template"
+	"github.com/devshahriar/notification-manager/tracing"
 	"github.com/mailgun/mailgun-go/v4"
 	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
 )
 
 var ctx context.Context = context.Background()
@@ -21,57 +22,78 @@ type TaskSendEmail struct {
 	*Worker
 }
 
-func (t *TaskSendEmail) SendEmail(userConfig, accId, eventType string, data []byte) error {
+func (t *TaskSendEmail) SendEmail(ctx context.Context, userConfig, accId, eventType string, data []byte) error {
+
+	logger := tracing.Logger(ctx, t.Logger)
 
 	var emailMeta contract.EmailMeta
 	var err error
 
-	if eventType == notification_manager.EventType_ACCOUNT_DELETED.String() {
-		t.Worker.Logger.Info("Sending user specific notification")
+	broadcastId := GetBroadcastId(ctx)
+	if broadcastId != "" {
+		emailMeta, err = t.Worker.Db.GetBroadcastEmailMeta(ctx, userConfig, broadcastId)
+	} else if eventType == notification_manager.EventType_ACCOUNT_DELETED.String() {
+		logger.Info("Sending user specific notification")
 		emailMeta, err = t.Worker.Db.GetEmailMetaForUserOnly(ctx, userConfig, eventType)
 	} else {
 		emailMeta, err = t.Worker.Db.GetEmailMeta(ctx, userConfig, accId, eventType)
 	}
 
-	t.Worker.Logger.Info(emailMeta)
-
 	if err != nil {
 		logrus.Info("Couldn't fetch email")
+		if IsLastAttempt(ctx) {
+			t.RecordDelivery(ctx, contract.EMAIL, 0, 1, err)
+		}
 		return err
 	}
 
-	t.Worker.Logger.Info(emailMeta)
-	t.Logger.Info()
-
 	api := contract.GetWorkerArgs().EmailBaseUrl
 	key := contract.GetWorkerArgs().EmailApiKey
 
-	t.Logger.Info(api)
-	t.Logger.Info(key)
-
 	mg := mailgun.NewMailgun(api, key)
 	mg.SetAPIBase("https://api.eu.mailgun.net/v3")
 
 	EmailList := []string{emailMeta.DefaultEmail}
 
 	if emailMeta.Email != nil && *emailMeta.Email != "" {
-		fmt.Println("emailMeta.Email is not null")
 		EmailList = append(EmailList, *emailMeta.Email)
 	}
 
-	t.Logger.Info(EmailList)
+	resend := GetResend(ctx)
+	if resend != nil {
+		EmailList = []string{resend.Destination}
+	}
+	if target := GetTarget(ctx); target != nil {
+		EmailList = []string{target.Destination}
+	} else if t.FanOut(ctx, contract.EMAIL, GetEmailTargets(EmailList)) {
+		return nil
+	}
+
+	logger.Debugw("Sending email", "eventType", eventType, "broadcastId", broadcastId, "recipients", len(EmailList), "resend", resend != nil)
 
 	var dataObj map[string]string
 	err = json.Unmarshal(data, &dataObj)
 
 	if err != nil {
-		t.Logger.Errorw("Error while decoding data []byte to map[string]string", err)
+		logger.Errorw("Error while decoding data []byte to map[string]string", err)
 		return err
 	}
 
-	body := template.IngestDataIntoMsgBody(emailMeta.FirstName, eventType, emailMeta.MessageTemplate, dataObj)
+	body := RenderMessage(ctx, contract.EMAIL, emailMeta.FirstName, eventType, emailMeta.MessageTemplate, dataObj)
+	if resend.GetMessage() != "" {
+		body = resend.Message
+	}
 
+	sent := 0
+	attempts := 0
+	var lastErr error
+	parking := t.NewParking(ctx, contract.EMAIL)
 	for _, email := range EmailList {
+		if IsDestinationSent(ctx, email) {
+			continue
+		}
+		attempts++
+
 		subject := emailMeta.Subject
 		sender := "Traders Connect noreply@mg.tradersconnect.com"
 		recipient := email
@@ -82,9 +104,23 @@ func (t *TaskSendEmail) SendEmail(userConfig, accId, eventType string, data []by
 		message.SetHtml(template)
 
 		message.AddRecipient(recipient)
-		_, _, err = mg.Send(context.Background(), message)
+		_, span := trace.StartSpan(ctx, "mailgun.Send", trace.WithSpanKind(trace.SpanKindClient))
+		_, err = t.Deliver(ctx, contract.EMAIL, email, []string{BreakerName(contract.EMAIL, 0)}, func() error {
+			sendCtx, cancel := context.WithTimeout(ctx, t.ProviderTimeout())
+			defer cancel()
+			_, _, err := mg.Send(sendCtx, message)
+			return err
+		})
+		tracing.EndSpan(span, err)
+		if parking.Park(email, err) {
+			lastErr = err
+			continue
+		}
 		if err != nil {
-			fmt.Println("Error sending email:", err)
+			lastErr = err
+			logger.Errorw("Error sending email", "error", err)
+		} else {
+			sent++
 		}
 
 		reqMeta := struct {
@@ -105,12 +141,30 @@ func (t *TaskSendEmail) SendEmail(userConfig, accId, eventType string, data []by
 			EventType:        eventType,
 			NotificationType: contract.EMAIL,
 			ReqMeta:          reqMetaBytes,
-			Status:           H.If(err != nil, "FAILED", "SUCCESS"),
+			Status:           H.If(err != nil, contract.STATUS_FAILED, contract.STATUS_SUCCESS),
+			ChainId:          GetChainId(ctx),
+			TraceId:          tracing.TraceId(ctx),
+			NotificationId:   GetNotificationId(ctx),
+			BroadcastId:      broadcastId,
+			Payload:          GetLogPayload(data),
+			ResendOf:         resend.GetLogId(),
 		})
 	}
 
-	t.Logger.Info("Email sent successfully!")
-	return err
+	//The parked task sends the recipients behind an open breaker and falls back if they fail
+	if parking.Publish(ctx) {
+		if attempts -= parking.Len(); attempts > 0 {
+			t.RecordDelivery(ctx, contract.EMAIL, sent, attempts, lastErr)
+		}
+		return nil
+	}
+
+	//Recipients are retried by Deliver. Failing the task would resend to the recipients that got the email.
+	//Without a fallback chain the task is dead-lettered
+	t.FinishDelivery(ctx, contract.EMAIL, userConfig, accId, eventType, data, sent, attempts, lastErr)
+
+	logger.Info("Email sent successfully!")
+	return nil
 }
 
 func GetHtmlTemplate(subject, body string) string {
@@ -1,183 +1,393 @@
 package worker
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/RichardKnop/machinery/v2/tasks"
 	nm "github.com/Traders-Connect/esb-contract/golang/notification_manager"
+	"github.com/devshahriar/notification-manager/contract"
+	"github.com/devshahriar/notification-manager/model"
+	"github.com/devshahriar/notification-manager/tracing"
+	"github.com/google/uuid"
+	"go.opencensus.io/trace"
+	"gorm.io/datatypes"
 )
 
 type NotificationRouter struct {
 	*Worker
 }
 
-func (n *NotificationRouter) RouteNotification(userId, accId, eventType string, dataBytes []byte) error {
+func (n *NotificationRouter) RouteNotification(ctx context.Context, userId, accId, eventType string, dataBytes []byte) error {
 
-	n.Logger.Info("Receviced notification task")
-	var data map[string]string
-	_ = json.Unmarshal(dataBytes, &data)
+	if EnvelopeFromContext(ctx) == nil {
+		ctx = WithEnvelope(ctx, NewEnvelope(userId, accId, eventType, dataBytes))
+	}
+	envelope := EnvelopeFromContext(ctx)
+	ctx, span := tracing.StartSpan(ctx, "RouteNotification", envelope.TraceContext)
+	defer span.End()
+	span.AddAttributes(trace.StringAttribute("notification.id", envelope.NotificationId), trace.StringAttribute("event.type", eventType))
+	logger := tracing.Logger(ctx, n.Logger)
+
+	logger.Info("Receviced notification task")
+	eventsReceived.WithLabelValues(eventType).Inc()
+
+	if !n.ClaimScheduledNotification(ctx) {
+		n.RecordTransition(ctx, "", contract.NOTIFICATION_SKIPPED, "scheduled notification was cancelled or already sent")
+		return nil
+	}
 
 	if userId != "" && eventType == nm.EventType_ACCOUNT_DELETED.String() {
-		n.Logger.Info("Routing notification based on userId")
-		err := n.SendUserSpecificNotification(userId, eventType, dataBytes)
-		n.Logger.Info(err)
+		logger.Info("Routing notification based on userId")
+		err := n.SendUserSpecificNotification(ctx, userId, eventType, dataBytes)
+		logger.Info(err)
 		return err
 	}
 
 	userConfigId, err := n.Worker.Db.GetUserConfig(ctx, accId)
 	if err != nil {
-		n.Worker.Logger.Infof("Failed to fetch UserConfig %v", userConfigId)
+		logger.Infof("Failed to fetch UserConfig %v", userConfigId)
 	}
 
-	n.Worker.Logger.Infof("Retrieving UserConfig %v", userConfigId)
-	//Check if notification for this event is disable dont send it to slave worker
+	logger.Infof("Retrieving UserConfig %v", userConfigId)
+
+	n.ResolveEscalations(userConfigId.UserConfigId, accId, eventType)
+
+	if n.SuppressFlap(ctx, userConfigId, accId, eventType, dataBytes) {
+		return nil
+	}
+
+	return n.DispatchNotification(ctx, userConfigId, accId, eventType, dataBytes)
+}
+
+// DispatchNotification sends the event of an account to the slaves of every enabled notification type
+func (n *NotificationRouter) DispatchNotification(ctx context.Context, userConfigId contract.ConfigIds, accId, eventType string, dataBytes []byte) error {
+
+	logger := tracing.Logger(ctx, n.Logger)
 
-	//GET enabled notificationTypes
-	notificationsTypes, err := n.Worker.Db.GetEnabledNotificationTypes(ctx, userConfigId.UserConfigId, eventType)
+	if userConfigId.UserConfigId == "" {
+		logger.Infof("No user config for accountId:%v. Not routing %v", accId, eventType)
+		n.RecordTransition(ctx, "", contract.NOTIFICATION_SKIPPED, "no user config for the account")
+		return nil
+	}
+
+	var data map[string]string
+	_ = json.Unmarshal(dataBytes, &data)
+
+	plan, err := n.Worker.Db.GetRoutingPlan(ctx, userConfigId, eventType)
 	if err != nil {
 		return err
 	}
 
 	sentNotificationType := map[string]bool{}
-	for i := 0; i < len(notificationsTypes); i++ {
-
-		if !n.ShouldSendNotification(userConfigId.UserId,
-			userConfigId.AccountConfId,
-			fmt.Sprintf("%d", notificationsTypes[i].ID),
-			notificationsTypes[i].EventType,
-			notificationsTypes[i].NotificationType) {
-			n.Logger.Errorw("Not routing notification for the config below as the account or userConfig is disabled")
+	payloads := map[string][]byte{}
+	headers := map[string]tasks.Headers{}
+	routed := []string{}
+	for _, v := range plan.Configs {
+
+		//Check if notification for this event is disable dont send it to slave worker
+		if !IsRoutable(plan, v) {
+			logger.Infof("Not routing notificationConfig:%v as the account or integration is disabled", v.ID)
+			n.RecordTransition(ctx, v.NotificationType, contract.NOTIFICATION_SKIPPED, "integration or account notification disabled")
 			continue
 		}
-		if _, ok := sentNotificationType[notificationsTypes[i].NotificationType]; ok {
+
+		filterHeaders, ok := n.FilterNotification(plan, v, data)
+		if !ok {
+			n.RecordTransition(ctx, v.NotificationType, contract.NOTIFICATION_SKIPPED, "filtered out")
+			continue
+		}
+		if _, ok := sentNotificationType[v.NotificationType]; ok {
 			continue //NotificationConfig table might have duplicate notificationType . Ignore routing duplicate notification type
 		} else {
-			sentNotificationType[notificationsTypes[i].NotificationType] = true
+			sentNotificationType[v.NotificationType] = true
 		}
 
-		worker := GetSlaveFromPool(notificationsTypes[i].NotificationType)
+		payload := n.StartEscalation(v, userConfigId.UserConfigId, accId, eventType, dataBytes)
+		payloads[v.NotificationType] = payload
+		headers[v.NotificationType] = filterHeaders
 
-		taskSignature := GetRouteNotificationTask(
-			GetTaskName(notificationsTypes[i].NotificationType),
-			worker.WorkerConfig.AMQP.BindingKey,
-			eventType,
-			userConfigId.UserConfigId,
-			accId,
-			dataBytes)
+		//Notification types in the fallback chain are only routed one at a time
+		if IsInFallbackChain(plan.Chain, v.NotificationType) {
+			continue
+		}
 
-		n.Logger.Info(notificationsTypes[i].NotificationType)
-		n.Logger.Info(taskSignature)
-		_, err = worker.MachineryServer.SendTask(taskSignature)
-		if err != nil {
-			n.Logger.Errorw("Error", err)
-		} else {
-			n.Logger.Infof("Send notification to %s", notificationsTypes[i].NotificationType)
+		if n.SendToSlave(ctx, v.NotificationType, eventType, userConfigId.UserConfigId, accId, payload, filterHeaders) == nil {
+			routed = append(routed, v.NotificationType)
 		}
+	}
 
+	if primary := n.RouteFallbackChain(ctx, plan.Chain, sentNotificationType, eventType, userConfigId.UserConfigId, accId, dataBytes, payloads, headers); primary != "" {
+		routed = append(routed, primary)
 	}
+	n.RecordRouted(ctx, routed)
 
 	return nil
 }
 
-func (n *NotificationRouter) SendUserSpecificNotification(userId, eventType string, dataBytes []byte) error {
+func (n *NotificationRouter) SendUserSpecificNotification(ctx context.Context, userId, eventType string, dataBytes []byte) error {
 
-	status, err := n.Worker.Db.GetIntegrationStatus(ctx, &nm.IntegrationStatusReq{UserId: userId})
+	logger := tracing.Logger(ctx, n.Logger)
+
+	userConfigId, err := n.Db.GetUserConfigId(ctx, userId)
 	if err != nil {
-		n.Logger.Info("Failed to retrieved integration status")
+		logger.Errorw("Failed to retrieve userConfig", "userId", userId, "error", err)
 		return err
 	}
+	logger.Infof("Sending user specific notification for userConfigId:%v", *userConfigId)
 
-	userConfigId, _ := n.Db.GetUserConfigId(ctx, userId)
-	n.Logger.Infof("Sending user specific notification for userConfigId:%v", userConfigId)
-
-	notificationsTypes, err := n.Worker.Db.GetEnabledNotificationTypes(ctx, fmt.Sprintf("%d", *userConfigId), eventType)
+	configIds := contract.ConfigIds{UserConfigId: fmt.Sprintf("%d", *userConfigId), UserId: userId}
+	plan, err := n.Worker.Db.GetRoutingPlan(ctx, configIds, eventType)
 	if err != nil {
-		n.Logger.Errorw("Error:", err)
+		logger.Errorw("Error:", err)
 		return err
 	}
 
-	for i := 0; i < len(notificationsTypes); i++ {
-		enabled := IsUserConfigEnabled(status, notificationsTypes[i].NotificationType)
-		if !enabled {
-			n.Logger.Infof("Notification integration not enabled for userId %v, notification type :%v", userId, notificationsTypes[i].NotificationType)
+	var data map[string]string
+	_ = json.Unmarshal(dataBytes, &data)
+
+	routable := map[string]bool{}
+	payloads := map[string][]byte{}
+	headers := map[string]tasks.Headers{}
+	routed := []string{}
+	for _, v := range plan.Configs {
+		if !IsRoutable(plan, v) {
+			logger.Infof("Notification integration not enabled for userId %v, notification type :%v", userId, v.NotificationType)
+			n.RecordTransition(ctx, v.NotificationType, contract.NOTIFICATION_SKIPPED, "integration disabled")
+			continue
+		}
+
+		filterHeaders, ok := n.FilterNotification(plan, v, data)
+		if !ok {
+			n.RecordTransition(ctx, v.NotificationType, contract.NOTIFICATION_SKIPPED, "filtered out")
 			continue
 		}
+		routable[v.NotificationType] = true
 
-		worker := GetSlaveFromPool(notificationsTypes[i].NotificationType)
+		payload := n.StartEscalation(v, configIds.UserConfigId, "", eventType, dataBytes)
+		payloads[v.NotificationType] = payload
+		headers[v.NotificationType] = filterHeaders
 
-		taskSignature := GetRouteNotificationTask(
-			GetTaskName(notificationsTypes[i].NotificationType),
-			worker.WorkerConfig.AMQP.BindingKey,
-			eventType,
-			fmt.Sprintf("%d", *userConfigId),
-			"",
-			dataBytes)
+		if IsInFallbackChain(plan.Chain, v.NotificationType) {
+			continue
+		}
 
-		n.Logger.Info(notificationsTypes[i].NotificationType)
-		n.Logger.Info(taskSignature)
-		_, err = worker.MachineryServer.SendTask(taskSignature)
-		if err != nil {
-			n.Logger.Errorw("Error", err)
-		} else {
-			n.Logger.Infof("Send notification to %s", notificationsTypes[i].NotificationType)
+		if n.SendToSlave(ctx, v.NotificationType, eventType, configIds.UserConfigId, "", payload, filterHeaders) == nil {
+			routed = append(routed, v.NotificationType)
 		}
+	}
 
+	if primary := n.RouteFallbackChain(ctx, plan.Chain, routable, eventType, configIds.UserConfigId, "", dataBytes, payloads, headers); primary != "" {
+		routed = append(routed, primary)
 	}
+	n.RecordRouted(ctx, routed)
 	return nil
 }
 
-func GetRouteNotificationTask(taskName, BindingKey, eventType, userConfig, accountId string, dataBytes []byte) *tasks.Signature {
-
-	return &tasks.Signature{
-		Name:       taskName,
-		RoutingKey: BindingKey,
-		Args: []tasks.Arg{
-			{
-				Name:  "userConfig",
-				Type:  "string",
-				Value: userConfig,
-			},
-			{
-				Name:  "accountId",
-				Type:  "string",
-				Value: accountId,
-			},
-			{
-				Name:  "eventType",
-				Type:  "string",
-				Value: eventType,
-			},
-			{
-				Name:  "dataBytes",
-				Type:  "[]byte",
-				Value: dataBytes,
-			},
-		},
-		RetryCount:   1,
-		RetryTimeout: 100,
+// SendToSlave publishes the event to the slave worker of notificationType.
+// The task envelope is derived from the envelope of ctx and the destination is tracked in its lifecycle
+func (n *NotificationRouter) SendToSlave(ctx context.Context, notificationType, eventType, userConfig, accId string, dataBytes []byte, headers tasks.Headers) error {
+
+	ctx, span := trace.StartSpan(ctx, "SendToSlave")
+	span.AddAttributes(trace.StringAttribute("notification.type", notificationType))
+	defer span.End()
+	logger := tracing.Logger(ctx, n.Logger)
+
+	parent := EnvelopeFromContext(ctx)
+	envelope := GetDeliveryEnvelope(parent, notificationType, eventType, userConfig, accId, dataBytes)
+	envelope.TraceContext = tracing.Inject(ctx)
+	record := func(state, reason string) {
+		if parent != nil {
+			n.RecordTransition(WithEnvelope(ctx, envelope), notificationType, state, reason)
+		}
 	}
 
+	worker := GetSlaveFromPool(notificationType)
+	if worker == nil || !IsSlaveAvailable(notificationType) {
+		logger.Errorw("No available slave worker for notification type", "notificationType", notificationType)
+		record(contract.NOTIFICATION_FAILED, "no available slave worker")
+		n.SlaveUnavailable(ctx, notificationType, eventType, userConfig, accId, dataBytes, headers)
+		return fmt.Errorf("no available slave worker for notification type %v", notificationType)
+	}
+
+	taskSignature, err := GetEnvelopeTask(GetEnvelopeTaskName(notificationType), worker.WorkerConfig.AMQP.BindingKey, envelope)
+	if err != nil {
+		logger.Errorw("Error while encoding task envelope", "notificationType", notificationType, "error", err)
+		record(contract.NOTIFICATION_FAILED, err.Error())
+		return err
+	}
+	taskSignature.Headers = headers
+
+	logger.Info(notificationType)
+	logger.Info(taskSignature)
+	_, err = worker.MachineryServer.SendTask(taskSignature)
+	if err != nil {
+		logger.Errorw("Error", err)
+		record(contract.NOTIFICATION_FAILED, err.Error())
+	} else {
+		logger.Infof("Send notification to %s", notificationType)
+		eventsRouted.WithLabelValues(notificationType).Inc()
+		record(contract.NOTIFICATION_ROUTED, "")
+	}
+	return err
+}
+
+// RecordRouted records the channels the notification was routed to
+func (n *NotificationRouter) RecordRouted(ctx context.Context, routed []string) {
+	if len(routed) == 0 {
+		n.RecordTransition(ctx, "", contract.NOTIFICATION_SKIPPED, "no channel to route to")
+		return
+	}
+	n.RecordTransition(ctx, "", contract.NOTIFICATION_ROUTED, strings.Join(routed, ","))
+}
+
+// RouteFallbackChain sends the event to the primary channel of the fallback chain.
+// The rest of the chain is only used when the slave reports a permanent failure.
+// payloads holds the data of notification types that got an acknowledgement link
+// and headers the bots filtered out per notification type. It returns the primary when it was sent
+func (n *NotificationRouter) RouteFallbackChain(ctx context.Context, chain []model.FallbackRules, routable map[string]bool, eventType, userConfig, accId string, dataBytes []byte, payloads map[string][]byte, headers map[string]tasks.Headers) string {
+	primary := GetPrimaryNotificationType(chain, routable)
+	if primary == "" {
+		return ""
+	}
+
+	if payload, ok := payloads[primary]; ok {
+		dataBytes = payload
+	}
+
+	chainId := uuid.New().String()
+	n.Logger.Infof("Routing notification through fallback chain chainId:%v primary:%v", chainId, primary)
+	if n.SendToSlave(ctx, primary, eventType, userConfig, accId, dataBytes, MergeHeaders(headers[primary], tasks.Headers{contract.HEADER_CHAIN_ID: chainId})) != nil {
+		return ""
+	}
+	return primary
 }
 
-func (n *NotificationRouter) ShouldSendNotification(userId string, accountConfigId string, notificationId string, eventType string, notificationType string) bool {
+// RouteFallback is called by a slave when delivery failed permanently.
+// It sends the event to the next routable channel of the fallback chain
+func (n *NotificationRouter) RouteFallback(ctx context.Context, userConfig, accId, eventType string, dataBytes []byte, failedNotificationType string) error {
 
-	disabled, err1 := n.Worker.Db.IsAccountNotificationDisabled(ctx, accountConfigId, notificationId)
+	ctx, span := tracing.StartSpan(ctx, "RouteFallback", TaskTraceContext(ctx))
+	defer span.End()
+	logger := tracing.Logger(ctx, n.Logger)
 
-	if err1 != nil {
+	chainId := GetChainId(ctx)
+	logger.Infof("Received fallback task chainId:%v failed notification type:%v", chainId, failedNotificationType)
 
-		n.Logger.Errorw("Notification disabled for accountConfigId", accountConfigId, "eventType", eventType, "notificationType", notificationType)
-		return false
+	userId, err := n.Worker.Db.GetUserIdByConfigId(ctx, userConfig)
+	if err != nil {
+		return err
+	}
+	ctx = WithNotification(ctx, userId, accId, eventType)
+
+	configIds := contract.ConfigIds{UserConfigId: userConfig, UserId: userId}
+	if accId != "" {
+		ids, err := n.Worker.Db.GetUserConfig(ctx, accId)
+		if err != nil {
+			return err
+		}
+		configIds.AccountConfId = ids.AccountConfId
 	}
 
-	status, err := n.Worker.Db.GetIntegrationStatus(ctx, &nm.IntegrationStatusReq{UserId: userId})
+	plan, err := n.Worker.Db.GetRoutingPlan(ctx, configIds, eventType)
 	if err != nil {
-		return false
+		return err
+	}
+
+	var data map[string]string
+	_ = json.Unmarshal(dataBytes, &data)
+
+	for _, rule := range GetNextFallbackRules(plan.Chain, failedNotificationType) {
+		for _, v := range plan.Configs {
+			if v.NotificationType != rule.NotificationType {
+				continue
+			}
+
+			if !IsRoutable(plan, v) {
+				break
+			}
+
+			if !IsSlaveAvailable(rule.NotificationType) {
+				logger.Infof("Skipping unavailable %v in fallback chainId:%v", rule.NotificationType, chainId)
+				break
+			}
+
+			filterHeaders, ok := n.FilterNotification(plan, v, data)
+			if !ok {
+				break
+			}
+
+			err = n.SendToSlave(ctx, rule.NotificationType, eventType, userConfig, accId, dataBytes, MergeHeaders(filterHeaders, tasks.Headers{contract.HEADER_CHAIN_ID: chainId}))
+			if err != nil {
+				break
+			}
+
+			n.DumpFallbackLog(ctx, chainId, userConfig, accId, eventType, rule.NotificationType, failedNotificationType, contract.STATUS_FALLBACK)
+			return nil
+		}
+	}
+
+	logger.Infof("Fallback chain exhausted chainId:%v", chainId)
+	n.DumpFallbackLog(ctx, chainId, userConfig, accId, eventType, "", failedNotificationType, contract.STATUS_FALLBACK_EXHAUSTED)
+	n.DeadLetterDelivery(ctx, failedNotificationType, eventType, userConfig, accId, dataBytes, tasks.Headers{contract.HEADER_CHAIN_ID: chainId},
+		fmt.Errorf("fallback chain exhausted. %v failed: %v", failedNotificationType, GetLastError(ctx)))
+	return nil
+}
+
+// SlaveUnavailable sends the event to the next channel of its fallback chain.
+// Events without a fallback chain are dead-lettered and logged with their data
+func (n *NotificationRouter) SlaveUnavailable(ctx context.Context, notificationType, eventType, userConfig, accId string, dataBytes []byte, headers tasks.Headers) {
+	unavailable := fmt.Errorf("no available slave worker for notification type %v", notificationType)
+	if chainId, _ := headers[contract.HEADER_CHAIN_ID].(string); chainId != "" {
+		n.SendFallback(ctx, chainId, userConfig, accId, eventType, dataBytes, notificationType, unavailable)
+		return
+	}
+	n.DeadLetterDelivery(ctx, notificationType, eventType, userConfig, accId, dataBytes, headers, unavailable)
+
+	reqMeta := datatypes.JSON("{}")
+	if json.Valid(dataBytes) {
+		reqMeta = datatypes.JSON(dataBytes)
 	}
-	enabled := IsUserConfigEnabled(status, notificationType)
+	broadcastId, _ := headers[contract.HEADER_BROADCAST_ID].(string)
+	dumpLogErr := n.Db.DumpLog(model.Logs{
+		UserConfig:       userConfig,
+		AccountId:        accId,
+		EventType:        eventType,
+		NotificationType: notificationType,
+		ReqMeta:          reqMeta,
+		Status:           contract.STATUS_DEAD_LETTER,
+		BroadcastId:      broadcastId,
+		TraceId:          tracing.TraceId(ctx),
+		NotificationId:   GetNotificationId(ctx),
+	})
+	if dumpLogErr != nil {
+		n.Logger.Errorw(dumpLogErr.Error())
+	}
+}
+
+func (n *NotificationRouter) DumpFallbackLog(ctx context.Context, chainId, userConfig, accId, eventType, notificationType, fallbackFrom, status string) {
+	dumpLogErr := n.Db.DumpLog(model.Logs{
+		UserConfig:       userConfig,
+		AccountId:        accId,
+		EventType:        eventType,
+		NotificationType: notificationType,
+		ReqMeta:          datatypes.JSON("{}"),
+		Status:           status,
+		ChainId:          chainId,
+		FallbackFrom:     fallbackFrom,
+		TraceId:          tracing.TraceId(ctx),
+		NotificationId:   GetNotificationId(ctx),
+	})
+	if dumpLogErr != nil {
+		n.Logger.Errorw(dumpLogErr.Error())
+	}
+}
 
-	return enabled && !disabled
+// IsRoutable reports whether the integration of the config is enabled and the account didn't disable it
+func IsRoutable(plan model.RoutingPlan, ntConfig model.NotificationConfig) bool {
+	return plan.Integration != nil && IsUserConfigEnabled(plan.Integration, ntConfig.NotificationType) && !plan.Blocked[ntConfig.ID]
 }
 
 func IsUserConfigEnabled(payload *nm.IntegrationStatusReply, notificationType string) bool {
@@ -1,15 +1,19 @@
 package worker
 
 import (
+	"context"
 	"encoding/json"
 	"log"
+	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/devShahriar/H"
 	"github.com/devshahriar/notification-manager/contract"
 	"github.com/devshahriar/notification-manager/model"
-	"github.com/devshahriar/notification-manager/template"
+	"github.com/devshahriar/notification-manager/secrets"
+	"github.com/devshahriar/notification-manager/tracing"
+	"go.opencensus.io/trace"
 	tgbotapi "gopkg.in/telegram-bot-api.v4"
 	"gorm.io/datatypes"
 )
@@ -18,41 +22,88 @@ type TaskSendTelegramNotification struct {
 	*Worker
 }
 
-func (t *TaskSendTelegramNotification) SendTelegramNotification(userConfig, accId, eventType string, data []byte) error {
+func (t *TaskSendTelegramNotification) SendTelegramNotification(ctx context.Context, userConfig, accId, eventType string, data []byte) error {
 
-	ntMeta, err := t.Db.GetBotNotificationMeta(ctx, userConfig, eventType, contract.TELEGRAM)
+	logger := tracing.Logger(ctx, t.Logger)
+
+	var ntMeta []model.BotNotificationMeta
+	var err error
+
+	broadcastId := GetBroadcastId(ctx)
+	if broadcastId != "" {
+		ntMeta, err = t.Db.GetBroadcastBotMeta(ctx, userConfig, broadcastId, contract.TELEGRAM)
+	} else {
+		ntMeta, err = t.Db.GetBotNotificationMeta(ctx, userConfig, eventType, contract.TELEGRAM)
+	}
 
 	if err != nil {
-		t.Logger.Errorw("Error: while retrieving BotNotification Meta for userConfig", userConfig, "eventType", eventType)
+		logger.Errorw("Error: while retrieving BotNotification Meta for userConfig", userConfig, "eventType", eventType)
+		if IsLastAttempt(ctx) {
+			t.RecordDelivery(ctx, contract.TELEGRAM, 0, 1, err)
+		}
 		return err
 	}
 
+	resend := GetResend(ctx)
+	if resend != nil {
+		ntMeta = GetResendBot(ntMeta, resend)
+	}
+	if target := GetTarget(ctx); target != nil {
+		ntMeta = GetTargetBots(ntMeta, target)
+	} else if t.FanOut(ctx, contract.TELEGRAM, GetBotTargets(ctx, ntMeta)) {
+		return nil
+	}
+
+	sent := 0
+	attempts := 0
+	var lastErr error
+	parking := t.NewParking(ctx, contract.TELEGRAM)
 	for _, v := range ntMeta {
 
+		destination := GetBotDestination(v.BotConfigId, v.ChannelId)
+		if IsBotFiltered(ctx, v.BotConfigId) || IsDestinationSent(ctx, destination) {
+			continue
+		}
+		attempts++
+
 		var dataObj map[string]string
 		err := json.Unmarshal(data, &dataObj)
 
 		if err != nil {
-			t.Logger.Errorw("Error while parsing notification data", err)
+			logger.Errorw("Error while parsing notification data", err)
+			lastErr = err
 			continue
 		}
 
-		message := template.IngestDataIntoMsgBody(v.FirstName, v.EventType, v.MessageTemplate, dataObj)
+		message := RenderMessage(ctx, contract.TELEGRAM, v.FirstName, v.EventType, v.MessageTemplate, dataObj)
+		if resend.GetMessage() != "" {
+			message = resend.Message
+		}
 
-		sendErr := t.Send(v.BotToken, v.ChannelId, message)
-		if sendErr != nil {
-			t.Logger.Errorw("Error while sending telegram notification", sendErr)
+		_, span := trace.StartSpan(ctx, "telegram.Send", trace.WithSpanKind(trace.SpanKindClient))
+		_, sendErr := t.Deliver(ctx, contract.TELEGRAM, "channel "+v.ChannelId, BotBreakers(contract.TELEGRAM, v.BotConfigId), func() error {
+			return secrets.Redact(t.Send(v.BotToken, v.ChannelId, message), v.BotToken)
+		})
+		tracing.EndSpan(span, sendErr)
+		if parking.Park(destination, sendErr) {
+			lastErr = sendErr
 			continue
 		}
+		if sendErr != nil {
+			lastErr = sendErr
+			logger.Errorw("Error while sending telegram notification", sendErr)
+		} else {
+			sent++
+		}
 
 		reqMeta := struct {
-			BotToken  string
-			ChannelId string
-			Message   string
+			BotConfigId uint64
+			ChannelId   string
+			Message     string
 		}{
-			BotToken:  v.BotToken,
-			ChannelId: v.ChannelId,
-			Message:   message,
+			BotConfigId: v.BotConfigId,
+			ChannelId:   v.ChannelId,
+			Message:     message,
 		}
 
 		reqMetaBytes, _ := json.Marshal(reqMeta)
@@ -63,22 +114,38 @@ func (t *TaskSendTelegramNotification) SendTelegramNotification(userConfig, accI
 			EventType:        eventType,
 			NotificationType: contract.TELEGRAM,
 			ReqMeta:          datatypes.JSON(reqMetaBytes),
-			Status:           H.If(sendErr != nil, "FAILED", "SUCCESS"),
+			Status:           H.If(sendErr != nil, contract.STATUS_FAILED, contract.STATUS_SUCCESS),
+			ChainId:          GetChainId(ctx),
+			TraceId:          tracing.TraceId(ctx),
+			NotificationId:   GetNotificationId(ctx),
+			BroadcastId:      broadcastId,
+			Payload:          GetLogPayload(data),
+			ResendOf:         resend.GetLogId(),
 		})
 
 		if dumpLogErr != nil {
-			t.Logger.Errorw(dumpLogErr.Error())
+			logger.Errorw(dumpLogErr.Error())
 		}
 
 		log.Println("Message sent successfully!")
 	}
+
+	//The parked task sends the destinations behind an open breaker and falls back if they fail
+	if parking.Publish(ctx) {
+		if attempts -= parking.Len(); attempts > 0 {
+			t.RecordDelivery(ctx, contract.TELEGRAM, sent, attempts, lastErr)
+		}
+		return nil
+	}
+
+	t.FinishDelivery(ctx, contract.TELEGRAM, userConfig, accId, eventType, data, sent, attempts, lastErr)
 	return nil
 }
 
 func (t *TaskSendTelegramNotification) Send(botToken, channelId, message string) error {
 
 	t.Logger.Info(message)
-	bot, err := tgbotapi.NewBotAPI(botToken)
+	bot, err := tgbotapi.NewBotAPIWithClient(botToken, &http.Client{Timeout: t.ProviderTimeout()})
 
 	if err != nil {
 		t.Logger.Errorw("Failed to created new BotAPi for telegram")
@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RichardKnop/machinery/v2/tasks"
+	"github.com/devshahriar/notification-manager/template"
+	"github.com/devshahriar/notification-manager/tracing"
+	"go.opencensus.io/trace"
+)
+
+// TaskTraceContext returns the trace context the task was sent with,
+// from its envelope or from the task headers of positional tasks
+func TaskTraceContext(ctx context.Context) map[string]string {
+	if envelope := EnvelopeFromContext(ctx); envelope != nil {
+		return envelope.TraceContext
+	}
+	signature := tasks.SignatureFromContext(ctx)
+	if signature == nil {
+		return nil
+	}
+	traceparent, _ := signature.Headers[tracing.TRACEPARENT].(string)
+	return map[string]string{tracing.TRACEPARENT: traceparent}
+}
+
+// RenderMessage renders the message template of the event in its own span.
+// Templates of unknown events panic, which is counted before the task fails
+func RenderMessage(ctx context.Context, notificationType, firstName, eventType, messageTemplate string, data map[string]string) string {
+	_, span := trace.StartSpan(ctx, "template.Render")
+	defer span.End()
+	defer func() {
+		if r := recover(); r != nil {
+			templateRenderErrors.WithLabelValues(eventType).Inc()
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: fmt.Sprint(r)})
+			panic(r)
+		}
+	}()
+	return template.IngestDataIntoMsgBody(firstName, eventType, messageTemplate, data, notificationType)
+}
@@ -2,6 +2,9 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
 
 	"github.com/RichardKnop/machinery/v2"
 	"github.com/RichardKnop/machinery/v2/backends/redis"
@@ -9,13 +12,13 @@ import (
 	"github.com/RichardKnop/machinery/v2/config"
 	machineryConf "github.com/RichardKnop/machinery/v2/config"
 	lock "github.com/RichardKnop/machinery/v2/locks/eager"
+	"github.com/devshahriar/notification-manager/cache"
+	"github.com/devshahriar/notification-manager/contract"
 	"github.com/devshahriar/notification-manager/db"
+	"github.com/devshahriar/notification-manager/health"
 	log "github.com/sirupsen/logrus"
 	"go.uber.org/zap"
-)
-
-var (
-	PrefetchCount = 150
+	"golang.org/x/time/rate"
 )
 
 type Worker struct {
@@ -25,12 +28,42 @@ type Worker struct {
 	WorkerConfig    *machineryConf.Config
 	MachineryWorker *machinery.Worker
 	MachineryServer *machinery.Server
-	Concurrency     int
+	Settings        contract.WorkerSettings // Read with GetSettings and changed with ApplySettings once the worker runs
 	Db              db.DB
 	Logger          *zap.SugaredLogger
+	RetryPolicies   map[string]RetryPolicy // Delivery retries by notification type
+	Breakers        *Breakers              // Circuit breakers of the providers. nil disables them
+	Dependencies    *health.Checker        // Health of the database, broker and result backend served on /ready
+	ShutdownTimeout time.Duration          // Time the tasks in flight get to finish once Run is cancelled
+
+	settingsMu sync.RWMutex
+	limiter    *rate.Limiter
+	reconsume  chan struct{}
+
+	consumerMu  sync.Mutex
+	consuming   bool
+	consumerErr error
 }
 
+// Consumer consumes the queue of the worker. It is the machinery worker
+type Consumer interface {
+	LaunchAsync(errorsChan chan<- error)
+	Quit()
+}
+
+// ErrShutdownTimeout is returned when tasks were still running at the shutdown deadline
+var ErrShutdownTimeout = errors.New("shutdown deadline passed with tasks in flight")
+
+// ErrSettingsChanged is returned by Consume when the concurrency or prefetch count changed
+var ErrSettingsChanged = errors.New("worker settings changed")
+
 func (w *Worker) InitMachineryWorker() {
+	w.MachineryServer = w.NewMachineryServer()
+	w.MachineryWorker = w.MachineryServer.NewWorker("notification_worker", w.GetSettings().Concurrency)
+}
+
+// NewMachineryServer connects to the queue of the worker with its current prefetch count
+func (w *Worker) NewMachineryServer() *machinery.Server {
 
 	conf := w.WorkerConfig
 
@@ -41,7 +74,9 @@ func (w *Worker) InitMachineryWorker() {
 			Exchange:      conf.AMQP.Exchange,
 			ExchangeType:  conf.AMQP.ExchangeType,
 			BindingKey:    conf.AMQP.BindingKey,
-			PrefetchCount: PrefetchCount,
+			PrefetchCount: w.prefetchCount(),
+
+			QueueDeclareArgs: conf.AMQP.QueueDeclareArgs,
 		},
 		Redis: &config.RedisConfig{
 
@@ -53,36 +88,123 @@ func (w *Worker) InitMachineryWorker() {
 			NormalTasksPollPeriod:  1000,
 			DelayedTasksPollPeriod: 500,
 		},
+		//Run stops the worker on the signals of the command
+		NoUnixSignals: true,
 	}
 
 	log.Info(conf.ResultBackend)
 	resultBackend := conf.ResultBackend
-	broker := amqpBroker.New(mc)
+	broker := w.ConsumerBroker(amqpBroker.New(mc))
 	backend := redis.NewGR(mc, []string{resultBackend}, 5)
 	lock := lock.New()
 
-	machinaryServer := machinery.NewServer(mc, broker, backend, lock)
-	machinaryWorker := machinaryServer.NewWorker("notification_worker", w.Concurrency)
-
-	w.MachineryServer = machinaryServer
-	w.MachineryWorker = machinaryWorker
-
+	return machinery.NewServer(mc, broker, backend, lock)
 }
 
 func (w *Worker) ResisterTask() {
+	w.registerTasks(w.MachineryServer)
+}
+
+func (w *Worker) registerTasks(server *machinery.Server) {
 	task := GetTaskByWorkerName(w.Name)
 	log.Info(task)
-	err := w.MachineryServer.RegisterTasks(task)
+	err := server.RegisterTasks(task)
 	if err != nil {
 		log.Info("Error while registering task")
 		log.Info(err)
 	}
 }
 
+// Run consumes the tasks of the worker until ctx is done, then drains the tasks in flight and flushes the logs.
+// When the concurrency or prefetch count changes or the connection of the consumer is lost the worker consumes again on a new connection
 func (w *Worker) Run(ctx context.Context) {
 	w.ResisterTask()
-	if err := w.MachineryWorker.Launch(); err != nil {
-		log.Info("[*] Error while launching worker")
-		log.Info(err)
+	consumer := w.MachineryWorker
+consuming:
+	for {
+		err := w.Consume(ctx, consumer)
+		switch {
+		case errors.Is(err, ErrSettingsChanged):
+		case errors.Is(err, ErrConsumerLost) && ctx.Err() == nil:
+			w.Logger.Errorw("Consuming again once the broker is back", "error", err, "retryIn", contract.CONSUMER_RECONNECT_SECONDS)
+			select {
+			case <-ctx.Done():
+				break consuming
+			case <-time.After(contract.CONSUMER_RECONNECT_SECONDS * time.Second):
+			}
+		default:
+			if err != nil {
+				log.Info("[*] Error while running worker")
+				log.Info(err)
+			}
+			break consuming
+		}
+
+		//Publishing keeps the server of the worker, only the consumer is replaced
+		settings := w.GetSettings()
+		server := w.NewMachineryServer()
+		w.registerTasks(server)
+		consumer = server.NewWorker("notification_worker", settings.Concurrency)
+		w.Logger.Infow("Consuming on a new connection", "concurrency", settings.Concurrency, "prefetch", w.prefetchCount())
+	}
+	_ = w.Logger.Sync()
+}
+
+// Consume launches consumer and stops it once ctx is done. Consuming stops at once and the tasks in flight
+// get ShutdownTimeout to finish and be acknowledged. Tasks still running at the deadline and the prefetched
+// tasks that never started aren't acknowledged, so the broker requeues them once the connection is gone.
+// A new concurrency or prefetch count stops consumer the same way and returns ErrSettingsChanged once it drained
+func (w *Worker) Consume(ctx context.Context, consumer Consumer) error {
+	w.settingsMu.Lock()
+	if w.reconsume == nil {
+		w.reconsume = make(chan struct{}, 1)
+	}
+	reconsume := w.reconsume
+	w.settingsMu.Unlock()
+
+	errorsChan := make(chan error, 1)
+	w.setConsuming(true)
+	defer w.setConsuming(false)
+	consumer.LaunchAsync(errorsChan)
+
+	reconsuming := false
+	select {
+	case err := <-errorsChan:
+		return err
+	case <-ctx.Done():
+	case <-reconsume:
+		reconsuming = true
+	}
+
+	w.Logger.Infow("Stopping worker", "inFlight", InFlight(), "timeout", w.ShutdownTimeout, "settingsChanged", reconsuming)
+	go consumer.Quit()
+
+	deadline := time.NewTimer(w.ShutdownTimeout)
+	defer deadline.Stop()
+	select {
+	case err := <-errorsChan:
+		w.Logger.Info("Worker stopped")
+		if reconsuming && err == nil && ctx.Err() == nil {
+			return ErrSettingsChanged
+		}
+		return err
+	case <-deadline.C:
+		//The stuck tasks keep running on the old connection and are acknowledged there
+		if reconsuming && ctx.Err() == nil {
+			w.Logger.Warnw("Tasks still in flight consuming with new settings", "inFlight", InFlight())
+			return ErrSettingsChanged
+		}
+		w.Logger.Warnw("Shutdown deadline passed. The broker requeues the tasks in flight", "inFlight", InFlight())
+		return ErrShutdownTimeout
 	}
 }
+
+// InitRoutingCache serves the routing reads of the worker from an in-process cache
+// and drops the entries of a user when the server publishes a config change
+func (w *Worker) InitRoutingCache(ctx context.Context, ttl time.Duration) {
+	routingCache := cache.New(ttl)
+	w.Db = db.NewCachedDB(w.Db, routingCache)
+
+	client := cache.NewRedisClient(w.WorkerConfig.ResultBackend)
+	go routingCache.Subscribe(ctx, client, contract.ROUTING_INVALIDATION_CHANNEL, w.Logger)
+}